@@ -3,8 +3,10 @@ package main
 import (
 	"fmt"
 	"os"
+	"os/signal"
 	"strconv"
 	"strings"
+	"syscall"
 
 	"github.com/boboTheFoff/shheissee-go/internal/config"
 	"github.com/boboTheFoff/shheissee-go/internal/detector"
@@ -20,7 +22,7 @@ func main() {
 	}
 
 	// Initialize configuration and directories
-	cfg := models.DefaultConfig()
+	cfg := loadConfig()
 	if err := config.EnsureDirectories(cfg); err != nil {
 		fmt.Printf("%sError setting up directories: %v%s\n", models.ColorRed, err, models.ColorReset)
 		os.Exit(1)
@@ -42,6 +44,7 @@ func main() {
 		os.Exit(1)
 	}
 	defer attackDetector.Close()
+	closeOnSignal(attackDetector)
 
 	consoleLogger := logging.NewConsoleLogger()
 	startupLogger.LogInfo("Go-Shheissee Security Monitor initialized")
@@ -76,6 +79,22 @@ func main() {
 	runMainMenu(attackDetector, consoleLogger, startupLogger)
 }
 
+// closeOnSignal closes the detector before exiting on SIGINT or SIGTERM, so
+// that scanners can undo changes to the host such as monitor interfaces. Close
+// cancels the running scan and waits for it before releasing the scanners.
+func closeOnSignal(attackDetector *detector.AttackDetector) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		<-signals
+		if err := attackDetector.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+		}
+		os.Exit(1)
+	}()
+}
+
 func handleCommand(args []string) {
 	command := strings.ToLower(args[0])
 
@@ -100,7 +119,7 @@ func handleCommand(args []string) {
 }
 
 func runMonitoring() {
-	cfg := models.DefaultConfig()
+	cfg := loadConfig()
 	config.EnsureDirectories(cfg)
 
 	attackDetector, err := detector.NewAttackDetector(cfg)
@@ -109,6 +128,7 @@ func runMonitoring() {
 		os.Exit(1)
 	}
 	defer attackDetector.Close()
+	closeOnSignal(attackDetector)
 
 	// Initialize web server
 	logger, _ := logging.NewLogger(cfg.LogFile)
@@ -125,7 +145,7 @@ func runMonitoring() {
 }
 
 func runQuickScan() {
-	cfg := models.DefaultConfig()
+	cfg := loadConfig()
 	config.EnsureDirectories(cfg)
 
 	attackDetector, err := detector.NewAttackDetector(cfg)
@@ -134,6 +154,7 @@ func runQuickScan() {
 		os.Exit(1)
 	}
 	defer attackDetector.Close()
+	closeOnSignal(attackDetector)
 
 	consoleLogger := logging.NewConsoleLogger()
 
@@ -152,7 +173,7 @@ func runQuickScan() {
 }
 
 func runBluetoothMonitor() {
-	cfg := models.DefaultConfig()
+	cfg := loadConfig()
 	config.EnsureDirectories(cfg)
 
 	attackDetector, err := detector.NewAttackDetector(cfg)
@@ -161,6 +182,7 @@ func runBluetoothMonitor() {
 		os.Exit(1)
 	}
 	defer attackDetector.Close()
+	closeOnSignal(attackDetector)
 
 	err = attackDetector.MonitorBluetoothDevices()
 	if err != nil {
@@ -169,7 +191,7 @@ func runBluetoothMonitor() {
 }
 
 func runDemo() {
-	cfg := models.DefaultConfig()
+	cfg := loadConfig()
 	config.EnsureDirectories(cfg)
 
 	attackDetector, err := detector.NewAttackDetector(cfg)
@@ -178,6 +200,7 @@ func runDemo() {
 		os.Exit(1)
 	}
 	defer attackDetector.Close()
+	closeOnSignal(attackDetector)
 
 	err = attackDetector.PerformDemoAttack()
 	if err != nil {
@@ -186,7 +209,7 @@ func runDemo() {
 }
 
 func runWebServer() {
-	cfg := models.DefaultConfig()
+	cfg := loadConfig()
 	config.EnsureDirectories(cfg)
 
 	logger, _ := logging.NewLogger(cfg.LogFile)
@@ -270,15 +293,23 @@ func runMainMenu(ad *detector.AttackDetector, consoleLogger *logging.ConsoleLogg
 			} else {
 				fmt.Printf("\n\033[32mFound %d WiFi device(s)/network(s):\033[0m\n", len(devices))
 				if len(devices) > 0 {
-					fmt.Println("\033[1mMAC Address         SSID                           Signal Ch Status\033[0m")
-					fmt.Println("-" + strings.Repeat("-", 83))
+					fmt.Println("\033[1mMAC Address         SSID                           Signal  Ch  Band    Security   Status\033[0m")
+					fmt.Println("-" + strings.Repeat("-", 100))
 					for _, device := range devices {
 						status := device.Status
 						if status == "" {
 							status = "Unknown"
 						}
-						fmt.Printf("%-18s %-30s %-6s %-2s %-10s\n",
-							device.Address, device.SSID, device.Signal, device.Channel, status)
+						security := device.Security
+						if security == "" {
+							security = "unknown"
+						}
+						signal := ""
+						if device.Signal != 0 {
+							signal = fmt.Sprintf("%d dBm", device.Signal)
+						}
+						fmt.Printf("%-18s %-30s %-7s %-3s %-7s %-10s %-10s\n",
+							device.Address, device.SSID, signal, device.Channel, device.Band, security, status)
 					}
 				}
 				fmt.Println()
@@ -297,6 +328,16 @@ func runMainMenu(ad *detector.AttackDetector, consoleLogger *logging.ConsoleLogg
 	}
 }
 
+// loadConfig loads the configuration file named by APP_CONFIG, or the defaults if unset
+func loadConfig() *models.AttackDetectorConfig {
+	cfg, err := config.LoadConfig(os.Getenv("APP_CONFIG"))
+	if err != nil {
+		fmt.Printf("%sError loading configuration: %v%s\n", models.ColorRed, err, models.ColorReset)
+		os.Exit(1)
+	}
+	return cfg
+}
+
 func waitForEnter() {
 	fmt.Print("\033[34mPress Enter to continue...\033[0m")
 	fmt.Scanln()
@@ -315,4 +356,5 @@ func showHelp() {
 	fmt.Println("  help, -h, --help  Show this help message")
 	fmt.Println()
 	fmt.Println("Running without arguments starts the interactive menu.")
+	fmt.Println("Set APP_CONFIG to a JSON file to load configuration from it.")
 }
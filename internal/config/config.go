@@ -2,6 +2,7 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 
@@ -35,9 +36,27 @@ func LoadConfig(configPath string) (*models.AttackDetectorConfig, error) {
 		return config, err
 	}
 
+	if err := validateConfig(config); err != nil {
+		return config, err
+	}
+
 	return config, nil
 }
 
+// maxPortScanRate bounds the connect scanner's rate limits in connects per second
+const maxPortScanRate = 1000000
+
+// validateConfig rejects settings no scanner could honor
+func validateConfig(config *models.AttackDetectorConfig) error {
+	if config.PortScanRate > maxPortScanRate {
+		return fmt.Errorf("port_scan_rate must be at most %d, got %d", maxPortScanRate, config.PortScanRate)
+	}
+	if config.PortScanHostRate > maxPortScanRate {
+		return fmt.Errorf("port_scan_host_rate must be at most %d, got %d", maxPortScanRate, config.PortScanHostRate)
+	}
+	return nil
+}
+
 // SaveConfig saves configuration to file
 func SaveConfig(config *models.AttackDetectorConfig, configPath string) error {
 	// Create directory if it doesn't exist
@@ -59,7 +78,10 @@ func EnsureDirectories(config *models.AttackDetectorConfig) error {
 	dirs := []string{
 		filepath.Dir(config.KnownDevicesFile),
 		filepath.Dir(config.BluetoothDevicesFile),
+		filepath.Dir(config.WiFiDevicesFile),
+		filepath.Dir(config.PortBaselineFile),
 		filepath.Dir(config.LogFile),
+		filepath.Dir(config.HoneypotLogFile),
 		"web/templates",
 		"web/static",
 		"scripts",
@@ -0,0 +1,28 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadConfigRejectsPortScanRates(t *testing.T) {
+	tests := []struct {
+		config  string
+		wantErr bool
+	}{
+		{`{"port_scan_rate": 0, "port_scan_host_rate": 0}`, false},
+		{`{"port_scan_rate": 1000000, "port_scan_host_rate": 50}`, false},
+		{`{"port_scan_rate": 2000000000}`, true},
+		{`{"port_scan_host_rate": 1000001}`, true},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "config.json")
+		if err := os.WriteFile(path, []byte(tt.config), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadConfig(path); (err != nil) != tt.wantErr {
+			t.Errorf("%s: got error %v, want error %v", tt.config, err, tt.wantErr)
+		}
+	}
+}
@@ -1,7 +1,9 @@
 package detector
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"strings"
 	"sync"
 	"time"
@@ -16,14 +18,21 @@ type AttackDetector struct {
 	config           *models.AttackDetectorConfig
 	logger           *logging.Logger
 	consoleLogger    *logging.ConsoleLogger
-	networkScanner   *scanners.NetworkScanner
-	bluetoothScanner *scanners.BluetoothScanner
-	wifiScanner      *scanners.WiFiScanner
+	scanners         []scanners.Scanner
 	anomalyDetector  *models.AnomalyDetector
 	knownDevices     []string
 	knownBtDevices   []models.BluetoothDevice
+	portBaseline     *portBaseline
+	presence         map[string]*presenceTracker
 	attackLog        []models.Attack
 	mu               sync.RWMutex
+
+	// Scans run under ctx; Close cancels it, waits for the scan in progress
+	// and then releases the scanners once
+	ctx              context.Context
+	cancel           context.CancelFunc
+	closeOnce        sync.Once
+	closeErr         error
 }
 
 // NewAttackDetector creates a new attack detector instance
@@ -39,6 +48,28 @@ func NewAttackDetector(config *models.AttackDetectorConfig) (*AttackDetector, er
 		return nil, fmt.Errorf("failed to load known Bluetooth devices: %v", err)
 	}
 
+	knownWiFiDevices, err := scanners.LoadKnownWiFiDevices(config.WiFiDevicesFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load known WiFi devices: %v", err)
+	}
+
+	knownBtAddresses := make([]string, len(knownBtDevices))
+	for i, device := range knownBtDevices {
+		knownBtAddresses[i] = device.Address
+	}
+
+	// Track last-seen times of known devices per scanner
+	presence := map[string]*presenceTracker{
+		"network":   newPresenceTracker("network", config.NetworkMissingGrace, knownDevices),
+		"wifi":      newPresenceTracker("WiFi", config.WiFiMissingGrace, knownWiFiDevices),
+		"bluetooth": newPresenceTracker("Bluetooth", config.BluetoothMissingGrace, knownBtAddresses),
+	}
+
+	portBaseline, err := loadPortBaseline(config.PortBaselineFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load port baseline: %v", err)
+	}
+
 	// Create logger
 	logger, err := logging.NewLogger(config.LogFile)
 	if err != nil {
@@ -47,10 +78,29 @@ func NewAttackDetector(config *models.AttackDetectorConfig) (*AttackDetector, er
 
 	consoleLogger := logging.NewConsoleLogger()
 
-	// Create scanners
-	networkScanner := scanners.NewNetworkScanner(knownDevices)
-	bluetoothScanner := scanners.NewBluetoothScanner(knownBtDevices)
-	wifiScanner := scanners.NewWiFiScanner()
+	// Create the command runner shared by all scanners
+	runner, err := scanners.NewCommandRunner(config.CommandMode, config.FixturesDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create command runner: %v", err)
+	}
+
+	// Load the MAC vendor database shared by all scanners
+	vendors, err := scanners.NewVendorDB(config.OUIDatabaseFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load vendor database: %v", err)
+	}
+
+	// Create every registered scanner
+	registered, err := scanners.Build(scanners.Options{
+		Config:                config,
+		Runner:                runner,
+		Vendors:               vendors,
+		KnownDevices:          knownDevices,
+		KnownBluetoothDevices: knownBtDevices,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create scanners: %v", err)
+	}
 
 	// Create anomaly detector
 	anomalyDetector := &models.AnomalyDetector{
@@ -60,113 +110,92 @@ func NewAttackDetector(config *models.AttackDetectorConfig) (*AttackDetector, er
 		AnomalyThreshold:  config.AnomalyThreshold,
 	}
 
+	ctx, cancel := context.WithCancel(context.Background())
 	detector := &AttackDetector{
 		config:           config,
 		logger:           logger,
 		consoleLogger:    consoleLogger,
-		networkScanner:   networkScanner,
-		bluetoothScanner: bluetoothScanner,
-		wifiScanner:      wifiScanner,
+		scanners:         registered,
 		anomalyDetector:  anomalyDetector,
 		knownDevices:     knownDevices,
 		knownBtDevices:   knownBtDevices,
+		portBaseline:     portBaseline,
+		presence:         presence,
 		attackLog:        []models.Attack{},
+		ctx:              ctx,
+		cancel:           cancel,
 	}
 
 	return detector, nil
 }
 
-// StartMonitoring begins continuous security monitoring
+// StartMonitoring begins continuous security monitoring until the detector is closed
 func (ad *AttackDetector) StartMonitoring() error {
 	ad.consoleLogger.DisplayStatus(len(ad.knownDevices), len(ad.knownBtDevices), len(ad.attackLog))
 
 	for {
 		ad.mu.Lock()
-		ad.performSecurityScan()
+		if ad.ctx.Err() == nil {
+			ad.performSecurityScan()
+		}
 		ad.mu.Unlock()
 
-		time.Sleep(ad.config.ScanInterval)
+		select {
+		case <-ad.ctx.Done():
+			return nil
+		case <-time.After(ad.config.ScanInterval):
+		}
 	}
 }
 
-// PerformSecurityScan performs a complete security scan
+// PerformSecurityScan performs a complete security scan. Callers hold mu.
 func (ad *AttackDetector) performSecurityScan() {
 	fmt.Print("\n\033[34mScanning for threats...\033[0m\r")
 
-	// Network scan
-	networkDevices, networkAttacks, err := ad.networkScanner.ScanNetwork()
-	if err != nil {
-		ad.logger.LogError("Network scan failed", err)
-	} else {
-		// Update anomaly detector with network data
-		ad.updateAnomalyDetector(networkDevices)
+	ctx := ad.ctx
 
-		// Detect AI anomalies
-		aiAttacks := ad.detectAIAnomalies()
-		networkAttacks = append(networkAttacks, aiAttacks...)
-
-		// Log network attacks
-		for _, attack := range networkAttacks {
-			ad.logAttack(attack)
+	for _, scanner := range ad.scanners {
+		if ctx.Err() != nil {
+			// Closing; results of an interrupted pass are incomplete
+			return
+		}
+		if !scanner.Available() {
+			continue
 		}
 
-		ad.logger.LogScanResult("network", &models.ScanResult{
-			Type:      "network",
-			Timestamp: time.Now(),
-			Devices:   []interface{}{networkDevices},
-			Attacks:   networkAttacks,
-		})
-	}
-
-	// Port scan
-	_, portAttacks, err := ad.networkScanner.ScanPorts(networkDevices)
-	if err != nil {
-		ad.logger.LogError("Port scan failed", err)
-	} else {
-		for _, attack := range portAttacks {
-			ad.logAttack(attack)
+		devices, attacks, err := scanner.Scan(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			ad.logger.LogError(fmt.Sprintf("%s scan failed", scanner.Name()), err)
+			if devices == nil && attacks == nil {
+				continue
+			}
 		}
-	}
 
-	// Bluetooth scan
-	bluetoothDevices, err := ad.bluetoothScanner.ScanBluetoothDevices()
-	if err != nil {
-		ad.logger.LogError("Bluetooth scan failed", err)
-	} else {
-		// Update anomaly detector with Bluetooth data
-		ad.updateBluetoothAnomalyDetector(bluetoothDevices)
+		// Update anomaly detector with the scanned devices
+		ad.updateAnomalyDetectorFrom(devices)
+		attacks = append(attacks, ad.detectPortChanges(devices)...)
+		if tracker, ok := ad.presence[scanner.Name()]; ok {
+			attacks = append(attacks, tracker.update(sightingsFrom(devices), time.Now())...)
+		}
 
-		// Detect Bluetooth attacks
-		bluetoothAttacks := ad.bluetoothScanner.DetectBluetoothAttacks(bluetoothDevices)
-		for _, attack := range bluetoothAttacks {
+		for _, attack := range attacks {
 			ad.logAttack(attack)
 		}
 
-		ad.logger.LogScanResult("bluetooth", &models.ScanResult{
-			Type:      "bluetooth",
+		ad.logger.LogScanResult(scanner.Name(), &models.ScanResult{
+			Type:      scanner.Name(),
 			Timestamp: time.Now(),
-			Devices:   []interface{}{bluetoothDevices},
-			Attacks:   bluetoothAttacks,
+			Devices:   devices,
+			Attacks:   attacks,
 		})
 	}
 
-	// WiFi scan
-	wifiDevices, err := ad.wifiScanner.ScanWiFiNetworks()
-	if err != nil {
-		ad.logger.LogError("WiFi scan failed", err)
-	} else {
-		// Detect WiFi attacks
-		wifiAttacks := ad.wifiScanner.DetectWiFiAttacks(wifiDevices)
-		for _, attack := range wifiAttacks {
-			ad.logAttack(attack)
-		}
-
-		ad.logger.LogScanResult("wifi", &models.ScanResult{
-			Type:      "wifi",
-			Timestamp: time.Now(),
-			Devices:   []interface{}{wifiDevices},
-			Attacks:   wifiAttacks,
-		})
+	// Detect AI anomalies across everything seen this pass
+	for _, attack := range ad.detectAIAnomalies() {
+		ad.logAttack(attack)
 	}
 
 	fmt.Print("\033[32mScan complete. Next scan in 60 seconds...\033[0m\r")
@@ -179,31 +208,19 @@ func (ad *AttackDetector) PerformQuickScan() []models.Attack {
 
 	var allAttacks []models.Attack
 
-	// Network scan
-	_, networkAttacks, err := ad.networkScanner.ScanNetwork()
-	if err == nil {
-		allAttacks = append(allAttacks, networkAttacks...)
-	}
-
-	// Port scan
-	networkDevices, portAttacks, err := ad.networkScanner.ScanNetwork()
-	ad.networkScanner.ScanPorts(networkDevices)
-	if err == nil {
-		allAttacks = append(allAttacks, portAttacks...)
-	}
+	ctx := ad.ctx
 
-	// Bluetooth scan
-	bluetoothDevices, err := ad.bluetoothScanner.ScanBluetoothDevices()
-	if err == nil {
-		bluetoothAttacks := ad.bluetoothScanner.DetectBluetoothAttacks(bluetoothDevices)
-		allAttacks = append(allAttacks, bluetoothAttacks...)
-	}
+	for _, scanner := range ad.scanners {
+		if ctx.Err() != nil {
+			break
+		}
+		if !scanner.Available() {
+			continue
+		}
 
-	// WiFi scan
-	wifiDevices, err := ad.wifiScanner.ScanWiFiNetworks()
-	if err == nil {
-		wifiAttacks := ad.wifiScanner.DetectWiFiAttacks(wifiDevices)
-		allAttacks = append(allAttacks, wifiAttacks...)
+		devices, attacks, _ := scanner.Scan(ctx)
+		allAttacks = append(allAttacks, attacks...)
+		allAttacks = append(allAttacks, ad.detectPortChanges(devices)...)
 	}
 
 	// Log all detected attacks
@@ -216,7 +233,11 @@ func (ad *AttackDetector) PerformQuickScan() []models.Attack {
 
 // ListBluetoothDevices returns a list of nearby Bluetooth devices
 func (ad *AttackDetector) ListBluetoothDevices() ([]models.BluetoothDevice, error) {
-	return ad.bluetoothScanner.ScanBluetoothDevices()
+	bluetoothScanner, err := ad.bluetoothScanner()
+	if err != nil {
+		return nil, err
+	}
+	return bluetoothScanner.ScanBluetoothDevices(context.Background())
 }
 
 // MonitorBluetoothDevices continuously monitors Bluetooth devices
@@ -224,17 +245,30 @@ func (ad *AttackDetector) MonitorBluetoothDevices() error {
 	fmt.Println("\033[35mStarting Bluetooth Device Monitor...\033[0m")
 	ad.logger.LogInfo("Starting Bluetooth Device Monitor")
 
+	bluetoothScanner, err := ad.bluetoothScanner()
+	if err != nil {
+		return err
+	}
+
 	// Simple monitoring loop
 	for {
-		devices, err := ad.bluetoothScanner.ScanBluetoothDevices()
-		if err != nil {
-			ad.logger.LogError("Bluetooth monitoring error", err)
-		} else {
-			ad.displayBluetoothDevices(devices)
+		ad.mu.Lock()
+		if ad.ctx.Err() == nil {
+			devices, err := bluetoothScanner.ScanBluetoothDevices(ad.ctx)
+			if err != nil {
+				ad.logger.LogError("Bluetooth monitoring error", err)
+			} else {
+				ad.displayBluetoothDevices(devices)
+			}
 		}
+		ad.mu.Unlock()
 
 		fmt.Println("\033[34mNext scan in 30 seconds...\033[0m")
-		time.Sleep(30 * time.Second)
+		select {
+		case <-ad.ctx.Done():
+			return nil
+		case <-time.After(30 * time.Second):
+		}
 	}
 }
 
@@ -243,7 +277,12 @@ func (ad *AttackDetector) MonitorBluetoothConnections() error {
 	fmt.Println("\033[35mStarting Bluetooth Connection Monitor...\033[0m")
 	ad.logger.LogInfo("Starting Bluetooth Connection Monitor")
 
-	attackCh, err := ad.bluetoothScanner.MonitorBluetoothConnections()
+	bluetoothScanner, err := ad.bluetoothScanner()
+	if err != nil {
+		return err
+	}
+
+	attackCh, err := bluetoothScanner.MonitorBluetoothConnections(ad.ctx)
 	if err != nil {
 		return err
 	}
@@ -257,7 +296,11 @@ func (ad *AttackDetector) MonitorBluetoothConnections() error {
 
 // ListWiFiDevices returns a list of nearby WiFi networks
 func (ad *AttackDetector) ListWiFiDevices() ([]models.WiFiDevice, error) {
-	return ad.wifiScanner.ScanWiFiNetworks()
+	wifiScanner, err := ad.wifiScanner()
+	if err != nil {
+		return nil, err
+	}
+	return wifiScanner.ScanWiFiNetworks(context.Background())
 }
 
 // PerformDemoAttack creates demo attack scenarios for testing
@@ -305,6 +348,85 @@ func (ad *AttackDetector) PerformDemoAttack() error {
 
 // Helper methods
 
+// findScanner returns the registered scanner with the given name
+func (ad *AttackDetector) findScanner(name string) (scanners.Scanner, error) {
+	for _, scanner := range ad.scanners {
+		if scanner.Name() == name {
+			return scanner, nil
+		}
+	}
+	return nil, fmt.Errorf("%s scanner not registered", name)
+}
+
+func (ad *AttackDetector) bluetoothScanner() (*scanners.BluetoothScanner, error) {
+	scanner, err := ad.findScanner("bluetooth")
+	if err != nil {
+		return nil, err
+	}
+	bluetoothScanner, ok := scanner.(*scanners.BluetoothScanner)
+	if !ok {
+		return nil, fmt.Errorf("bluetooth scanner has unexpected type %T", scanner)
+	}
+	return bluetoothScanner, nil
+}
+
+func (ad *AttackDetector) wifiScanner() (*scanners.WiFiScanner, error) {
+	scanner, err := ad.findScanner("wifi")
+	if err != nil {
+		return nil, err
+	}
+	wifiScanner, ok := scanner.(*scanners.WiFiScanner)
+	if !ok {
+		return nil, fmt.Errorf("wifi scanner has unexpected type %T", scanner)
+	}
+	return wifiScanner, nil
+}
+
+// updateAnomalyDetectorFrom feeds generic scan results to the typed anomaly trackers
+func (ad *AttackDetector) updateAnomalyDetectorFrom(devices []interface{}) {
+	var networkDevices []models.NetworkDevice
+	var bluetoothDevices []models.BluetoothDevice
+
+	for _, device := range devices {
+		switch d := device.(type) {
+		case models.NetworkDevice:
+			networkDevices = append(networkDevices, d)
+		case models.BluetoothDevice:
+			bluetoothDevices = append(bluetoothDevices, d)
+		}
+	}
+
+	if len(networkDevices) > 0 {
+		ad.updateAnomalyDetector(networkDevices)
+	}
+	if len(bluetoothDevices) > 0 {
+		ad.updateBluetoothAnomalyDetector(bluetoothDevices)
+	}
+}
+
+// detectPortChanges compares port-scanned network devices with the port baseline
+func (ad *AttackDetector) detectPortChanges(devices []interface{}) []models.Attack {
+	var attacks []models.Attack
+	updated := false
+
+	for _, device := range devices {
+		networkDevice, ok := device.(models.NetworkDevice)
+		if !ok || !networkDevice.PortsScanned {
+			continue
+		}
+		attacks = append(attacks, ad.portBaseline.update(networkDevice)...)
+		updated = true
+	}
+
+	if updated {
+		if err := ad.portBaseline.save(); err != nil {
+			ad.logger.LogError("Failed to save port baseline", err)
+		}
+	}
+
+	return attacks
+}
+
 func (ad *AttackDetector) displayBluetoothDevices(devices []models.BluetoothDevice) {
 	if len(devices) == 0 {
 		fmt.Println("\033[33mNo Bluetooth devices found nearby.\033[0m")
@@ -483,9 +605,40 @@ func (ad *AttackDetector) GetRecentAttacks(limit int) []models.Attack {
 	return ad.attackLog[start:]
 }
 
-// Close shuts down the attack detector and cleans up resources
+// Close stops scanning and cleans up resources, including scanners that hold
+// resources such as monitor interfaces they created. It cancels the scan in
+// progress and waits for it to return before closing anything, and may be
+// called more than once and from several goroutines.
 func (ad *AttackDetector) Close() error {
-	return ad.logger.Close()
+	ad.cancel()
+
+	ad.closeOnce.Do(func() {
+		// Scans hold mu, so taking it waits for the one in progress
+		ad.mu.Lock()
+		defer ad.mu.Unlock()
+		ad.closeErr = ad.close()
+	})
+	return ad.closeErr
+}
+
+// close releases the scanners and the logger. Callers hold mu.
+func (ad *AttackDetector) close() error {
+	var errs []string
+	for _, scanner := range ad.scanners {
+		if closer, ok := scanner.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				errs = append(errs, fmt.Sprintf("%s scanner: %v", scanner.Name(), err))
+			}
+		}
+	}
+	if err := ad.logger.Close(); err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("close failed: %s", strings.Join(errs, "; "))
+	}
+	return nil
 }
 
 // Utility functions
@@ -0,0 +1,176 @@
+package detector
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+)
+
+// portBaseline remembers the open ports and services last seen on each device,
+// keyed by MAC address when it is known and by IP address otherwise
+type portBaseline struct {
+	filename string
+	devices  map[string]map[string]models.Port
+}
+
+// loadPortBaseline reads the baseline file, starting empty if it does not exist
+func loadPortBaseline(filename string) (*portBaseline, error) {
+	baseline := &portBaseline{
+		filename: filename,
+		devices:  make(map[string]map[string]models.Port),
+	}
+	if filename == "" {
+		return baseline, nil
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return baseline, nil
+		}
+		return nil, err
+	}
+
+	var stored map[string][]models.Port
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("invalid port baseline %s: %v", filename, err)
+	}
+	for key, ports := range stored {
+		baseline.devices[key] = portsByKey(ports)
+	}
+	return baseline, nil
+}
+
+// save writes the baseline back to its file
+func (b *portBaseline) save() error {
+	if b.filename == "" {
+		return nil
+	}
+
+	stored := make(map[string][]models.Port, len(b.devices))
+	for key, ports := range b.devices {
+		list := make([]models.Port, 0, len(ports))
+		for _, port := range ports {
+			list = append(list, port)
+		}
+		sort.Slice(list, func(i, j int) bool { return list[i].Number < list[j].Number })
+		stored[key] = list
+	}
+
+	data, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(b.filename), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(b.filename, data, 0644)
+}
+
+// portsByKey indexes open ports by "protocol/number"
+func portsByKey(ports []models.Port) map[string]models.Port {
+	indexed := make(map[string]models.Port)
+	for _, port := range ports {
+		if port.State != "" && port.State != "open" {
+			continue
+		}
+		indexed[fmt.Sprintf("%s/%d", port.Protocol, port.Number)] = port
+	}
+	return indexed
+}
+
+// serviceChange describes how a port's service differs from the baseline, or ""
+func serviceChange(before, after models.Port) string {
+	var changes []string
+	if before.Service != "" && after.Service != "" && before.Service != after.Service {
+		changes = append(changes, fmt.Sprintf("service %s -> %s", before.Service, after.Service))
+	}
+	if before.Product != "" && after.Product != "" && before.Product != after.Product {
+		changes = append(changes, fmt.Sprintf("product %s -> %s", before.Product, after.Product))
+	}
+	if before.Version != "" && after.Version != "" && before.Version != after.Version {
+		changes = append(changes, fmt.Sprintf("version %s -> %s", before.Version, after.Version))
+	}
+	if before.Banner != "" && after.Banner != "" && before.Banner != after.Banner {
+		changes = append(changes, fmt.Sprintf("banner %q -> %q", before.Banner, after.Banner))
+	}
+	return strings.Join(changes, ", ")
+}
+
+// baselineKey identifies a device in the baseline, so that a device keeps its
+// baseline when DHCP hands it a different address
+func baselineKey(device models.NetworkDevice) string {
+	if device.MAC != "" {
+		return strings.ToUpper(device.MAC)
+	}
+	return device.IP
+}
+
+// update compares a port-scanned device with its baseline and records the new state.
+// A device seen for the first time only seeds the baseline.
+func (b *portBaseline) update(device models.NetworkDevice) []models.Attack {
+	key := baselineKey(device)
+	current := portsByKey(device.Ports)
+	previous, known := b.devices[key]
+	if !known && key != device.IP {
+		// Entries recorded before the MAC was known move to the MAC key
+		previous, known = b.devices[device.IP]
+		delete(b.devices, device.IP)
+	}
+	b.devices[key] = current
+
+	if !known {
+		return nil
+	}
+
+	var attacks []models.Attack
+	now := time.Now()
+
+	for key, port := range current {
+		before, existed := previous[key]
+		if !existed {
+			attacks = append(attacks, models.Attack{
+				Type:        "NEW_OPEN_PORT",
+				Severity:    models.SeverityMedium,
+				Description: fmt.Sprintf("New open port on %s: %s (%s)", device.IP, key, port.Service),
+				Target:      device.IP,
+				Timestamp:   now,
+			})
+			continue
+		}
+		if change := serviceChange(before, port); change != "" {
+			attacks = append(attacks, models.Attack{
+				Type:        "SERVICE_CHANGED",
+				Severity:    models.SeverityMedium,
+				Description: fmt.Sprintf("Service on %s %s changed: %s", device.IP, key, change),
+				Target:      device.IP,
+				Timestamp:   now,
+			})
+		}
+	}
+
+	for key, port := range previous {
+		if _, open := current[key]; !open {
+			attacks = append(attacks, models.Attack{
+				Type:        "PORT_CLOSED",
+				Severity:    models.SeverityLow,
+				Description: fmt.Sprintf("Previously open port on %s is now closed: %s (%s)", device.IP, key, port.Service),
+				Target:      device.IP,
+				Timestamp:   now,
+			})
+		}
+	}
+
+	sort.Slice(attacks, func(i, j int) bool { return attacks[i].Description < attacks[j].Description })
+	for i := range attacks {
+		attacks[i].Vendor = device.Vendor
+		attacks[i].DeviceClass = device.Class
+	}
+	return attacks
+}
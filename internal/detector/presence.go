@@ -0,0 +1,125 @@
+package detector
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+)
+
+// sighting is one device observed during a scan pass
+type sighting struct {
+	ID     string
+	Vendor string
+	Class  string
+}
+
+// presenceTracker follows when each known device of one kind was last seen
+type presenceTracker struct {
+	kind     string
+	grace    time.Duration
+	names    map[string]string
+	lastSeen map[string]time.Time
+	missing  map[string]bool
+	details  map[string]sighting
+}
+
+// newPresenceTracker starts tracking the known devices as if all were just seen,
+// so a device absent from startup is reported once the grace period has passed
+func newPresenceTracker(kind string, grace time.Duration, known []string) *presenceTracker {
+	tracker := &presenceTracker{
+		kind:     kind,
+		grace:    grace,
+		names:    make(map[string]string),
+		lastSeen: make(map[string]time.Time),
+		missing:  make(map[string]bool),
+		details:  make(map[string]sighting),
+	}
+
+	now := time.Now()
+	for _, id := range known {
+		key := strings.ToLower(id)
+		tracker.names[key] = id
+		tracker.lastSeen[key] = now
+	}
+	return tracker
+}
+
+// update records the devices seen in a successful scan pass. It returns
+// DEVICE_RETURNED for missing devices that came back and DEVICE_MISSING for
+// those unseen for longer than the grace period.
+func (t *presenceTracker) update(seen []sighting, now time.Time) []models.Attack {
+	var attacks []models.Attack
+
+	for _, device := range seen {
+		key := strings.ToLower(device.ID)
+		lastSeen, known := t.lastSeen[key]
+		if !known {
+			continue
+		}
+
+		if t.missing[key] {
+			attacks = append(attacks, models.Attack{
+				Type:        "DEVICE_RETURNED",
+				Severity:    models.SeverityLow,
+				Description: fmt.Sprintf("Known %s device %s is back after %s", t.kind, t.names[key], now.Sub(lastSeen).Round(time.Second)),
+				Target:      t.names[key],
+				Timestamp:   now,
+				Vendor:      device.Vendor,
+				DeviceClass: device.Class,
+			})
+			delete(t.missing, key)
+		}
+
+		t.lastSeen[key] = now
+		t.details[key] = device
+	}
+
+	if t.grace <= 0 {
+		return attacks
+	}
+
+	keys := make([]string, 0, len(t.lastSeen))
+	for key := range t.lastSeen {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		lastSeen := t.lastSeen[key]
+		if t.missing[key] || now.Sub(lastSeen) <= t.grace {
+			continue
+		}
+
+		t.missing[key] = true
+		attacks = append(attacks, models.Attack{
+			Type:        "DEVICE_MISSING",
+			Severity:    models.SeverityMedium,
+			Description: fmt.Sprintf("Known %s device %s not seen for %s (last seen %s)", t.kind, t.names[key], now.Sub(lastSeen).Round(time.Second), lastSeen.Format("2006-01-02 15:04:05")),
+			Target:      t.names[key],
+			Timestamp:   now,
+			Vendor:      t.details[key].Vendor,
+			DeviceClass: t.details[key].Class,
+		})
+	}
+
+	return attacks
+}
+
+// sightingsFrom extracts the identity of every device in a scan result
+func sightingsFrom(devices []interface{}) []sighting {
+	var seen []sighting
+	for _, device := range devices {
+		switch d := device.(type) {
+		case models.NetworkDevice:
+			seen = append(seen, sighting{ID: d.IP, Vendor: d.Vendor, Class: d.Class})
+		case models.BluetoothDevice:
+			seen = append(seen, sighting{ID: d.Address, Vendor: d.Vendor, Class: d.Class})
+		case models.WiFiDevice:
+			seen = append(seen, sighting{ID: d.Address})
+		}
+	}
+	return seen
+}
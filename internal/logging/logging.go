@@ -138,6 +138,9 @@ func (c *ConsoleLogger) DisplayAttack(attack *models.Attack) {
 		attack.Severity.String(), attack.Type, resetColor)
 	fmt.Printf("%sDescription:%s %s\n", models.ColorBold, resetColor, attack.Description)
 	fmt.Printf("%sTarget:%s %s\n", models.ColorBold, resetColor, attack.Target)
+	if attack.Vendor != "" || attack.DeviceClass != "" {
+		fmt.Printf("%sDevice:%s %s %s\n", models.ColorBold, resetColor, attack.Vendor, attack.DeviceClass)
+	}
 	fmt.Printf("%sTime:%s %s\n", models.ColorBold, resetColor,
 		attack.Timestamp.Format("2006-01-02 15:04:05"))
 }
@@ -192,6 +195,6 @@ func (c *ConsoleLogger) DisplayMenu() {
 		models.ColorGreen, models.ColorReset)
 	fmt.Printf("  7. %sExit%s\n",
 		models.ColorRed, models.ColorReset)
-	fmt.Printf("%s==========================================%s\n",
+	fmt.Printf("%s%s==========================================%s\n",
 		models.ColorPurple, models.ColorBold, models.ColorReset)
 }
@@ -1,8 +1,10 @@
 package models
 
 import (
+	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -41,6 +43,20 @@ func (s Severity) String() string {
 	}
 }
 
+// ParseSeverity converts "low", "medium" or "high" into a Severity
+func ParseSeverity(s string) (Severity, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "low":
+		return SeverityLow, nil
+	case "medium":
+		return SeverityMedium, nil
+	case "high":
+		return SeverityHigh, nil
+	default:
+		return SeverityLow, fmt.Errorf("unknown severity: %q", s)
+	}
+}
+
 // Attack represents a detected security threat
 type Attack struct {
 	Type       string    `json:"type"`
@@ -48,40 +64,146 @@ type Attack struct {
 	Description string   `json:"description"`
 	Target     string    `json:"target"`
 	Timestamp  time.Time `json:"timestamp"`
+	Vendor     string    `json:"vendor,omitempty"`
+	DeviceClass string   `json:"device_class,omitempty"`
 }
 
+// Device classes assigned by the scanners' classifier
+const (
+	DeviceClassPhone   = "phone"
+	DeviceClassPrinter = "printer"
+	DeviceClassCamera  = "camera"
+	DeviceClassIoT     = "iot"
+	DeviceClassRouter  = "router"
+)
+
 // NetworkDevice represents a device on the network
 type NetworkDevice struct {
-	IP     string `json:"ip"`
-	MAC    string `json:"mac,omitempty"`
-	Name   string `json:"name,omitempty"`
-	State  string `json:"state,omitempty"`
-	Ports  []Port `json:"ports,omitempty"`
+	IP            string   `json:"ip"`
+	MAC           string   `json:"mac,omitempty"`
+	Vendor        string   `json:"vendor,omitempty"`
+	Class         string   `json:"class,omitempty"`
+	RandomizedMAC bool     `json:"randomized_mac,omitempty"`
+	Name          string      `json:"name,omitempty"`
+	Model         string      `json:"model,omitempty"`
+	Services      []string    `json:"services,omitempty"`
+	UPnP          *UPnPDevice `json:"upnp,omitempty"`
+	State         string      `json:"state,omitempty"`
+	Ports         []Port      `json:"ports,omitempty"`
+	PortsScanned  bool        `json:"ports_scanned,omitempty"`
+	OSGuesses     []string    `json:"os_guesses,omitempty"`
+	Range         string      `json:"range,omitempty"`
+	Interface     string      `json:"interface,omitempty"`
+}
+
+// UPnPDevice is the description a UPnP device publishes at its LOCATION URL
+type UPnPDevice struct {
+	Location     string   `json:"location"`
+	DeviceType   string   `json:"device_type,omitempty"`
+	FriendlyName string   `json:"friendly_name,omitempty"`
+	Manufacturer string   `json:"manufacturer,omitempty"`
+	ModelName    string   `json:"model_name,omitempty"`
+	ModelNumber  string   `json:"model_number,omitempty"`
+	Services     []string `json:"services,omitempty"`
 }
 
 // Port represents an open port on a device
 type Port struct {
-	Number   int    `json:"number"`
-	Protocol string `json:"protocol"`
-	Service  string `json:"service"`
-	State    string `json:"state"`
+	Number       int             `json:"number"`
+	Protocol     string          `json:"protocol"`
+	Service      string          `json:"service"`
+	Tunnel       string          `json:"tunnel,omitempty"` // "ssl" when nmap found the service behind TLS
+	State        string          `json:"state"`
+	Product      string          `json:"product,omitempty"`
+	Version      string          `json:"version,omitempty"`
+	Banner       string          `json:"banner,omitempty"`
+	TLS          *TLSCertificate `json:"tls,omitempty"`
+	DefaultLogin string          `json:"default_login,omitempty"`
+}
+
+// PortPolicy decides which open ports are acceptable on a group of devices.
+// A policy applies to devices inside any of its subnets and of any of its device
+// classes; an empty list matches every device.
+type PortPolicy struct {
+	Name           string     `json:"name"`
+	Subnets        []string   `json:"subnets,omitempty"`
+	DeviceClasses  []string   `json:"device_classes,omitempty"`
+	AllowedPorts   []string   `json:"allowed_ports,omitempty"`
+	ForbiddenPorts []PortRule `json:"forbidden_ports,omitempty"`
+	MaxOpenPorts   int        `json:"max_open_ports,omitempty"`
+	Severity       string     `json:"severity,omitempty"`
+}
+
+// PortRule flags open ports with a service label and severity
+type PortRule struct {
+	Ports    string `json:"ports"`
+	Service  string `json:"service,omitempty"`
+	Severity string `json:"severity"`
+}
+
+// HoneypotListener is a decoy port the honeypot opens and the service it pretends to run
+type HoneypotListener struct {
+	Port    int    `json:"port"`
+	Service string `json:"service"`
+}
+
+// AuthorizedNetwork is an SSID the organization runs and how its access points may look.
+// BSSIDs holds full addresses or OUI prefixes such as "AA:BB:CC"; an empty list leaves
+// that property unchecked.
+type AuthorizedNetwork struct {
+	SSID     string   `json:"ssid"`
+	BSSIDs   []string `json:"bssids,omitempty"`
+	Channels []int    `json:"channels,omitempty"`
+	Security []string `json:"security,omitempty"`
+}
+
+// TLSCertificate describes the certificate a TLS service presented
+type TLSCertificate struct {
+	Subject    string    `json:"subject"`
+	Issuer     string    `json:"issuer"`
+	DNSNames   []string  `json:"dns_names,omitempty"`
+	NotBefore  time.Time `json:"not_before"`
+	NotAfter   time.Time `json:"not_after"`
+	SelfSigned bool      `json:"self_signed"`
 }
 
 // BluetoothDevice represents a Bluetooth device
 type BluetoothDevice struct {
-	Address string `json:"address"`
-	Name    string `json:"name,omitempty"`
-	RSSI    int    `json:"rssi,omitempty"`
-	Status  string `json:"status"`
+	Address       string `json:"address"`
+	Name          string `json:"name,omitempty"`
+	RSSI          int    `json:"rssi,omitempty"`
+	Status        string `json:"status"`
+	Vendor        string `json:"vendor,omitempty"`
+	Class         string `json:"class,omitempty"`
+	RandomizedMAC bool   `json:"randomized_mac,omitempty"`
 }
 
 // WiFiDevice represents a WiFi access point or device
 type WiFiDevice struct {
-	Address string `json:"address"`
-	SSID    string `json:"ssid,omitempty"`
-	Signal  string `json:"signal,omitempty"`
-	Channel string `json:"channel,omitempty"`
-	Status  string `json:"status"`
+	Address     string    `json:"address"`
+	SSID        string    `json:"ssid,omitempty"`
+	Signal      int       `json:"signal,omitempty"`    // dBm
+	Frequency   int       `json:"frequency,omitempty"` // MHz
+	Band        string    `json:"band,omitempty"`
+	Channel     string    `json:"channel,omitempty"`
+	Width       int       `json:"width,omitempty"` // MHz
+	Security    string    `json:"security,omitempty"`
+	GroupCipher string    `json:"group_cipher,omitempty"`
+	Ciphers     []string  `json:"ciphers,omitempty"`
+	AKM         []string  `json:"akm,omitempty"`
+	PMF         string    `json:"pmf,omitempty"`
+	WPS         *WPSInfo  `json:"wps,omitempty"`
+	LastSeen    time.Time `json:"last_seen"`
+	Status      string    `json:"status"`
+}
+
+// WPSInfo is the WiFi Protected Setup state an access point advertises
+type WPSInfo struct {
+	Version       string   `json:"version,omitempty"`
+	State         string   `json:"state,omitempty"`
+	Locked        bool     `json:"locked"`
+	ConfigMethods []string `json:"config_methods,omitempty"`
+	DeviceName    string   `json:"device_name,omitempty"`
 }
 
 // KnownDevices contains lists of known/authorized devices
@@ -104,10 +226,77 @@ type ScanResult struct {
 type AttackDetectorConfig struct {
 	KnownDevicesFile        string        `json:"known_devices_file"`
 	BluetoothDevicesFile    string        `json:"bluetooth_devices_file"`
+	WiFiDevicesFile         string        `json:"wifi_devices_file"`
+	PortBaselineFile        string        `json:"port_baseline_file"`
 	LogFile                 string        `json:"log_file"`
 	ScanInterval            time.Duration `json:"scan_interval"`
+	NetworkMissingGrace     time.Duration `json:"network_missing_grace"`
+	WiFiMissingGrace        time.Duration `json:"wifi_missing_grace"`
+	BluetoothMissingGrace   time.Duration `json:"bluetooth_missing_grace"`
 	AnomalyThreshold        float64       `json:"anomaly_threshold"`
 	WebServerPort           int           `json:"web_server_port"`
+	ScanTargets             []string      `json:"scan_targets"`
+	ScanExclusions          []string      `json:"scan_exclusions"`
+	ScanIPv6                bool          `json:"scan_ipv6"`
+	IPv6AuthorizedRouters   []string      `json:"ipv6_authorized_routers"`
+	IPv6AuthorizedPrefixes  []string      `json:"ipv6_authorized_prefixes"`
+	PortScanPorts           []string      `json:"port_scan_ports"`
+	PortScanTimeout         time.Duration `json:"port_scan_timeout"`
+	PortScanWorkers         int           `json:"port_scan_workers"`
+	PortScanRate            int           `json:"port_scan_rate"`
+	PortScanHostRate        int           `json:"port_scan_host_rate"`
+	PortPolicies            []PortPolicy  `json:"port_policies"`
+	GrabBanners             bool          `json:"grab_banners"`
+	BannerTimeout           time.Duration `json:"banner_timeout"`
+	MinOpenSSHVersion       string        `json:"min_openssh_version"`
+	CheckDefaultCredentials bool          `json:"check_default_credentials"`
+	DefaultCredentials      []string      `json:"default_credentials"`
+	OUIDatabaseFile         string        `json:"oui_database_file"`
+	NmapServiceVersions     bool          `json:"nmap_service_versions"`
+	NmapOSDetection         bool          `json:"nmap_os_detection"`
+	GatewayMACs             []string      `json:"gateway_macs"`
+	ARPMaxIPsPerMAC         int           `json:"arp_max_ips_per_mac"`
+	ARPListener             bool          `json:"arp_listener"`
+	ARPListenInterfaces     []string      `json:"arp_listen_interfaces"`
+	ARPFloodThreshold       int           `json:"arp_flood_threshold"`
+	DHCPInterfaces          []string      `json:"dhcp_interfaces"`
+	DHCPAuthorizedServers   []string      `json:"dhcp_authorized_servers"`
+	DHCPTimeout             time.Duration `json:"dhcp_timeout"`
+	PoisoningProbe          bool          `json:"poisoning_probe"`
+	PoisoningInterfaces     []string      `json:"poisoning_interfaces"`
+	PoisoningDecoyNames     []string      `json:"poisoning_decoy_names"`
+	PoisoningTimeout        time.Duration `json:"poisoning_timeout"`
+	PassiveDiscovery        bool          `json:"passive_discovery"`
+	DiscoveryInterfaces     []string      `json:"discovery_interfaces"`
+	UPnPPortMappingAllowed  []string      `json:"upnp_port_mapping_allowed"`
+	InboundScanDetection    bool          `json:"inbound_scan_detection"`
+	InboundInterfaces       []string      `json:"inbound_interfaces"`
+	InboundScanWindow       time.Duration `json:"inbound_scan_window"`
+	InboundPortThreshold    int           `json:"inbound_port_threshold"`
+	InboundSweepThreshold   int           `json:"inbound_sweep_threshold"`
+	InboundIgnore           []string      `json:"inbound_ignore"`
+	Honeypot                bool          `json:"honeypot"`
+	HoneypotAddress         string        `json:"honeypot_address"`
+	HoneypotListeners       []HoneypotListener `json:"honeypot_listeners"`
+	HoneypotLogFile         string        `json:"honeypot_log_file"`
+	WiFiScanInterfaces      []string      `json:"wifi_scan_interfaces"`
+	WiFiMonitorInterface    string        `json:"wifi_monitor_interface"`
+	WiFiMonitorPhy          string        `json:"wifi_monitor_phy"`
+	WiFiHopBands            []string      `json:"wifi_hop_bands"`
+	WiFiHopChannels         []int         `json:"wifi_hop_channels"`
+	WiFiHopDwell            time.Duration `json:"wifi_hop_dwell"`
+	WiFiCaptureFile         string        `json:"wifi_capture_file"`
+	DeauthWindow            time.Duration `json:"deauth_window"`
+	DeauthThreshold         int           `json:"deauth_threshold"`
+	KarmaWindow             time.Duration `json:"karma_window"`
+	KarmaSSIDThreshold      int           `json:"karma_ssid_threshold"`
+	AuthorizedNetworks      []AuthorizedNetwork `json:"authorized_networks"`
+	NativeProbe             bool          `json:"native_probe"`
+	NativeProbePorts        []int         `json:"native_probe_ports"`
+	NativeProbeTimeout      time.Duration `json:"native_probe_timeout"`
+	NativeProbeWorkers      int           `json:"native_probe_workers"`
+	CommandMode             string        `json:"command_mode"`
+	FixturesDir             string        `json:"fixtures_dir"`
 }
 
 // DefaultConfig returns default configuration
@@ -118,13 +307,106 @@ func DefaultConfig() *AttackDetectorConfig {
 			port = p
 		}
 	}
+	commandMode := "live"
+	if mode := os.Getenv("APP_COMMAND_MODE"); mode != "" {
+		commandMode = mode
+	}
+	fixturesDir := "fixtures"
+	if dir := os.Getenv("APP_FIXTURES_DIR"); dir != "" {
+		fixturesDir = dir
+	}
 	return &AttackDetectorConfig{
 		KnownDevicesFile:        "model/known_devices.json",
 		BluetoothDevicesFile:    "model/known_bluetooth_devices.json",
+		WiFiDevicesFile:         "model/known_wifi_devices.json",
+		PortBaselineFile:        "model/port_baseline.json",
 		LogFile:                 "log/intrusion_log.log",
 		ScanInterval:            60 * time.Second,
+		NetworkMissingGrace:     5 * time.Minute,
+		WiFiMissingGrace:        10 * time.Minute,
+		BluetoothMissingGrace:   10 * time.Minute,
 		AnomalyThreshold:        2.0,
 		WebServerPort:           port,
+		ScanTargets:             []string{"auto"},
+		ScanExclusions:          []string{},
+		ScanIPv6:                true,
+		IPv6AuthorizedRouters:   []string{},
+		IPv6AuthorizedPrefixes:  []string{},
+		PortScanPorts:           []string{"21-23", "25", "53", "80", "110", "143", "443", "445", "993", "995", "3389"},
+		PortScanTimeout:         time.Second,
+		PortScanWorkers:         128,
+		PortScanRate:            500,
+		PortScanHostRate:        50,
+		PortPolicies: []PortPolicy{
+			{
+				Name: "default",
+				ForbiddenPorts: []PortRule{
+					{Ports: "21", Service: "FTP", Severity: "medium"},
+					{Ports: "23", Service: "Telnet", Severity: "medium"},
+					{Ports: "3389", Service: "RDP", Severity: "medium"},
+					{Ports: "445", Service: "SMB", Severity: "medium"},
+				},
+				MaxOpenPorts: 5,
+				Severity:     "medium",
+			},
+		},
+		GrabBanners:             false,
+		BannerTimeout:           3 * time.Second,
+		MinOpenSSHVersion:       "8.0",
+		CheckDefaultCredentials: false,
+		DefaultCredentials:      []string{"admin:admin", "admin:password", "admin:", "admin:1234", "root:root", "user:user"},
+		OUIDatabaseFile:         "",
+		NmapServiceVersions:     true,
+		NmapOSDetection:         false,
+		GatewayMACs:             []string{},
+		ARPMaxIPsPerMAC:         3,
+		ARPListener:             false,
+		ARPListenInterfaces:     []string{},
+		ARPFloodThreshold:       30,
+		DHCPInterfaces:          []string{},
+		DHCPAuthorizedServers:   []string{},
+		DHCPTimeout:             3 * time.Second,
+		PoisoningProbe:          false,
+		PoisoningInterfaces:     []string{},
+		PoisoningDecoyNames:     []string{},
+		PoisoningTimeout:        2 * time.Second,
+		PassiveDiscovery:        false,
+		DiscoveryInterfaces:     []string{},
+		UPnPPortMappingAllowed:  []string{},
+		InboundScanDetection:    false,
+		InboundInterfaces:       []string{},
+		InboundScanWindow:       time.Minute,
+		InboundPortThreshold:    15,
+		InboundSweepThreshold:   30,
+		InboundIgnore:           []string{},
+		Honeypot:                false,
+		HoneypotAddress:         "",
+		HoneypotListeners: []HoneypotListener{
+			{Port: 23, Service: "telnet"},
+			{Port: 2222, Service: "ssh"},
+			{Port: 445, Service: "smb"},
+			{Port: 3389, Service: "rdp"},
+			{Port: 8081, Service: "http"},
+		},
+		HoneypotLogFile:         "log/honeypot.log",
+		WiFiScanInterfaces:      []string{},
+		WiFiMonitorInterface:    "",
+		WiFiMonitorPhy:          "",
+		WiFiHopBands:            []string{},
+		WiFiHopChannels:         []int{},
+		WiFiHopDwell:            500 * time.Millisecond,
+		WiFiCaptureFile:         "",
+		DeauthWindow:            30 * time.Second,
+		DeauthThreshold:         10,
+		KarmaWindow:             5 * time.Minute,
+		KarmaSSIDThreshold:      3,
+		AuthorizedNetworks:      []AuthorizedNetwork{},
+		NativeProbe:             true,
+		NativeProbePorts:        []int{80, 443, 22, 445, 139, 8080},
+		NativeProbeTimeout:      500 * time.Millisecond,
+		NativeProbeWorkers:      64,
+		CommandMode:             commandMode,
+		FixturesDir:             fixturesDir,
 	}
 }
 
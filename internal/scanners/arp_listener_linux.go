@@ -0,0 +1,76 @@
+//go:build linux
+
+package scanners
+
+import (
+	"context"
+	"net"
+	"syscall"
+	"time"
+)
+
+// arpReadTimeout bounds each read so the listener notices cancellation
+const arpReadTimeout = time.Second
+
+// htons converts a 16-bit value to network byte order
+func htons(v uint16) uint16 {
+	return v<<8 | v>>8
+}
+
+// listenARP reads ARP packets from an AF_PACKET socket until ctx is done, passing
+// each to handle with the interface it arrived on. An empty interface list listens
+// on every interface. It needs CAP_NET_RAW.
+func listenARP(ctx context.Context, interfaces []string, handle func(arpPacket, string)) error {
+	fd, err := syscall.Socket(syscall.AF_PACKET, syscall.SOCK_DGRAM, int(htons(syscall.ETH_P_ARP)))
+	if err != nil {
+		return err
+	}
+	defer syscall.Close(fd)
+
+	timeout := syscall.NsecToTimeval(arpReadTimeout.Nanoseconds())
+	if err := syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &timeout); err != nil {
+		return err
+	}
+
+	wanted := make(map[int]bool)
+	for _, name := range interfaces {
+		ifi, err := net.InterfaceByName(name)
+		if err != nil {
+			return err
+		}
+		wanted[ifi.Index] = true
+	}
+
+	names := make(map[int]string)
+	buf := make([]byte, 1500)
+	for ctx.Err() == nil {
+		n, from, err := syscall.Recvfrom(fd, buf, 0)
+		if err != nil {
+			if err == syscall.EAGAIN || err == syscall.EINTR {
+				continue
+			}
+			return err
+		}
+
+		link, ok := from.(*syscall.SockaddrLinklayer)
+		if !ok || (len(wanted) > 0 && !wanted[link.Ifindex]) {
+			continue
+		}
+
+		packet, ok := parseARPPacket(buf[:n])
+		if !ok {
+			continue
+		}
+
+		name, ok := names[link.Ifindex]
+		if !ok {
+			if ifi, err := net.InterfaceByIndex(link.Ifindex); err == nil {
+				name = ifi.Name
+			}
+			names[link.Ifindex] = name
+		}
+		handle(packet, name)
+	}
+
+	return ctx.Err()
+}
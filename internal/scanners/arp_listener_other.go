@@ -0,0 +1,10 @@
+//go:build !linux
+
+package scanners
+
+import "context"
+
+// listenARP is only implemented on Linux
+func listenARP(ctx context.Context, interfaces []string, handle func(arpPacket, string)) error {
+	return errARPListenerUnsupported
+}
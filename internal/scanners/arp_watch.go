@@ -0,0 +1,365 @@
+package scanners
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+)
+
+// routeTablePath is where the kernel exposes the IPv4 routing table
+const routeTablePath = "/proc/net/route"
+
+// ARP operation codes
+const (
+	arpRequest = 1
+	arpReply   = 2
+)
+
+// arpBindingLifetime is how long an IP to MAC binding is kept without being seen again
+const arpBindingLifetime = 30 * time.Minute
+
+// arpMaxObserved bounds the distinct neighbors and announcing MACs the listener
+// keeps between scans, so a flood of spoofed senders cannot exhaust memory
+const arpMaxObserved = 4096
+
+// errARPListenerUnsupported is returned where AF_PACKET sockets are unavailable
+var errARPListenerUnsupported = errors.New("arp listener not supported on this platform")
+
+// arpPacket is a decoded Ethernet/IPv4 ARP message
+type arpPacket struct {
+	Operation int
+	SenderMAC string
+	SenderIP  string
+	TargetMAC string
+	TargetIP  string
+}
+
+// parseARPPacket decodes an ARP payload without its link-layer header
+func parseARPPacket(data []byte) (arpPacket, bool) {
+	// Hardware type Ethernet (1), protocol IPv4 (0x0800), 6-byte MACs, 4-byte IPs
+	if len(data) < 28 || binary.BigEndian.Uint16(data[0:2]) != 1 ||
+		binary.BigEndian.Uint16(data[2:4]) != 0x0800 || data[4] != 6 || data[5] != 4 {
+		return arpPacket{}, false
+	}
+
+	return arpPacket{
+		Operation: int(binary.BigEndian.Uint16(data[6:8])),
+		SenderMAC: strings.ToUpper(net.HardwareAddr(data[8:14]).String()),
+		SenderIP:  net.IP(data[14:18]).String(),
+		TargetMAC: strings.ToUpper(net.HardwareAddr(data[18:24]).String()),
+		TargetIP:  net.IP(data[24:28]).String(),
+	}, true
+}
+
+// IsGratuitous reports whether the packet announces the sender's own binding
+// rather than asking for or answering a specific host
+func (p arpPacket) IsGratuitous() bool {
+	if p.SenderIP == "0.0.0.0" {
+		// Address conflict probes carry no sender address
+		return false
+	}
+	if p.SenderIP == p.TargetIP {
+		return true
+	}
+	return p.Operation == arpReply && (p.TargetMAC == "FF:FF:FF:FF:FF:FF" || p.TargetMAC == "00:00:00:00:00:00")
+}
+
+// parseDefaultGateways returns the IPv4 default gateway of each interface in /proc/net/route
+func parseDefaultGateways(output string) map[string]string {
+	gateways := make(map[string]string)
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	// Skip header line
+	scanner.Scan()
+
+	for scanner.Scan() {
+		// Iface, Destination, Gateway, Flags, ...
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || fields[1] != "00000000" || fields[2] == "00000000" {
+			continue
+		}
+
+		var gateway uint32
+		if _, err := fmt.Sscanf(fields[2], "%08X", &gateway); err != nil {
+			continue
+		}
+		// Addresses are stored in host byte order, which is little-endian on Linux targets
+		ip := make(net.IP, 4)
+		binary.LittleEndian.PutUint32(ip, gateway)
+		gateways[fields[0]] = ip.String()
+	}
+
+	return gateways
+}
+
+// arpBinding is the MAC an IPv4 address was last seen at
+type arpBinding struct {
+	mac      string
+	lastSeen time.Time
+}
+
+// arpWatch keeps the IP to MAC binding table and what the ARP listener observed
+type arpWatch struct {
+	mu         sync.Mutex
+	started    bool
+	err        error
+	since      time.Time
+	gratuitous map[string]int
+	observed   map[Neighbor]bool
+	dropped    int
+
+	bindings       map[string]arpBinding
+	trustedGateway map[string]string
+	alertedGateway map[string]string
+	flaggedMACs    map[string]bool
+}
+
+func newARPWatch() *arpWatch {
+	return &arpWatch{
+		since:          time.Now(),
+		gratuitous:     make(map[string]int),
+		observed:       make(map[Neighbor]bool),
+		bindings:       make(map[string]arpBinding),
+		trustedGateway: make(map[string]string),
+		alertedGateway: make(map[string]string),
+		flaggedMACs:    make(map[string]bool),
+	}
+}
+
+// record notes one packet seen by the listener
+func (w *arpWatch) record(packet arpPacket, iface string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if packet.SenderIP != "0.0.0.0" {
+		neighbor := Neighbor{IP: packet.SenderIP, MAC: packet.SenderMAC, Interface: iface}
+		if w.observed[neighbor] || len(w.observed) < arpMaxObserved {
+			w.observed[neighbor] = true
+		} else {
+			w.dropped++
+		}
+	}
+	if packet.IsGratuitous() {
+		if _, ok := w.gratuitous[packet.SenderMAC]; ok || len(w.gratuitous) < arpMaxObserved {
+			w.gratuitous[packet.SenderMAC]++
+		} else {
+			w.dropped++
+		}
+	}
+}
+
+// drain returns and resets what the listener collected since the last call
+func (w *arpWatch) drain() (map[string]int, []Neighbor, time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	observed := make([]Neighbor, 0, len(w.observed))
+	for neighbor := range w.observed {
+		observed = append(observed, neighbor)
+	}
+	gratuitous := w.gratuitous
+	elapsed := time.Since(w.since)
+
+	w.gratuitous = make(map[string]int)
+	w.observed = make(map[Neighbor]bool)
+	w.since = time.Now()
+	return gratuitous, observed, elapsed
+}
+
+// listenerError returns why the listener stopped, once, and how many packets
+// it dropped since the last call because the limits were reached
+func (w *arpWatch) listenerError() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var errs []string
+	if w.err != nil {
+		errs = append(errs, w.err.Error())
+	}
+	if w.dropped > 0 {
+		errs = append(errs, fmt.Sprintf("ARP listener dropped %d packets beyond %d senders", w.dropped, arpMaxObserved))
+	}
+	w.err = nil
+	w.dropped = 0
+	if len(errs) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(errs, "; "))
+}
+
+// startARPListener runs the AF_PACKET listener in the background once, until
+// Close. A listener that fails is not restarted; its error is kept for Scan.
+func (ns *NetworkScanner) startARPListener() {
+	ns.arp.mu.Lock()
+	defer ns.arp.mu.Unlock()
+
+	if ns.arp.started {
+		return
+	}
+	ns.arp.started = true
+
+	ctx := ns.listenCtx
+	go func() {
+		err := listenARP(ctx, ns.config.ARPListenInterfaces, ns.arp.record)
+		if ctx.Err() != nil {
+			return
+		}
+		ns.arp.mu.Lock()
+		ns.arp.err = fmt.Errorf("ARP listener stopped: %v", err)
+		ns.arp.mu.Unlock()
+	}()
+}
+
+// DetectARPAttacks refreshes the IP to MAC binding table from the scanned devices,
+// the neighbor table and the ARP listener, then looks for ARP poisoning: a default
+// gateway answering from a new MAC, one MAC claiming many IPv4 addresses, and
+// floods of gratuitous ARP announcements
+func (ns *NetworkScanner) DetectARPAttacks(devices []models.NetworkDevice) []models.Attack {
+	var attacks []models.Attack
+	now := time.Now()
+
+	if ns.config.ARPListener {
+		ns.startARPListener()
+	}
+	gratuitous, observed, elapsed := ns.arp.drain()
+
+	var entries []Neighbor
+	neighbors, _ := ReadNeighborTable()
+	entries = append(entries, neighbors...)
+	entries = append(entries, observed...)
+	for _, device := range devices {
+		if device.MAC != "" {
+			entries = append(entries, Neighbor{IP: device.IP, MAC: device.MAC, Interface: device.Interface})
+		}
+	}
+
+	// Current bindings, and the IPv4 addresses each MAC answers for per interface
+	claims := make(map[string]map[string]bool)
+	for _, entry := range entries {
+		ip := net.ParseIP(entry.IP)
+		if ip == nil || ip.To4() == nil || entry.MAC == "" {
+			continue
+		}
+		ns.arp.bindings[entry.IP] = arpBinding{mac: entry.MAC, lastSeen: now}
+
+		key := entry.MAC + "%" + entry.Interface
+		if claims[key] == nil {
+			claims[key] = make(map[string]bool)
+		}
+		claims[key][entry.IP] = true
+	}
+
+	for ip, binding := range ns.arp.bindings {
+		if now.Sub(binding.lastSeen) > arpBindingLifetime {
+			delete(ns.arp.bindings, ip)
+		}
+	}
+
+	// The default gateway must keep answering from a trusted MAC
+	authorized := make(map[string]bool)
+	for _, mac := range ns.config.GatewayMACs {
+		authorized[strings.ToUpper(mac)] = true
+	}
+	routes, _ := os.ReadFile(routeTablePath)
+	for iface, gateway := range parseDefaultGateways(string(routes)) {
+		binding, ok := ns.arp.bindings[gateway]
+		if !ok {
+			continue
+		}
+		mac := binding.mac
+
+		trusted := ns.arp.trustedGateway[gateway]
+		if trusted == "" && len(authorized) == 0 {
+			ns.arp.trustedGateway[gateway] = mac
+			continue
+		}
+		if authorized[mac] || mac == trusted {
+			delete(ns.arp.alertedGateway, gateway)
+			continue
+		}
+		if ns.arp.alertedGateway[gateway] == mac {
+			continue
+		}
+		ns.arp.alertedGateway[gateway] = mac
+
+		expected := trusted
+		if expected == "" {
+			expected = strings.Join(ns.config.GatewayMACs, ", ")
+		}
+		attacks = append(attacks, models.Attack{
+			Type:        "GATEWAY_MAC_CHANGED",
+			Severity:    models.SeverityHigh,
+			Description: fmt.Sprintf("Default gateway %s on %s now answers from %s%s instead of %s (possible ARP spoofing)", gateway, iface, mac, deviceLabel(ns.vendors.Lookup(mac), "", IsRandomizedMAC(mac)), expected),
+			Target:      gateway,
+			Timestamp:   now,
+		})
+	}
+
+	// One MAC answering for many addresses is the signature of a poisoner
+	if limit := ns.config.ARPMaxIPsPerMAC; limit > 0 {
+		flagged := make(map[string]bool)
+		for key, ips := range claims {
+			if len(ips) <= limit {
+				continue
+			}
+			flagged[key] = true
+			if ns.arp.flaggedMACs[key] {
+				continue
+			}
+
+			mac, iface, _ := strings.Cut(key, "%")
+			list := make([]string, 0, len(ips))
+			for ip := range ips {
+				list = append(list, ip)
+			}
+			sort.Strings(list)
+
+			location := ""
+			if iface != "" {
+				location = " on " + iface
+			}
+			attacks = append(attacks, models.Attack{
+				Type:        "MAC_CLAIMS_MULTIPLE_IPS",
+				Severity:    models.SeverityHigh,
+				Description: fmt.Sprintf("MAC %s%s claims %d IP addresses%s: %s (possible ARP spoofing)", mac, deviceLabel(ns.vendors.Lookup(mac), "", IsRandomizedMAC(mac)), len(list), location, strings.Join(list, ", ")),
+				Target:      mac,
+				Timestamp:   now,
+			})
+		}
+		ns.arp.flaggedMACs = flagged
+	}
+
+	// Gratuitous ARP rates from the listener
+	if threshold := ns.config.ARPFloodThreshold; threshold > 0 && elapsed > 0 {
+		macs := make([]string, 0, len(gratuitous))
+		for mac := range gratuitous {
+			macs = append(macs, mac)
+		}
+		sort.Strings(macs)
+
+		for _, mac := range macs {
+			perMinute := float64(gratuitous[mac]) / elapsed.Minutes()
+			if perMinute < float64(threshold) {
+				continue
+			}
+			attacks = append(attacks, models.Attack{
+				Type:        "GRATUITOUS_ARP_FLOOD",
+				Severity:    models.SeverityHigh,
+				Description: fmt.Sprintf("Gratuitous ARP flood from %s%s: %d announcements in %s (%.0f/min)", mac, deviceLabel(ns.vendors.Lookup(mac), "", IsRandomizedMAC(mac)), gratuitous[mac], elapsed.Round(time.Second), perMinute),
+				Target:      mac,
+				Timestamp:   now,
+			})
+		}
+	}
+
+	return attacks
+}
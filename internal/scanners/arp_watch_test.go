@@ -0,0 +1,44 @@
+package scanners
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestARPWatchBoundsObservations(t *testing.T) {
+	w := newARPWatch()
+
+	// Repeated chatter from one host is kept once, while its announcements are counted
+	announce := arpPacket{Operation: arpReply, SenderMAC: "AA:BB:CC:00:00:01", SenderIP: "10.0.0.1", TargetIP: "10.0.0.1"}
+	for i := 0; i < 100; i++ {
+		w.record(announce, "eth0")
+	}
+	gratuitous, observed, _ := w.drain()
+	if len(observed) != 1 || gratuitous["AA:BB:CC:00:00:01"] != 100 {
+		t.Errorf("got %d neighbors and %d announcements, want 1 and 100", len(observed), gratuitous["AA:BB:CC:00:00:01"])
+	}
+	if err := w.listenerError(); err != nil {
+		t.Errorf("unexpected listener error: %v", err)
+	}
+
+	// A flood of spoofed senders stops at the limit and is reported once
+	for i := 0; i < arpMaxObserved+10; i++ {
+		mac := fmt.Sprintf("02:00:00:00:%02X:%02X", i>>8, i&0xff)
+		ip := fmt.Sprintf("10.1.%d.%d", i>>8, i&0xff)
+		w.record(arpPacket{Operation: arpReply, SenderMAC: mac, SenderIP: ip, TargetIP: ip}, "eth0")
+	}
+	// Senders already seen are still counted
+	w.record(arpPacket{Operation: arpReply, SenderMAC: "02:00:00:00:00:00", SenderIP: "10.1.0.0", TargetIP: "10.1.0.0"}, "eth0")
+
+	gratuitous, observed, _ = w.drain()
+	if len(observed) != arpMaxObserved || len(gratuitous) != arpMaxObserved || gratuitous["02:00:00:00:00:00"] != 2 {
+		t.Errorf("got %d neighbors and %d announcing MACs, want %d each", len(observed), len(gratuitous), arpMaxObserved)
+	}
+	if err := w.listenerError(); err == nil || !strings.Contains(err.Error(), "dropped 20 packets") {
+		t.Errorf("overflow not reported: %v", err)
+	}
+	if err := w.listenerError(); err != nil {
+		t.Errorf("overflow reported twice: %v", err)
+	}
+}
@@ -0,0 +1,413 @@
+package scanners
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+)
+
+// maxBannerLength caps how much of a service greeting is kept
+const maxBannerLength = 256
+
+// tlsPorts are ports that speak TLS from the first byte
+var tlsPorts = map[int]bool{
+	443:  true,
+	465:  true,
+	636:  true,
+	993:  true,
+	995:  true,
+	8443: true,
+}
+
+// httpPorts are plain-text ports that answer HTTP requests
+var httpPorts = map[int]bool{
+	80:   true,
+	81:   true,
+	8000: true,
+	8008: true,
+	8080: true,
+	8081: true,
+	8888: true,
+}
+
+// isTLSPort reports whether the port is expected to speak TLS, including
+// services nmap reports behind an SSL tunnel on non-standard ports
+func isTLSPort(port models.Port) bool {
+	return tlsPorts[port.Number] || port.Tunnel == "ssl" || port.Service == "https" || strings.HasPrefix(port.Service, "ssl")
+}
+
+// isHTTPPort reports whether the port is expected to answer HTTP, with or without TLS
+func isHTTPPort(port models.Port) bool {
+	return httpPorts[port.Number] || port.Number == 443 || port.Number == 8443 ||
+		strings.HasPrefix(port.Service, "http") || port.Service == "https"
+}
+
+// grabBanners connects to every open port of every device and records banners,
+// TLS certificates and, when enabled, default credentials accepted by web panels
+func (ns *NetworkScanner) grabBanners(ctx context.Context, devices []models.NetworkDevice) {
+	type bannerJob struct {
+		device int
+		port   int
+	}
+
+	workers := ns.config.PortScanWorkers
+	if workers <= 0 {
+		workers = 1
+	}
+
+	jobs := make(chan bannerJob)
+	var wg sync.WaitGroup
+
+	// Each job owns a distinct port record, so workers write without locking
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				device := &devices[job.device]
+				ns.inspectPort(ctx, device.IP, &device.Ports[job.port])
+			}
+		}()
+	}
+
+feed:
+	for i := range devices {
+		for j, port := range devices[i].Ports {
+			if port.State != "open" || port.Protocol != "tcp" {
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				break feed
+			case jobs <- bannerJob{device: i, port: j}:
+			}
+		}
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// inspectPort grabs what the service on one port reveals about itself
+func (ns *NetworkScanner) inspectPort(ctx context.Context, ip string, port *models.Port) {
+	timeout := ns.config.BannerTimeout
+	address := net.JoinHostPort(ip, strconv.Itoa(port.Number))
+	tlsPort := isTLSPort(*port)
+
+	if tlsPort {
+		if cert, err := fetchCertificate(ctx, address, timeout); err == nil {
+			port.TLS = cert
+		}
+	}
+
+	if isHTTPPort(*port) {
+		scheme := "http"
+		if tlsPort {
+			scheme = "https"
+		}
+		url := scheme + "://" + address + "/"
+
+		if server, err := fetchHTTPServer(ctx, url, timeout); err == nil {
+			port.Banner = server
+		}
+		if ns.config.CheckDefaultCredentials {
+			port.DefaultLogin = tryDefaultCredentials(ctx, url, ns.config.DefaultCredentials, timeout)
+		}
+		if port.Service == "" {
+			port.Service = scheme
+		}
+		return
+	}
+
+	if tlsPort {
+		return
+	}
+
+	// SSH, FTP and SMTP servers greet the client first
+	if banner, err := readGreeting(ctx, address, timeout); err == nil {
+		port.Banner = banner
+		identifyFromBanner(port)
+	}
+}
+
+// readGreeting returns the first line a service sends after connecting
+func readGreeting(ctx context.Context, address string, timeout time.Duration) (string, error) {
+	dialer := net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	line, err := bufio.NewReaderSize(conn, maxBannerLength).ReadSlice('\n')
+	if len(line) == 0 {
+		return "", err
+	}
+
+	banner := strings.TrimSpace(string(line))
+	if len(banner) > maxBannerLength {
+		banner = banner[:maxBannerLength]
+	}
+	return banner, nil
+}
+
+// identifyFromBanner fills in the service, product and version a greeting announces
+func identifyFromBanner(port *models.Port) {
+	banner := port.Banner
+	switch {
+	case strings.HasPrefix(banner, "SSH-"):
+		port.Service = "ssh"
+		// "SSH-2.0-OpenSSH_8.9p1 Ubuntu-3"
+		fields := strings.SplitN(banner, "-", 3)
+		if len(fields) == 3 && port.Product == "" {
+			software := strings.Fields(fields[2])
+			if len(software) > 0 {
+				name, version, _ := strings.Cut(software[0], "_")
+				port.Product = name
+				port.Version = version
+			}
+		}
+	case strings.HasPrefix(banner, "220") && port.Service == "":
+		if port.Number == 21 || strings.Contains(strings.ToUpper(banner), "FTP") {
+			port.Service = "ftp"
+		} else {
+			port.Service = "smtp"
+		}
+	}
+}
+
+// fetchCertificate completes a TLS handshake and describes the server's leaf certificate
+func fetchCertificate(ctx context.Context, address string, timeout time.Duration) (*models.TLSCertificate, error) {
+	dialer := tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: timeout},
+		// The certificate is inspected, not trusted
+		Config: &tls.Config{InsecureSkipVerify: true},
+	}
+
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	certs := conn.(*tls.Conn).ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no certificate presented by %s", address)
+	}
+
+	leaf := certs[0]
+	return &models.TLSCertificate{
+		Subject:    leaf.Subject.String(),
+		Issuer:     leaf.Issuer.String(),
+		DNSNames:   leaf.DNSNames,
+		NotBefore:  leaf.NotBefore,
+		NotAfter:   leaf.NotAfter,
+		SelfSigned: isSelfSigned(leaf),
+	}, nil
+}
+
+// isSelfSigned reports whether a certificate is signed by its own key
+func isSelfSigned(cert *x509.Certificate) bool {
+	if cert.Issuer.String() != cert.Subject.String() {
+		return false
+	}
+	return cert.CheckSignatureFrom(cert) == nil
+}
+
+// newInspectionClient returns an HTTP client that accepts any certificate and never follows redirects
+func newInspectionClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+			DisableKeepAlives: true,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// fetchHTTPServer returns the Server header of a web service
+func fetchHTTPServer(ctx context.Context, url string, timeout time.Duration) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := newInspectionClient(timeout).Do(req)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+
+	return resp.Header.Get("Server"), nil
+}
+
+// tryDefaultCredentials returns the first "user:password" pair a Basic-auth panel
+// accepts, or "" when the page is not protected or none are accepted
+func tryDefaultCredentials(ctx context.Context, url string, credentials []string, timeout time.Duration) string {
+	client := newInspectionClient(timeout)
+
+	status := func(user, password string, auth bool) int {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return 0
+		}
+		if auth {
+			req.SetBasicAuth(user, password)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return 0
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if status("", "", false) != http.StatusUnauthorized {
+		return ""
+	}
+
+	for _, credential := range credentials {
+		user, password, _ := strings.Cut(credential, ":")
+		if code := status(user, password, true); code >= 200 && code < 400 {
+			return credential
+		}
+	}
+	return ""
+}
+
+// DetectServiceIssues applies the banner and certificate rules to a scanned device:
+// expired or self-signed certificates, outdated SSH servers and web panels that
+// accept default credentials
+func (ns *NetworkScanner) DetectServiceIssues(device models.NetworkDevice) []models.Attack {
+	var attacks []models.Attack
+	now := time.Now()
+
+	for _, port := range device.Ports {
+		endpoint := net.JoinHostPort(device.IP, strconv.Itoa(port.Number))
+
+		if cert := port.TLS; cert != nil {
+			if now.After(cert.NotAfter) {
+				attacks = append(attacks, models.Attack{
+					Type:        "EXPIRED_TLS_CERTIFICATE",
+					Severity:    models.SeverityMedium,
+					Description: fmt.Sprintf("TLS certificate on %s expired %s (subject: %s)", endpoint, cert.NotAfter.Format("2006-01-02"), cert.Subject),
+					Target:      device.IP,
+					Timestamp:   now,
+				})
+			}
+			if cert.SelfSigned {
+				attacks = append(attacks, models.Attack{
+					Type:        "SELF_SIGNED_TLS_CERTIFICATE",
+					Severity:    models.SeverityLow,
+					Description: fmt.Sprintf("Self-signed TLS certificate on %s (subject: %s)", endpoint, cert.Subject),
+					Target:      device.IP,
+					Timestamp:   now,
+				})
+			}
+		}
+
+		if strings.HasPrefix(port.Banner, "SSH-") {
+			if reason := ns.outdatedSSH(port.Banner); reason != "" {
+				attacks = append(attacks, models.Attack{
+					Type:        "OUTDATED_SSH_VERSION",
+					Severity:    models.SeverityMedium,
+					Description: fmt.Sprintf("Outdated SSH server on %s: %s (%s)", endpoint, port.Banner, reason),
+					Target:      device.IP,
+					Timestamp:   now,
+				})
+			}
+		}
+
+		if port.DefaultLogin != "" {
+			user, _, _ := strings.Cut(port.DefaultLogin, ":")
+			attacks = append(attacks, models.Attack{
+				Type:        "DEFAULT_CREDENTIALS",
+				Severity:    models.SeverityHigh,
+				Description: fmt.Sprintf("Admin panel on %s accepts default credentials for user %q", endpoint, user),
+				Target:      device.IP,
+				Timestamp:   now,
+			})
+		}
+	}
+
+	return attacks
+}
+
+// outdatedSSH explains why an SSH banner is outdated, or returns ""
+func (ns *NetworkScanner) outdatedSSH(banner string) string {
+	fields := strings.SplitN(banner, "-", 3)
+	if len(fields) < 3 {
+		return ""
+	}
+
+	// "SSH-1.99" still accepts protocol 1 clients
+	if fields[1] != "2.0" {
+		return "supports SSH protocol 1"
+	}
+
+	software := strings.Fields(fields[2])
+	if len(software) == 0 || ns.config.MinOpenSSHVersion == "" {
+		return ""
+	}
+	name, version, found := strings.Cut(software[0], "_")
+	if !found || name != "OpenSSH" {
+		return ""
+	}
+	if compareVersions(version, ns.config.MinOpenSSHVersion) < 0 {
+		return "older than OpenSSH " + ns.config.MinOpenSSHVersion
+	}
+	return ""
+}
+
+// compareVersions compares the leading dotted numbers of two versions, so "7.4p1" < "8.0"
+func compareVersions(a, b string) int {
+	numbers := func(v string) []int {
+		var parts []int
+		for _, part := range strings.Split(v, ".") {
+			end := 0
+			for end < len(part) && part[end] >= '0' && part[end] <= '9' {
+				end++
+			}
+			n, err := strconv.Atoi(part[:end])
+			if err != nil {
+				break
+			}
+			parts = append(parts, n)
+			if end < len(part) {
+				break
+			}
+		}
+		return parts
+	}
+
+	pa, pb := numbers(a), numbers(b)
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
@@ -2,10 +2,10 @@ package scanners
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
-	"os/exec"
 	"regexp"
 	"strconv"
 	"strings"
@@ -17,55 +17,86 @@ import (
 // BluetoothScanner handles Bluetooth device discovery and attack detection
 type BluetoothScanner struct {
 	knownDevices map[string]bool
+	runner       CommandRunner
+	vendors      *VendorDB
 }
 
 // NewBluetoothScanner creates a new Bluetooth scanner
-func NewBluetoothScanner(knownDevices []models.BluetoothDevice) *BluetoothScanner {
+func NewBluetoothScanner(knownDevices []models.BluetoothDevice, runner CommandRunner, vendors *VendorDB) *BluetoothScanner {
 	knownMap := make(map[string]bool)
 	for _, device := range knownDevices {
 		knownMap[device.Address] = true
 	}
 	return &BluetoothScanner{
 		knownDevices: knownMap,
+		runner:       runner,
+		vendors:      vendors,
 	}
 }
 
+// Name returns the registry name of the Bluetooth scanner
+func (bs *BluetoothScanner) Name() string {
+	return "bluetooth"
+}
+
+// Available reports whether a Bluetooth scanning tool is installed
+func (bs *BluetoothScanner) Available() bool {
+	return isCommandAvailable(bs.runner, "bluetoothctl")
+}
+
+// Scan discovers nearby Bluetooth devices and analyzes them for attacks
+func (bs *BluetoothScanner) Scan(ctx context.Context) ([]interface{}, []models.Attack, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	devices, err := bs.ScanBluetoothDevices(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	attacks := bs.DetectBluetoothAttacks(devices)
+	annotateBluetoothAttacks(attacks, devices)
+
+	return toInterfaces(devices), attacks, nil
+}
+
 // ScanBluetoothDevices discovers nearby Bluetooth devices
-func (bs *BluetoothScanner) ScanBluetoothDevices() ([]models.BluetoothDevice, error) {
-	if !isCommandAvailable("bluetoothctl") {
+func (bs *BluetoothScanner) ScanBluetoothDevices(ctx context.Context) ([]models.BluetoothDevice, error) {
+	if !isCommandAvailable(bs.runner, "bluetoothctl") {
 		// Try other methods or return empty
 		return []models.BluetoothDevice{}, fmt.Errorf("bluetoothctl not available")
 	}
 
 	// Use bluetoothctl to scan for devices
-	devices, err := bs.scanWithBluetoothctl()
+	devices, err := bs.scanWithBluetoothctl(ctx)
 	if err != nil {
 		// Fallback to other methods if available
-		devices, err = bs.scanWithHcitool()
+		devices, err = bs.scanWithHcitool(ctx)
 		if err != nil {
 			return nil, fmt.Errorf("no Bluetooth scanning method available: %v", err)
 		}
 	}
 
+	for i := range devices {
+		classifyBluetoothDevice(&devices[i], bs.vendors)
+	}
+
 	return devices, nil
 }
 
 // scanWithBluetoothctl uses bluetoothctl to scan for devices
-func (bs *BluetoothScanner) scanWithBluetoothctl() ([]models.BluetoothDevice, error) {
-	// Start scan
-	scanCmd := exec.Command("bluetoothctl", "scan", "on")
-	scanCmd.Start()
-
-	// Wait for scanning to start
-	time.Sleep(2 * time.Second)
+func (bs *BluetoothScanner) scanWithBluetoothctl(ctx context.Context) ([]models.BluetoothDevice, error) {
+	// Scan for two seconds; the command is stopped when the timeout expires
+	scanCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	bs.runner.Output(scanCtx, "bluetoothctl", "scan", "on")
+	cancel()
 
 	// Stop scan and list devices
-	stopScanCmd := exec.Command("bluetoothctl", "scan", "off")
-	stopScanCmd.Run()
+	bs.runner.Output(ctx, "bluetoothctl", "scan", "off")
 
 	// Get device list
-	listCmd := exec.Command("bluetoothctl", "devices")
-	output, err := listCmd.CombinedOutput()
+	output, err := bs.runner.Output(ctx, "bluetoothctl", "devices")
 	if err != nil {
 		return nil, err
 	}
@@ -74,14 +105,13 @@ func (bs *BluetoothScanner) scanWithBluetoothctl() ([]models.BluetoothDevice, er
 }
 
 // scanWithHcitool uses hcitool as alternative
-func (bs *BluetoothScanner) scanWithHcitool() ([]models.BluetoothDevice, error) {
-	if !isCommandAvailable("hcitool") {
+func (bs *BluetoothScanner) scanWithHcitool(ctx context.Context) ([]models.BluetoothDevice, error) {
+	if !isCommandAvailable(bs.runner, "hcitool") {
 		return nil, fmt.Errorf("hcitool not available")
 	}
 
 	// Scan for devices
-	cmd := exec.Command("hcitool", "scan", "--flush")
-	output, err := cmd.CombinedOutput()
+	output, err := bs.runner.Output(ctx, "hcitool", "scan", "--flush")
 	if err != nil {
 		return nil, err
 	}
@@ -326,7 +356,7 @@ func (bs *BluetoothScanner) DetectBluetoothAttacks(devices []models.BluetoothDev
 			attacks = append(attacks, models.Attack{
 				Type:        "UNKNOWN_BLUETOOTH",
 				Severity:    models.SeverityHigh,
-				Description: fmt.Sprintf("Unknown Bluetooth device: %s (%s)", device.Name, device.Address),
+				Description: fmt.Sprintf("Unknown Bluetooth device: %s (%s)%s", device.Name, device.Address, deviceLabel(device.Vendor, device.Class, device.RandomizedMAC)),
 				Target:      device.Address,
 				Timestamp:   time.Now(),
 			})
@@ -337,8 +367,8 @@ func (bs *BluetoothScanner) DetectBluetoothAttacks(devices []models.BluetoothDev
 }
 
 // MonitorBluetoothConnections monitors Bluetooth connection attempts
-func (bs *BluetoothScanner) MonitorBluetoothConnections() (<-chan models.Attack, error) {
-	if !isCommandAvailable("bluetoothctl") {
+func (bs *BluetoothScanner) MonitorBluetoothConnections(ctx context.Context) (<-chan models.Attack, error) {
+	if !isCommandAvailable(bs.runner, "bluetoothctl") {
 		return nil, fmt.Errorf("bluetoothctl not available for connection monitoring")
 	}
 
@@ -348,15 +378,11 @@ func (bs *BluetoothScanner) MonitorBluetoothConnections() (<-chan models.Attack,
 		defer close(attackCh)
 
 		// Start bluetoothctl monitor
-		cmd := exec.Command("bluetoothctl", "--monitor")
-		stdout, err := cmd.StdoutPipe()
+		stdout, err := bs.runner.Stream(ctx, "bluetoothctl", "--monitor")
 		if err != nil {
 			return
 		}
-
-		if err := cmd.Start(); err != nil {
-			return
-		}
+		defer stdout.Close()
 
 		scanner := bufio.NewScanner(stdout)
 
@@ -0,0 +1,197 @@
+package scanners
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+)
+
+// classPorts are TCP ports that on their own identify the kind of device
+var classPorts = []struct {
+	port  int
+	class string
+}{
+	{9100, models.DeviceClassPrinter}, // raw printing
+	{631, models.DeviceClassPrinter},  // IPP
+	{515, models.DeviceClassPrinter},  // LPD
+	{554, models.DeviceClassCamera},   // RTSP
+	{8554, models.DeviceClassCamera},
+	{37777, models.DeviceClassCamera}, // Dahua DVR
+	{1883, models.DeviceClassIoT},     // MQTT
+	{8883, models.DeviceClassIoT},
+	{6668, models.DeviceClassIoT}, // Tuya
+}
+
+// classKeywords map words in device names and vendors to a class, checked in order
+var classKeywords = []struct {
+	keyword string
+	class   string
+}{
+	{"iphone", models.DeviceClassPhone},
+	{"android", models.DeviceClassPhone},
+	{"galaxy", models.DeviceClassPhone},
+	{"pixel", models.DeviceClassPhone},
+	{"phone", models.DeviceClassPhone},
+	{"printer", models.DeviceClassPrinter},
+	{"laserjet", models.DeviceClassPrinter},
+	{"officejet", models.DeviceClassPrinter},
+	{"brother", models.DeviceClassPrinter},
+	{"epson", models.DeviceClassPrinter},
+	{"canon", models.DeviceClassPrinter},
+	{"xerox", models.DeviceClassPrinter},
+	{"ricoh", models.DeviceClassPrinter},
+	{"lexmark", models.DeviceClassPrinter},
+	{"kyocera", models.DeviceClassPrinter},
+	{"zebra", models.DeviceClassPrinter},
+	{"camera", models.DeviceClassCamera},
+	{"ipcam", models.DeviceClassCamera},
+	{"hikvision", models.DeviceClassCamera},
+	{"dahua", models.DeviceClassCamera},
+	{"axis", models.DeviceClassCamera},
+	{"reolink", models.DeviceClassCamera},
+	{"wyze", models.DeviceClassCamera},
+	{"router", models.DeviceClassRouter},
+	{"gateway", models.DeviceClassRouter},
+	{"openwrt", models.DeviceClassRouter},
+	{"fritz", models.DeviceClassRouter},
+	{"cisco", models.DeviceClassRouter},
+	{"juniper", models.DeviceClassRouter},
+	{"aruba", models.DeviceClassRouter},
+	{"fortinet", models.DeviceClassRouter},
+	{"palo alto", models.DeviceClassRouter},
+	{"ubiquiti", models.DeviceClassRouter},
+	{"mikrotik", models.DeviceClassRouter},
+	{"tp-link", models.DeviceClassRouter},
+	{"netgear", models.DeviceClassRouter},
+	{"avm", models.DeviceClassRouter},
+	{"espressif", models.DeviceClassIoT},
+	{"esp32", models.DeviceClassIoT},
+	{"esp8266", models.DeviceClassIoT},
+	{"tasmota", models.DeviceClassIoT},
+	{"shelly", models.DeviceClassIoT},
+	{"philips hue", models.DeviceClassIoT},
+	{"sonos", models.DeviceClassIoT},
+	{"roku", models.DeviceClassIoT},
+	{"nest", models.DeviceClassIoT},
+	{"chromecast", models.DeviceClassIoT},
+	{"echo", models.DeviceClassIoT},
+	{"amazon", models.DeviceClassIoT},
+}
+
+// classKeywordPatterns match each keyword as a whole word, optionally numbered as
+// in "printer2", so that "nest" does not match "honest"
+var classKeywordPatterns = func() []*regexp.Regexp {
+	patterns := make([]*regexp.Regexp, len(classKeywords))
+	for i, ck := range classKeywords {
+		patterns[i] = regexp.MustCompile(`(^|[^a-z0-9])` + regexp.QuoteMeta(ck.keyword) + `[0-9]*($|[^a-z0-9])`)
+	}
+	return patterns
+}()
+
+// ClassifyDevice labels a device as a phone, printer, camera, IoT device or router.
+// Identifying ports win over the device name, which wins over the vendor; a host
+// serving DNS is taken for a router, and a randomized MAC with nothing else to go
+// on usually belongs to a phone. It returns "" when nothing matches.
+func ClassifyDevice(vendor, name string, ports []models.Port, randomizedMAC bool) string {
+	open := make(map[int]bool)
+	for _, port := range ports {
+		if port.State == "open" {
+			open[port.Number] = true
+		}
+	}
+
+	for _, cp := range classPorts {
+		if open[cp.port] {
+			return cp.class
+		}
+	}
+
+	for _, text := range []string{name, vendor} {
+		lower := strings.ToLower(text)
+		if lower == "" {
+			continue
+		}
+		for i, pattern := range classKeywordPatterns {
+			if pattern.MatchString(lower) {
+				return classKeywords[i].class
+			}
+		}
+	}
+
+	if open[53] {
+		return models.DeviceClassRouter
+	}
+	if randomizedMAC {
+		return models.DeviceClassPhone
+	}
+	return ""
+}
+
+// classifyNetworkDevice fills in a network device's vendor, MAC randomization and class
+func classifyNetworkDevice(device *models.NetworkDevice, vendors *VendorDB) {
+	if device.MAC != "" {
+		device.RandomizedMAC = IsRandomizedMAC(device.MAC)
+		if device.Vendor == "" {
+			device.Vendor = vendors.Lookup(device.MAC)
+		}
+	}
+	name := strings.TrimSpace(device.Name + " " + device.Model)
+	device.Class = ClassifyDevice(device.Vendor, name, device.Ports, device.RandomizedMAC)
+}
+
+// classifyBluetoothDevice fills in a Bluetooth device's vendor, address randomization and class
+func classifyBluetoothDevice(device *models.BluetoothDevice, vendors *VendorDB) {
+	device.RandomizedMAC = IsRandomizedMAC(device.Address)
+	if device.Vendor == "" && !device.RandomizedMAC {
+		device.Vendor = vendors.Lookup(device.Address)
+	}
+	device.Class = ClassifyDevice(device.Vendor, device.Name, nil, false)
+}
+
+// deviceLabel describes a device's vendor and class for attack descriptions,
+// e.g. " (Hikvision, camera)", or "" when neither is known
+func deviceLabel(vendor, class string, randomizedMAC bool) string {
+	var parts []string
+	if vendor != "" {
+		parts = append(parts, vendor)
+	} else if randomizedMAC {
+		parts = append(parts, "randomized MAC")
+	}
+	if class != "" {
+		parts = append(parts, class)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (%s)", strings.Join(parts, ", "))
+}
+
+// annotateNetworkAttacks tags attacks aimed at a scanned device with its vendor and class
+func annotateNetworkAttacks(attacks []models.Attack, devices []models.NetworkDevice) {
+	byIP := make(map[string]*models.NetworkDevice, len(devices))
+	for i := range devices {
+		byIP[devices[i].IP] = &devices[i]
+	}
+	for i := range attacks {
+		if device, ok := byIP[attacks[i].Target]; ok {
+			attacks[i].Vendor = device.Vendor
+			attacks[i].DeviceClass = device.Class
+		}
+	}
+}
+
+// annotateBluetoothAttacks tags attacks aimed at a scanned device with its vendor and class
+func annotateBluetoothAttacks(attacks []models.Attack, devices []models.BluetoothDevice) {
+	byAddress := make(map[string]*models.BluetoothDevice, len(devices))
+	for i := range devices {
+		byAddress[devices[i].Address] = &devices[i]
+	}
+	for i := range attacks {
+		if device, ok := byAddress[attacks[i].Target]; ok {
+			attacks[i].Vendor = device.Vendor
+			attacks[i].DeviceClass = device.Class
+		}
+	}
+}
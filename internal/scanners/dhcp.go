@@ -0,0 +1,287 @@
+package scanners
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+)
+
+// DHCP message layout and option codes from RFC 2131 and RFC 2132
+const (
+	dhcpHeaderLen       = 236
+	dhcpMagicCookie     = 0x63825363
+	dhcpOptSubnetMask   = 1
+	dhcpOptRouter       = 3
+	dhcpOptDNS          = 6
+	dhcpOptLeaseTime    = 51
+	dhcpOptMessageType  = 53
+	dhcpOptServerID     = 54
+	dhcpOptParamRequest = 55
+	dhcpOptEnd          = 255
+	dhcpDiscover        = 1
+	dhcpOffer           = 2
+)
+
+// errDHCPProbeUnsupported is returned where the DHCP probe cannot bind to an interface
+var errDHCPProbeUnsupported = errors.New("dhcp probing not supported on this platform")
+
+// DHCPOffer is one server's answer to a DISCOVER
+type DHCPOffer struct {
+	Server    string
+	ServerMAC string
+	Interface string
+	OfferedIP string
+	Subnet    string
+	Gateways  []string
+	DNS       []string
+	Lease     time.Duration
+}
+
+// buildDHCPDiscover creates a broadcast DISCOVER for the given client hardware address
+func buildDHCPDiscover(xid uint32, mac net.HardwareAddr) []byte {
+	packet := make([]byte, dhcpHeaderLen, dhcpHeaderLen+16)
+	packet[0] = 1 // BOOTREQUEST
+	packet[1] = 1 // Ethernet
+	packet[2] = 6
+	binary.BigEndian.PutUint32(packet[4:8], xid)
+	// Ask for broadcast replies since the client has no address yet
+	binary.BigEndian.PutUint16(packet[10:12], 0x8000)
+	copy(packet[28:44], mac)
+
+	packet = binary.BigEndian.AppendUint32(packet, dhcpMagicCookie)
+	packet = append(packet, dhcpOptMessageType, 1, dhcpDiscover)
+	packet = append(packet, dhcpOptParamRequest, 4, dhcpOptSubnetMask, dhcpOptRouter, dhcpOptDNS, dhcpOptLeaseTime)
+	packet = append(packet, dhcpOptEnd)
+	return packet
+}
+
+// parseDHCPOffer decodes an OFFER answering the transaction xid
+func parseDHCPOffer(packet []byte, xid uint32) (DHCPOffer, bool) {
+	if len(packet) < dhcpHeaderLen+4 || packet[0] != 2 ||
+		binary.BigEndian.Uint32(packet[4:8]) != xid ||
+		binary.BigEndian.Uint32(packet[dhcpHeaderLen:dhcpHeaderLen+4]) != dhcpMagicCookie {
+		return DHCPOffer{}, false
+	}
+
+	offer := DHCPOffer{
+		OfferedIP: net.IP(packet[16:20]).String(),
+	}
+
+	ipList := func(data []byte) []string {
+		var ips []string
+		for len(data) >= 4 {
+			ips = append(ips, net.IP(data[:4]).String())
+			data = data[4:]
+		}
+		return ips
+	}
+
+	messageType := 0
+	options := packet[dhcpHeaderLen+4:]
+	for len(options) > 0 {
+		code := options[0]
+		if code == dhcpOptEnd {
+			break
+		}
+		if code == 0 {
+			options = options[1:]
+			continue
+		}
+		if len(options) < 2 || int(options[1])+2 > len(options) {
+			break
+		}
+		data := options[2 : 2+int(options[1])]
+		options = options[2+int(options[1]):]
+
+		switch code {
+		case dhcpOptMessageType:
+			if len(data) == 1 {
+				messageType = int(data[0])
+			}
+		case dhcpOptSubnetMask:
+			if len(data) == 4 {
+				ones, _ := net.IPMask(data).Size()
+				offer.Subnet = fmt.Sprintf("/%d", ones)
+			}
+		case dhcpOptRouter:
+			offer.Gateways = ipList(data)
+		case dhcpOptDNS:
+			offer.DNS = ipList(data)
+		case dhcpOptServerID:
+			if len(data) == 4 {
+				offer.Server = net.IP(data).String()
+			}
+		case dhcpOptLeaseTime:
+			if len(data) == 4 {
+				offer.Lease = time.Duration(binary.BigEndian.Uint32(data)) * time.Second
+			}
+		}
+	}
+
+	return offer, messageType == dhcpOffer
+}
+
+// DHCPScanner broadcasts DHCP DISCOVERs and reports servers outside the authorized set
+type DHCPScanner struct {
+	config     *models.AttackDetectorConfig
+	vendors    *VendorDB
+	authorized map[string]bool
+}
+
+// NewDHCPScanner creates a new DHCP scanner. Probing needs the authorized servers,
+// so it fails when interfaces are configured without them.
+func NewDHCPScanner(config *models.AttackDetectorConfig, vendors *VendorDB) (*DHCPScanner, error) {
+	if len(config.DHCPInterfaces) > 0 && len(config.DHCPAuthorizedServers) == 0 {
+		return nil, fmt.Errorf("dhcp_authorized_servers must list the authorized DHCP servers when dhcp_interfaces is set")
+	}
+
+	authorized := make(map[string]bool)
+	for _, server := range config.DHCPAuthorizedServers {
+		authorized[strings.ToLower(server)] = true
+	}
+	return &DHCPScanner{
+		config:     config,
+		vendors:    vendors,
+		authorized: authorized,
+	}, nil
+}
+
+// Name returns the registry name of the DHCP scanner
+func (ds *DHCPScanner) Name() string {
+	return "dhcp"
+}
+
+// Available reports whether any interfaces are configured for probing
+func (ds *DHCPScanner) Available() bool {
+	return len(ds.config.DHCPInterfaces) > 0
+}
+
+// Scan probes every configured interface and checks the offers
+func (ds *DHCPScanner) Scan(ctx context.Context) ([]interface{}, []models.Attack, error) {
+	var offers []DHCPOffer
+	var errs []string
+
+	for _, iface := range ds.config.DHCPInterfaces {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+		ifaceOffers, err := ds.ProbeInterface(ctx, iface)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", iface, err))
+			continue
+		}
+		offers = append(offers, ifaceOffers...)
+	}
+
+	if len(errs) == len(ds.config.DHCPInterfaces) {
+		return nil, nil, fmt.Errorf("dhcp probe failed: %s", strings.Join(errs, "; "))
+	}
+
+	return nil, ds.DetectRogueServers(offers), nil
+}
+
+// ProbeInterface sends one DISCOVER on iface and collects every OFFER until the timeout
+func (ds *DHCPScanner) ProbeInterface(ctx context.Context, iface string) ([]DHCPOffer, error) {
+	ifi, err := net.InterfaceByName(iface)
+	if err != nil {
+		return nil, err
+	}
+	if len(ifi.HardwareAddr) != 6 {
+		return nil, fmt.Errorf("%s has no Ethernet address", iface)
+	}
+
+	var xidBytes [4]byte
+	if _, err := rand.Read(xidBytes[:]); err != nil {
+		return nil, err
+	}
+	xid := binary.BigEndian.Uint32(xidBytes[:])
+
+	replies, err := dhcpExchange(ctx, iface, buildDHCPDiscover(xid, ifi.HardwareAddr), ds.config.DHCPTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	neighbors, _ := ReadNeighborTable()
+	neighborIndex := neighborsByIP(neighbors)
+
+	var offers []DHCPOffer
+	for _, reply := range replies {
+		offer, ok := parseDHCPOffer(reply.Packet, xid)
+		if !ok {
+			continue
+		}
+		offer.Interface = iface
+		offer.ServerMAC = reply.MAC
+		if offer.Server == "" {
+			offer.Server = reply.Source
+		}
+		if offer.ServerMAC == "" {
+			offer.ServerMAC = neighborIndex[reply.Source].MAC
+		}
+		offers = append(offers, offer)
+	}
+
+	return offers, nil
+}
+
+// dhcpReply is a raw reply together with where it came from
+type dhcpReply struct {
+	Packet []byte
+	Source string
+	MAC    string
+}
+
+// DetectRogueServers raises ROGUE_DHCP_SERVER for each offer from a server whose IP
+// or MAC is not authorized
+func (ds *DHCPScanner) DetectRogueServers(offers []DHCPOffer) []models.Attack {
+	var attacks []models.Attack
+	authorized := ds.authorized
+
+	seen := make(map[string]bool)
+	sort.Slice(offers, func(i, j int) bool { return offers[i].Server < offers[j].Server })
+	for _, offer := range offers {
+		if authorized[strings.ToLower(offer.Server)] ||
+			(offer.ServerMAC != "" && authorized[strings.ToLower(offer.ServerMAC)]) {
+			continue
+		}
+
+		key := offer.Server + "%" + offer.Interface
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		mac := offer.ServerMAC
+		if mac == "" {
+			mac = "unknown"
+		}
+		attacks = append(attacks, models.Attack{
+			Type:     "ROGUE_DHCP_SERVER",
+			Severity: models.SeverityHigh,
+			Description: fmt.Sprintf("Unauthorized DHCP server %s (MAC: %s%s) on %s offered %s%s, gateway %s, DNS %s",
+				offer.Server, mac, deviceLabel(ds.vendors.Lookup(offer.ServerMAC), "", false), offer.Interface,
+				offer.OfferedIP, offer.Subnet, listOrNone(offer.Gateways), listOrNone(offer.DNS)),
+			Target:    offer.Server,
+			Timestamp: time.Now(),
+			Vendor:    ds.vendors.Lookup(offer.ServerMAC),
+		})
+	}
+
+	return attacks
+}
+
+// listOrNone joins a list of addresses for descriptions
+func listOrNone(items []string) string {
+	if len(items) == 0 {
+		return "none"
+	}
+	return strings.Join(items, ", ")
+}
@@ -0,0 +1,139 @@
+//go:build linux
+
+package scanners
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// dhcpExchange broadcasts a DHCP request on one interface and returns every reply
+// received before the timeout. Like a real DHCP client it sends from 0.0.0.0 over
+// an AF_PACKET socket, which also yields each server's MAC address, so it needs
+// root or CAP_NET_RAW.
+func dhcpExchange(ctx context.Context, iface string, request []byte, timeout time.Duration) ([]dhcpReply, error) {
+	ifi, err := net.InterfaceByName(iface)
+	if err != nil {
+		return nil, err
+	}
+
+	fd, err := syscall.Socket(syscall.AF_PACKET, syscall.SOCK_DGRAM, int(htons(syscall.ETH_P_IP)))
+	if err != nil {
+		return nil, err
+	}
+	defer syscall.Close(fd)
+	if err := syscall.Bind(fd, &syscall.SockaddrLinklayer{Protocol: htons(syscall.ETH_P_IP), Ifindex: ifi.Index}); err != nil {
+		return nil, err
+	}
+
+	broadcast := &syscall.SockaddrLinklayer{
+		Protocol: htons(syscall.ETH_P_IP),
+		Ifindex:  ifi.Index,
+		Halen:    6,
+		Addr:     [8]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+	}
+	packet := buildUDPPacket(net.IPv4zero, net.IPv4bcast, 68, 67, request)
+	if err := syscall.Sendto(fd, packet, 0, broadcast); err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+
+	var replies []dhcpReply
+	buf := make([]byte, 2048)
+	for {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			break
+		}
+		// A zero receive timeout would block forever
+		if remaining < time.Millisecond {
+			remaining = time.Millisecond
+		}
+		tv := syscall.NsecToTimeval(remaining.Nanoseconds())
+		if err := syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &tv); err != nil {
+			return replies, err
+		}
+
+		n, from, err := syscall.Recvfrom(fd, buf, 0)
+		if err != nil {
+			if err == syscall.EAGAIN || err == syscall.EINTR {
+				continue
+			}
+			return replies, err
+		}
+
+		source, payload, ok := udpPayload(buf[:n], 67, 68)
+		if !ok {
+			continue
+		}
+
+		reply := dhcpReply{
+			Packet: append([]byte(nil), payload...),
+			Source: source,
+		}
+		if link, ok := from.(*syscall.SockaddrLinklayer); ok && link.Halen == 6 {
+			reply.MAC = strings.ToUpper(net.HardwareAddr(link.Addr[:6]).String())
+		}
+		replies = append(replies, reply)
+	}
+
+	return replies, nil
+}
+
+// buildUDPPacket wraps a payload in IPv4 and UDP headers. The UDP checksum is
+// left at zero, which IPv4 permits.
+func buildUDPPacket(src, dst net.IP, srcPort, dstPort uint16, payload []byte) []byte {
+	packet := make([]byte, 28, 28+len(payload))
+	packet[0] = 0x45 // IPv4, 20-byte header
+	binary.BigEndian.PutUint16(packet[2:4], uint16(28+len(payload)))
+	packet[8] = 64 // TTL
+	packet[9] = syscall.IPPROTO_UDP
+	copy(packet[12:16], src.To4())
+	copy(packet[16:20], dst.To4())
+
+	var sum uint32
+	for i := 0; i < 20; i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(packet[i : i+2]))
+	}
+	for sum > 0xffff {
+		sum = sum&0xffff + sum>>16
+	}
+	binary.BigEndian.PutUint16(packet[10:12], ^uint16(sum))
+
+	binary.BigEndian.PutUint16(packet[20:22], srcPort)
+	binary.BigEndian.PutUint16(packet[22:24], dstPort)
+	binary.BigEndian.PutUint16(packet[24:26], uint16(8+len(payload)))
+
+	return append(packet, payload...)
+}
+
+// udpPayload extracts the source address and payload of an IPv4 UDP packet
+// sent from srcPort to dstPort
+func udpPayload(packet []byte, srcPort, dstPort uint16) (string, []byte, bool) {
+	if len(packet) < 20 || packet[0]>>4 != 4 || packet[9] != syscall.IPPROTO_UDP {
+		return "", nil, false
+	}
+	headerLen := int(packet[0]&0x0f) * 4
+	if len(packet) < headerLen+8 {
+		return "", nil, false
+	}
+
+	udp := packet[headerLen:]
+	if binary.BigEndian.Uint16(udp[0:2]) != srcPort || binary.BigEndian.Uint16(udp[2:4]) != dstPort {
+		return "", nil, false
+	}
+	length := int(binary.BigEndian.Uint16(udp[4:6]))
+	if length < 8 || length > len(udp) {
+		return "", nil, false
+	}
+
+	return net.IP(packet[12:16]).String(), udp[8:length], true
+}
@@ -0,0 +1,176 @@
+//go:build linux
+
+package scanners
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+)
+
+// vethPair creates a veth pair for the test and returns the names of its ends
+func vethPair(t *testing.T) (string, string) {
+	t.Helper()
+	if os.Geteuid() != 0 {
+		t.Skip("creating a veth pair needs root")
+	}
+	if _, err := exec.LookPath("ip"); err != nil {
+		t.Skip("ip not available")
+	}
+
+	probe, peer := fmt.Sprintf("shd%d", os.Getpid()%100000), fmt.Sprintf("shr%d", os.Getpid()%100000)
+	if output, err := exec.Command("ip", "link", "add", probe, "type", "veth", "peer", "name", peer).CombinedOutput(); err != nil {
+		t.Skipf("veth pairs not supported: %v: %s", err, output)
+	}
+	t.Cleanup(func() { exec.Command("ip", "link", "del", probe).Run() })
+
+	for _, name := range []string{probe, peer} {
+		if output, err := exec.Command("ip", "link", "set", name, "up").CombinedOutput(); err != nil {
+			t.Fatalf("failed to bring up %s: %v: %s", name, err, output)
+		}
+	}
+	return probe, peer
+}
+
+// buildDHCPOffer answers a DISCOVER the way a DHCP server would
+func buildDHCPOffer(discover []byte, server, offered net.IP) []byte {
+	packet := make([]byte, dhcpHeaderLen)
+	packet[0] = 2 // BOOTREPLY
+	packet[1] = 1
+	packet[2] = 6
+	copy(packet[4:8], discover[4:8])
+	copy(packet[16:20], offered.To4())
+	copy(packet[28:44], discover[28:44])
+
+	packet = binary.BigEndian.AppendUint32(packet, dhcpMagicCookie)
+	packet = append(packet, dhcpOptMessageType, 1, dhcpOffer)
+	packet = append(packet, dhcpOptServerID, 4)
+	packet = append(packet, server.To4()...)
+	packet = append(packet, dhcpOptSubnetMask, 4, 255, 255, 255, 0)
+	packet = append(packet, dhcpOptRouter, 4)
+	packet = append(packet, server.To4()...)
+	packet = append(packet, dhcpOptDNS, 4, 8, 8, 8, 8)
+	packet = append(packet, dhcpOptLeaseTime, 4, 0, 0, 0x0e, 0x10)
+	return append(packet, dhcpOptEnd)
+}
+
+// standInDHCPServer answers every DISCOVER arriving on iface until the test ends
+func standInDHCPServer(t *testing.T, iface string, server, offered net.IP) {
+	t.Helper()
+	ifi, err := net.InterfaceByName(iface)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fd, err := syscall.Socket(syscall.AF_PACKET, syscall.SOCK_DGRAM, int(htons(syscall.ETH_P_IP)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := syscall.Bind(fd, &syscall.SockaddrLinklayer{Protocol: htons(syscall.ETH_P_IP), Ifindex: ifi.Index}); err != nil {
+		syscall.Close(fd)
+		t.Fatal(err)
+	}
+	tv := syscall.NsecToTimeval((100 * time.Millisecond).Nanoseconds())
+	if err := syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &tv); err != nil {
+		syscall.Close(fd)
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	t.Cleanup(func() {
+		close(done)
+		<-stopped
+		syscall.Close(fd)
+	})
+
+	broadcast := &syscall.SockaddrLinklayer{
+		Protocol: htons(syscall.ETH_P_IP),
+		Ifindex:  ifi.Index,
+		Halen:    6,
+		Addr:     [8]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+	}
+	go func() {
+		defer close(stopped)
+		buf := make([]byte, 2048)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			n, _, err := syscall.Recvfrom(fd, buf, 0)
+			if err != nil {
+				continue
+			}
+			_, discover, ok := udpPayload(buf[:n], 68, 67)
+			if !ok || len(discover) < dhcpHeaderLen || discover[0] != 1 {
+				continue
+			}
+			offer := buildUDPPacket(server, net.IPv4bcast, 67, 68, buildDHCPOffer(discover, server, offered))
+			syscall.Sendto(fd, offer, 0, broadcast)
+		}
+	}()
+}
+
+func TestDHCPProbeAgainstStandInServer(t *testing.T) {
+	probe, peer := vethPair(t)
+	server := net.ParseIP("10.99.0.1")
+	standInDHCPServer(t, peer, server, net.ParseIP("10.99.0.50"))
+
+	peerIfi, err := net.InterfaceByName(peer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	peerMAC := strings.ToUpper(peerIfi.HardwareAddr.String())
+
+	config := models.DefaultConfig()
+	config.DHCPInterfaces = []string{probe}
+	config.DHCPAuthorizedServers = []string{"10.99.0.254"}
+	config.DHCPTimeout = time.Second
+
+	ds, err := NewDHCPScanner(config, &VendorDB{vendors: make(map[string]string)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	offers, err := ds.ProbeInterface(context.Background(), probe)
+	if err != nil {
+		t.Fatalf("ProbeInterface: %v", err)
+	}
+	if len(offers) == 0 {
+		t.Fatal("no offer received from the stand-in server")
+	}
+	offer := offers[0]
+	if offer.Server != "10.99.0.1" || offer.ServerMAC != peerMAC || offer.Interface != probe ||
+		offer.OfferedIP != "10.99.0.50" || offer.Subnet != "/24" || offer.Lease != time.Hour ||
+		len(offer.DNS) != 1 || offer.DNS[0] != "8.8.8.8" {
+		t.Errorf("unexpected offer: %+v", offer)
+	}
+
+	_, attacks, err := ds.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	if len(attacks) != 1 || attacks[0].Type != "ROGUE_DHCP_SERVER" || attacks[0].Target != "10.99.0.1" ||
+		!strings.Contains(attacks[0].Description, peerMAC) {
+		t.Errorf("stand-in server not reported as rogue: %+v", attacks)
+	}
+
+	// The same server is accepted once its MAC is authorized
+	config.DHCPAuthorizedServers = []string{peerMAC}
+	if ds, err = NewDHCPScanner(config, ds.vendors); err != nil {
+		t.Fatal(err)
+	}
+	if _, attacks, err := ds.Scan(context.Background()); err != nil || len(attacks) != 0 {
+		t.Errorf("authorized server reported: %+v, %v", attacks, err)
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package scanners
+
+import (
+	"context"
+	"time"
+)
+
+// dhcpExchange is only implemented on Linux
+func dhcpExchange(ctx context.Context, iface string, request []byte, timeout time.Duration) ([]dhcpReply, error) {
+	return nil, errDHCPProbeUnsupported
+}
@@ -0,0 +1,50 @@
+package scanners
+
+import (
+	"testing"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+)
+
+func TestNewDHCPScannerRequiresAuthorizedServers(t *testing.T) {
+	config := models.DefaultConfig()
+	if _, err := NewDHCPScanner(config, nil); err != nil {
+		t.Errorf("scanner without interfaces rejected: %v", err)
+	}
+
+	config.DHCPInterfaces = []string{"eth0"}
+	if _, err := NewDHCPScanner(config, nil); err == nil {
+		t.Error("interfaces without authorized servers accepted")
+	}
+
+	config.DHCPAuthorizedServers = []string{"10.0.0.1"}
+	if _, err := NewDHCPScanner(config, nil); err != nil {
+		t.Errorf("authorized servers rejected: %v", err)
+	}
+}
+
+func TestDetectRogueServers(t *testing.T) {
+	config := models.DefaultConfig()
+	config.DHCPInterfaces = []string{"eth0"}
+	config.DHCPAuthorizedServers = []string{"10.0.0.1", "aa:bb:cc:00:00:02"}
+	ds, err := NewDHCPScanner(config, &VendorDB{vendors: make(map[string]string)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	attacks := ds.DetectRogueServers([]DHCPOffer{
+		{Server: "10.0.0.1", ServerMAC: "AA:BB:CC:00:00:01", Interface: "eth0"},
+		{Server: "10.0.0.2", ServerMAC: "AA:BB:CC:00:00:02", Interface: "eth0"},
+		{Server: "10.0.0.66", ServerMAC: "AA:BB:CC:00:00:66", Interface: "eth0"},
+		{Server: "10.0.0.66", ServerMAC: "AA:BB:CC:00:00:66", Interface: "eth0"},
+	})
+	if len(attacks) != 1 || attacks[0].Target != "10.0.0.66" {
+		t.Errorf("got %+v, want one alert for 10.0.0.66", attacks)
+	}
+
+	// The first servers to answer are not trusted on their own
+	attacks = ds.DetectRogueServers([]DHCPOffer{{Server: "192.168.1.1", Interface: "eth0"}})
+	if len(attacks) != 1 {
+		t.Errorf("offer from unknown server not reported: %+v", attacks)
+	}
+}
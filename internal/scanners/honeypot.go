@@ -0,0 +1,543 @@
+package scanners
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+)
+
+// Limits that keep the decoy services cheap to run and hard to abuse
+const (
+	honeypotSessionTimeout = 10 * time.Second
+	honeypotMaxSessions    = 64
+	honeypotMaxLine        = 256
+	honeypotMaxCredentials = 5
+	honeypotMaxGroups      = 1024
+)
+
+// Banners the decoy services present
+const (
+	honeypotSSHBanner    = "SSH-2.0-OpenSSH_8.9p1 Ubuntu-3ubuntu0.6"
+	honeypotTelnetPrompt = "\r\nUbuntu 22.04.4 LTS\r\nlogin: "
+	honeypotHTTPBody     = "<html><head><title>401 Unauthorized</title></head><body><h1>Unauthorized</h1></body></html>"
+)
+
+// honeypotUserFields and honeypotPasswordFields are form fields that carry logins
+var (
+	honeypotUserFields     = []string{"username", "user", "login", "email", "name"}
+	honeypotPasswordFields = []string{"password", "pass", "passwd", "pwd"}
+)
+
+// HoneypotTouch is one connection to a decoy listener
+type HoneypotTouch struct {
+	Time     time.Time `json:"time"`
+	Source   string    `json:"source"`
+	Port     int       `json:"port"`
+	Service  string    `json:"service"`
+	Banner   string    `json:"banner,omitempty"`
+	Username string    `json:"username,omitempty"`
+	Password string    `json:"password,omitempty"`
+}
+
+// credential formats the login a touch tried, if any
+func (t HoneypotTouch) credential() string {
+	if t.Password == "" {
+		return t.Username
+	}
+	return t.Username + ":" + t.Password
+}
+
+// honeypotGroup is what one source did to one decoy listener
+type honeypotGroup struct {
+	first       HoneypotTouch
+	count       int
+	banner      string
+	credentials []string
+}
+
+// honeypotTouches aggregates touches by source and decoy listener, in the order
+// the groups were first seen
+type honeypotTouches struct {
+	groups  map[string]*honeypotGroup
+	keys    []string
+	dropped int
+}
+
+// add counts a touch in its group. Touches that would open a group beyond limit
+// are only counted as dropped; a limit of zero keeps every group.
+func (t *honeypotTouches) add(touch HoneypotTouch, limit int) {
+	key := fmt.Sprintf("%s%%%d", touch.Source, touch.Port)
+	g, ok := t.groups[key]
+	if !ok {
+		if limit > 0 && len(t.groups) >= limit {
+			t.dropped++
+			return
+		}
+		if t.groups == nil {
+			t.groups = make(map[string]*honeypotGroup)
+		}
+		g = &honeypotGroup{first: touch}
+		t.groups[key] = g
+		t.keys = append(t.keys, key)
+	}
+	g.count++
+	if g.banner == "" {
+		g.banner = touch.Banner
+	}
+	if credential := touch.credential(); credential != "" && len(g.credentials) < honeypotMaxCredentials &&
+		!containsString(g.credentials, credential) {
+		g.credentials = append(g.credentials, credential)
+	}
+}
+
+// HoneypotScanner opens decoy listeners no legitimate device should connect to
+// and reports every connection they receive
+type HoneypotScanner struct {
+	config  *models.AttackDetectorConfig
+	vendors *VendorDB
+
+	mu        sync.Mutex
+	started   bool
+	closed    bool
+	listeners []net.Listener
+	errs      []string
+	touches   honeypotTouches
+	log       *os.File
+	sessions  chan struct{}
+}
+
+// NewHoneypotScanner creates a new honeypot scanner
+func NewHoneypotScanner(config *models.AttackDetectorConfig, vendors *VendorDB) *HoneypotScanner {
+	return &HoneypotScanner{
+		config:   config,
+		vendors:  vendors,
+		sessions: make(chan struct{}, honeypotMaxSessions),
+	}
+}
+
+// Name returns the registry name of the honeypot scanner
+func (hs *HoneypotScanner) Name() string {
+	return "honeypot"
+}
+
+// Available reports whether the honeypot is enabled with any listeners
+func (hs *HoneypotScanner) Available() bool {
+	return hs.config.Honeypot && len(hs.config.HoneypotListeners) > 0
+}
+
+// Scan opens the listeners on first use and reports the connections since the
+// last pass. Listeners that failed, and connections dropped because too many
+// sources touched the decoys, are returned as an error along with them.
+func (hs *HoneypotScanner) Scan(ctx context.Context) ([]interface{}, []models.Attack, error) {
+	if err := hs.start(); err != nil {
+		return nil, nil, err
+	}
+
+	hs.mu.Lock()
+	touches, errs := hs.touches, hs.errs
+	hs.touches, hs.errs = honeypotTouches{}, nil
+	hs.mu.Unlock()
+
+	attacks := hs.reportTouches(touches)
+	if touches.dropped > 0 {
+		errs = append(errs, fmt.Sprintf("honeypot dropped %d connections beyond %d sources and listeners; see the honeypot log", touches.dropped, honeypotMaxGroups))
+	}
+	if len(errs) > 0 {
+		return nil, attacks, errors.New(strings.Join(errs, "; "))
+	}
+	return nil, attacks, nil
+}
+
+// start opens every configured listener once. Listeners that cannot bind are
+// reported with the next scan; the honeypot runs as long as at least one of
+// them is open.
+func (hs *HoneypotScanner) start() error {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+
+	if hs.started || hs.closed {
+		return nil
+	}
+
+	var listeners []net.Listener
+	var decoys []models.HoneypotListener
+	var errs []string
+	for _, decoy := range hs.config.HoneypotListeners {
+		address := net.JoinHostPort(hs.config.HoneypotAddress, strconv.Itoa(decoy.Port))
+		listener, err := net.Listen("tcp", address)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s on port %d: %v", decoy.Service, decoy.Port, err))
+			continue
+		}
+		listeners = append(listeners, listener)
+		decoys = append(decoys, decoy)
+	}
+	if len(listeners) == 0 {
+		return fmt.Errorf("no honeypot listeners could be opened: %s", strings.Join(errs, "; "))
+	}
+
+	if hs.config.HoneypotLogFile != "" {
+		log, err := os.OpenFile(hs.config.HoneypotLogFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			for _, listener := range listeners {
+				listener.Close()
+			}
+			return fmt.Errorf("failed to open honeypot log: %v", err)
+		}
+		hs.log = log
+	}
+
+	for _, err := range errs {
+		hs.errs = append(hs.errs, "honeypot listener not started: "+err)
+	}
+	hs.listeners = listeners
+	for i, listener := range listeners {
+		go hs.serve(listener, decoys[i])
+	}
+	hs.started = true
+	return nil
+}
+
+// serve accepts connections on one decoy listener until it fails or is closed
+func (hs *HoneypotScanner) serve(listener net.Listener, decoy models.HoneypotListener) {
+	defer listener.Close()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				continue
+			}
+			hs.mu.Lock()
+			if !hs.closed {
+				hs.errs = append(hs.errs, fmt.Sprintf("honeypot %s listener on port %d stopped: %v", decoy.Service, decoy.Port, err))
+			}
+			hs.mu.Unlock()
+			return
+		}
+		go hs.handle(conn, decoy)
+	}
+}
+
+// Close closes the decoy listeners and the honeypot log
+func (hs *HoneypotScanner) Close() error {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+
+	hs.closed = true
+	for _, listener := range hs.listeners {
+		listener.Close()
+	}
+	hs.listeners = nil
+
+	if hs.log == nil {
+		return nil
+	}
+	err := hs.log.Close()
+	hs.log = nil
+	return err
+}
+
+// handle plays the decoy service on one connection and records what the peer sent.
+// When too many sessions are open the connection is only recorded.
+func (hs *HoneypotScanner) handle(conn net.Conn, decoy models.HoneypotListener) {
+	defer conn.Close()
+
+	source, _, _ := net.SplitHostPort(conn.RemoteAddr().String())
+	touch := HoneypotTouch{
+		Time:    time.Now(),
+		Source:  source,
+		Port:    decoy.Port,
+		Service: decoy.Service,
+	}
+
+	select {
+	case hs.sessions <- struct{}{}:
+		conn.SetDeadline(time.Now().Add(honeypotSessionTimeout))
+		honeypotSession(conn, bufio.NewReader(conn), decoy.Service, &touch)
+		<-hs.sessions
+	default:
+	}
+
+	hs.record(touch)
+}
+
+// record counts a touch for the next scan and appends it in full to the honeypot log
+func (hs *HoneypotScanner) record(touch HoneypotTouch) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+
+	hs.touches.add(touch, honeypotMaxGroups)
+	if hs.log != nil {
+		if data, err := json.Marshal(touch); err == nil {
+			hs.log.Write(append(data, '\n'))
+		}
+	}
+}
+
+// honeypotSession runs the conversation of a decoy service far enough to capture
+// the client's banner and any login it tries
+func honeypotSession(conn net.Conn, r *bufio.Reader, service string, touch *HoneypotTouch) {
+	switch strings.ToLower(service) {
+	case "telnet":
+		io.WriteString(conn, honeypotTelnetPrompt)
+		touch.Username = readTelnetLine(r)
+		if touch.Username == "" {
+			return
+		}
+		io.WriteString(conn, "Password: ")
+		touch.Password = readTelnetLine(r)
+		io.WriteString(conn, "\r\nLogin incorrect\r\n")
+
+	case "ssh":
+		io.WriteString(conn, honeypotSSHBanner+"\r\n")
+		line, _ := r.ReadString('\n')
+		touch.Banner = printable(line)
+
+	case "http":
+		req, err := http.ReadRequest(r)
+		if err != nil {
+			return
+		}
+		touch.Banner = printable(req.Method + " " + req.URL.RequestURI() + " " + req.UserAgent())
+		if user, pass, ok := req.BasicAuth(); ok {
+			touch.Username, touch.Password = printable(user), printable(pass)
+		} else if req.Method == http.MethodPost {
+			req.Body = http.MaxBytesReader(nil, req.Body, 4096)
+			if req.ParseForm() == nil {
+				touch.Username = firstFormValue(req, honeypotUserFields)
+				touch.Password = firstFormValue(req, honeypotPasswordFields)
+			}
+		}
+		fmt.Fprintf(conn, "HTTP/1.1 401 Unauthorized\r\nWWW-Authenticate: Basic realm=\"Administration\"\r\n"+
+			"Content-Type: text/html\r\nContent-Length: %d\r\nConnection: close\r\n\r\n%s",
+			len(honeypotHTTPBody), honeypotHTTPBody)
+
+	case "smb":
+		touch.Banner = smbNegotiateBanner(readFramed(r, 4, func(header []byte) int {
+			return int(header[1])<<16 | int(binary.BigEndian.Uint16(header[2:4]))
+		}))
+
+	case "rdp":
+		request := readFramed(r, 4, func(header []byte) int {
+			return int(binary.BigEndian.Uint16(header[2:4])) - 4
+		})
+		if request == nil {
+			return
+		}
+		touch.Banner = "RDP connection request"
+		if _, cookie, found := bytes.Cut(request, []byte("Cookie: mstshash=")); found {
+			if end := bytes.IndexAny(cookie, "\r\n"); end >= 0 {
+				touch.Username = printable(string(cookie[:end]))
+			}
+		}
+
+	default:
+		buf := make([]byte, 512)
+		n, _ := r.Read(buf)
+		touch.Banner = printable(string(buf[:n]))
+	}
+}
+
+// readTelnetLine reads one line of input, dropping telnet option negotiation
+func readTelnetLine(r *bufio.Reader) string {
+	var line []byte
+	for len(line) < honeypotMaxLine {
+		b, err := r.ReadByte()
+		if err != nil {
+			break
+		}
+		switch {
+		case b == 0xff:
+			// IAC: skip the command, its option and any subnegotiation
+			cmd, _ := r.ReadByte()
+			if cmd >= 251 && cmd <= 254 {
+				r.ReadByte()
+			} else if cmd == 250 {
+				for {
+					c, err := r.ReadByte()
+					if err != nil {
+						return printable(string(line))
+					}
+					if c == 0xff {
+						if next, _ := r.ReadByte(); next == 240 {
+							break
+						}
+					}
+				}
+			}
+		case b == '\r' || b == '\n':
+			if len(line) > 0 {
+				return printable(string(line))
+			}
+		case b == 0:
+		default:
+			line = append(line, b)
+		}
+	}
+	return printable(string(line))
+}
+
+// readFramed reads one length-prefixed message, where length extracts the payload
+// size from the fixed-size header
+func readFramed(r *bufio.Reader, headerLen int, length func([]byte) int) []byte {
+	header := make([]byte, headerLen)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil
+	}
+	size := length(header)
+	if size <= 0 || size > 4096 {
+		return nil
+	}
+	payload := make([]byte, size)
+	n, _ := io.ReadFull(r, payload)
+	return payload[:n]
+}
+
+// smbNegotiateBanner describes the dialects offered in an SMB negotiate request
+func smbNegotiateBanner(packet []byte) string {
+	switch {
+	case bytes.HasPrefix(packet, []byte("\xffSMB")) && len(packet) > 35:
+		// SMB1 dialects are 0x02-prefixed, NUL-terminated strings after the byte count
+		var dialects []string
+		for _, dialect := range bytes.Split(packet[35:], []byte{0}) {
+			if len(dialect) > 1 && dialect[0] == 0x02 {
+				dialects = append(dialects, string(dialect[1:]))
+			}
+		}
+		return printable("SMB1 negotiate: " + strings.Join(dialects, ", "))
+
+	case bytes.HasPrefix(packet, []byte("\xfeSMB")) && len(packet) >= 100:
+		count := int(binary.LittleEndian.Uint16(packet[66:68]))
+		var dialects []string
+		for i := 0; i < count && 100+2*i+2 <= len(packet); i++ {
+			dialects = append(dialects, fmt.Sprintf("0x%04x", binary.LittleEndian.Uint16(packet[100+2*i:])))
+		}
+		return "SMB2 negotiate: " + strings.Join(dialects, ", ")
+
+	case len(packet) > 0:
+		return "SMB session without negotiate"
+	}
+	return ""
+}
+
+// firstFormValue returns the first non-empty value among the named form fields
+func firstFormValue(req *http.Request, fields []string) string {
+	for _, field := range fields {
+		if value := req.PostForm.Get(field); value != "" {
+			return printable(value)
+		}
+	}
+	return ""
+}
+
+// printable trims a captured string and escapes control characters for logging
+func printable(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) > honeypotMaxLine {
+		s = s[:honeypotMaxLine]
+	}
+
+	var b strings.Builder
+	for _, r := range s {
+		if r < 0x20 || r == 0x7f || r == utf8.RuneError {
+			fmt.Fprintf(&b, "\\x%02x", r&0xff)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// DetectHoneypotTouches raises one HONEYPOT_TOUCH per source and decoy listener,
+// listing the logins tried and the first banner seen
+func (hs *HoneypotScanner) DetectHoneypotTouches(touches []HoneypotTouch) []models.Attack {
+	var grouped honeypotTouches
+	for _, touch := range touches {
+		grouped.add(touch, 0)
+	}
+	return hs.reportTouches(grouped)
+}
+
+// reportTouches raises one HONEYPOT_TOUCH per group
+func (hs *HoneypotScanner) reportTouches(touches honeypotTouches) []models.Attack {
+	var attacks []models.Attack
+	if len(touches.keys) == 0 {
+		return attacks
+	}
+
+	local := make(map[string]bool)
+	if addrs, err := net.InterfaceAddrs(); err == nil {
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok {
+				local[ipNet.IP.String()] = true
+			}
+		}
+	}
+
+	keys := append([]string(nil), touches.keys...)
+	sort.Strings(keys)
+
+	neighbors, _ := ReadNeighborTable()
+	neighborIndex := neighborsByIP(neighbors)
+
+	for _, key := range keys {
+		g := touches.groups[key]
+		// The sensor's own port scans would otherwise trip its honeypot
+		if local[g.first.Source] {
+			continue
+		}
+		mac := neighborIndex[g.first.Source].MAC
+		vendor := hs.vendors.Lookup(mac)
+		shownMAC := mac
+		if shownMAC == "" {
+			shownMAC = "unknown"
+		}
+
+		description := fmt.Sprintf("Honeypot %s listener on port %d touched by %s (MAC: %s%s) %d time(s)",
+			g.first.Service, g.first.Port, g.first.Source, shownMAC, deviceLabel(vendor, "", IsRandomizedMAC(mac)), g.count)
+		if len(g.credentials) > 0 {
+			description += "; logins tried: " + strings.Join(g.credentials, ", ")
+		}
+		if g.banner != "" {
+			description += "; client sent: " + g.banner
+		}
+
+		attacks = append(attacks, models.Attack{
+			Type:        "HONEYPOT_TOUCH",
+			Severity:    models.SeverityHigh,
+			Description: description,
+			Target:      g.first.Source,
+			Timestamp:   g.first.Time,
+			Vendor:      vendor,
+		})
+	}
+
+	return attacks
+}
+
+// containsString reports whether list holds s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
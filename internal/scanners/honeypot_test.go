@@ -0,0 +1,68 @@
+package scanners
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+)
+
+func TestHoneypotRecordAggregatesTouches(t *testing.T) {
+	hs := NewHoneypotScanner(models.DefaultConfig(), &VendorDB{vendors: make(map[string]string)})
+	logPath := filepath.Join(t.TempDir(), "honeypot.log")
+	log, err := os.Create(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hs.log = log
+	defer hs.Close()
+
+	// One source hammering a decoy is kept as a single group
+	for i := 0; i < 500; i++ {
+		hs.record(HoneypotTouch{Time: time.Now(), Source: "192.0.2.7", Port: 23, Service: "telnet",
+			Username: "root", Password: fmt.Sprintf("pass%d", i)})
+	}
+	// Spoofed sources beyond the limit are only logged
+	for i := 0; i < honeypotMaxGroups+3; i++ {
+		hs.record(HoneypotTouch{Time: time.Now(), Source: fmt.Sprintf("198.51.%d.%d", i/256, i%256), Port: 2222, Service: "ssh"})
+	}
+
+	touches := hs.touches
+	group := touches.groups["192.0.2.7%23"]
+	if len(touches.groups) != honeypotMaxGroups || group.count != 500 || len(group.credentials) != honeypotMaxCredentials {
+		t.Errorf("got %d groups, first counted %d times with %d logins", len(touches.groups), group.count, len(group.credentials))
+	}
+	if touches.dropped != 4 {
+		t.Errorf("dropped %d touches, want 4", touches.dropped)
+	}
+
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := bytes.Count(data, []byte("\n")); lines != 500+honeypotMaxGroups+3 {
+		t.Errorf("log holds %d touches, want every one", lines)
+	}
+}
+
+func TestDetectHoneypotTouches(t *testing.T) {
+	hs := NewHoneypotScanner(models.DefaultConfig(), &VendorDB{vendors: make(map[string]string)})
+	attacks := hs.DetectHoneypotTouches([]HoneypotTouch{
+		{Source: "192.0.2.7", Port: 23, Service: "telnet", Username: "admin", Password: "admin"},
+		{Source: "192.0.2.7", Port: 23, Service: "telnet", Username: "admin", Password: "admin"},
+		{Source: "192.0.2.7", Port: 8081, Service: "http", Banner: "GET / HTTP/1.1"},
+		{Source: "192.0.2.8", Port: 23, Service: "telnet"},
+	})
+	if len(attacks) != 3 {
+		t.Fatalf("got %d alerts, want one per source and port: %+v", len(attacks), attacks)
+	}
+	if !strings.Contains(attacks[0].Description, "2 time(s); logins tried: admin:admin") ||
+		!strings.Contains(attacks[1].Description, "client sent: GET / HTTP/1.1") || attacks[2].Target != "192.0.2.8" {
+		t.Errorf("unexpected alerts: %+v", attacks)
+	}
+}
@@ -0,0 +1,93 @@
+//go:build linux
+
+package scanners
+
+import (
+	"context"
+	"net"
+	"strings"
+	"syscall"
+)
+
+// listenInbound reads every frame arriving on the sensor from an AF_PACKET socket
+// until ctx is done, passing inbound IPv4 TCP segments and ARP requests to handle.
+// Frames the sensor sends itself and loopback traffic are skipped. An empty
+// interface list listens on every interface. It needs CAP_NET_RAW.
+func listenInbound(ctx context.Context, interfaces []string, handle func(inboundProbe)) error {
+	fd, err := syscall.Socket(syscall.AF_PACKET, syscall.SOCK_DGRAM, int(htons(syscall.ETH_P_ALL)))
+	if err != nil {
+		return err
+	}
+	defer syscall.Close(fd)
+
+	timeout := syscall.NsecToTimeval(arpReadTimeout.Nanoseconds())
+	if err := syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &timeout); err != nil {
+		return err
+	}
+
+	wanted := make(map[int]bool)
+	for _, name := range interfaces {
+		ifi, err := net.InterfaceByName(name)
+		if err != nil {
+			return err
+		}
+		wanted[ifi.Index] = true
+	}
+
+	names := make(map[int]string)
+	loopback := make(map[int]bool)
+	buf := make([]byte, 2048)
+	for ctx.Err() == nil {
+		n, from, err := syscall.Recvfrom(fd, buf, 0)
+		if err != nil {
+			if err == syscall.EAGAIN || err == syscall.EINTR {
+				continue
+			}
+			return err
+		}
+
+		link, ok := from.(*syscall.SockaddrLinklayer)
+		if !ok || link.Pkttype == syscall.PACKET_OUTGOING || (len(wanted) > 0 && !wanted[link.Ifindex]) {
+			continue
+		}
+
+		name, ok := names[link.Ifindex]
+		if !ok {
+			if ifi, err := net.InterfaceByIndex(link.Ifindex); err == nil {
+				name = ifi.Name
+				loopback[link.Ifindex] = ifi.Flags&net.FlagLoopback != 0
+			}
+			names[link.Ifindex] = name
+		}
+		if loopback[link.Ifindex] {
+			continue
+		}
+
+		var probe inboundProbe
+		switch link.Protocol {
+		case htons(syscall.ETH_P_IP):
+			if link.Pkttype != syscall.PACKET_HOST {
+				continue
+			}
+			if probe, ok = parseInboundTCP(buf[:n]); !ok {
+				continue
+			}
+		case htons(syscall.ETH_P_ARP):
+			packet, ok := parseARPPacket(buf[:n])
+			if !ok || packet.Operation != arpRequest || packet.IsGratuitous() || packet.SenderIP == "0.0.0.0" {
+				continue
+			}
+			probe = inboundProbe{Source: packet.SenderIP, ARPTarget: packet.TargetIP}
+		default:
+			continue
+		}
+
+		if link.Halen == 6 {
+			probe.MAC = strings.ToUpper(net.HardwareAddr(link.Addr[:6]).String())
+		}
+		probe.Interface = name
+		handle(probe)
+	}
+
+	return ctx.Err()
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package scanners
+
+import "context"
+
+// listenInbound is only implemented on Linux
+func listenInbound(ctx context.Context, interfaces []string, handle func(inboundProbe)) error {
+	return errInboundListenerUnsupported
+}
@@ -0,0 +1,400 @@
+package scanners
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+)
+
+// TCP flags the inbound scan detector looks at
+const (
+	tcpFIN = 0x01
+	tcpSYN = 0x02
+	tcpRST = 0x04
+	tcpACK = 0x10
+)
+
+// craftedSYNHeaderLen is the largest TCP header seen on SYNs from raw-packet
+// scanners, which send at most an MSS option. Operating system stacks add SACK,
+// timestamp and window scale options on top.
+const craftedSYNHeaderLen = 24
+
+// Inbound scan styles reported in INBOUND_PORT_SCAN alerts
+const (
+	scanStyleSYN     = "SYN"
+	scanStyleConnect = "connect"
+	scanStyleStealth = "FIN/NULL/Xmas"
+	scanStyleSweep   = "sweep"
+)
+
+// Limits on the probes kept between scans, so a flood of spoofed sources cannot
+// exhaust memory. Thresholds may not exceed the per-source limits.
+const (
+	inboundMaxSources    = 4096
+	inboundMaxPorts      = 1024
+	inboundMaxARPTargets = 1024
+)
+
+// errInboundListenerUnsupported is returned where AF_PACKET sockets are unavailable
+var errInboundListenerUnsupported = errors.New("inbound listener not supported on this platform")
+
+// inboundProbe is an inbound TCP segment or ARP request reduced to what scan
+// detection needs
+type inboundProbe struct {
+	Source    string
+	MAC       string
+	Interface string
+
+	// TCP destination port, flags and header length; zero for ARP
+	Port      int
+	Flags     byte
+	HeaderLen int
+
+	// Address asked for by an ARP request
+	ARPTarget string
+}
+
+// parseInboundTCP decodes the source, destination port and flags of an IPv4 TCP
+// segment without its link-layer header
+func parseInboundTCP(packet []byte) (inboundProbe, bool) {
+	if len(packet) < 20 || packet[0]>>4 != 4 || packet[9] != 6 {
+		return inboundProbe{}, false
+	}
+	headerLen := int(packet[0]&0x0f) * 4
+	// Only the first fragment carries the TCP header
+	if binary.BigEndian.Uint16(packet[6:8])&0x1fff != 0 || len(packet) < headerLen+14 {
+		return inboundProbe{}, false
+	}
+
+	tcp := packet[headerLen:]
+	return inboundProbe{
+		Source:    net.IP(packet[12:16]).String(),
+		Port:      int(binary.BigEndian.Uint16(tcp[2:4])),
+		Flags:     tcp[13],
+		HeaderLen: int(tcp[12]>>4) * 4,
+	}, true
+}
+
+// inboundPort is what one source did to one port of the sensor
+type inboundPort struct {
+	lastSeen time.Time
+	crafted  bool
+	stealth  bool
+	acked    bool
+}
+
+// inboundSource is the recent activity of one source address
+type inboundSource struct {
+	mac       string
+	iface     string
+	ports     map[int]*inboundPort
+	arpTarget map[string]time.Time
+}
+
+// InboundScanDetector watches connection attempts against the sensor itself and
+// reports sources that probe many ports or sweep the subnet with ARP requests
+type InboundScanDetector struct {
+	config  *models.AttackDetectorConfig
+	vendors *VendorDB
+
+	mu           sync.Mutex
+	listening    bool
+	lastErr      error
+	stopListener context.CancelFunc
+	closed       bool
+	sources      map[string]*inboundSource
+	alerted      map[string]time.Time
+	dropped      int
+}
+
+// NewInboundScanDetector creates a new inbound scan detector. With detection
+// enabled it fails unless both thresholds are positive and within the limits.
+func NewInboundScanDetector(config *models.AttackDetectorConfig, vendors *VendorDB) (*InboundScanDetector, error) {
+	if config.InboundScanDetection {
+		if config.InboundPortThreshold <= 0 || config.InboundPortThreshold > inboundMaxPorts {
+			return nil, fmt.Errorf("inbound_port_threshold must be between 1 and %d, got %d", inboundMaxPorts, config.InboundPortThreshold)
+		}
+		if config.InboundSweepThreshold <= 0 || config.InboundSweepThreshold > inboundMaxARPTargets {
+			return nil, fmt.Errorf("inbound_sweep_threshold must be between 1 and %d, got %d", inboundMaxARPTargets, config.InboundSweepThreshold)
+		}
+	}
+
+	return &InboundScanDetector{
+		config:  config,
+		vendors: vendors,
+		sources: make(map[string]*inboundSource),
+		alerted: make(map[string]time.Time),
+	}, nil
+}
+
+// Name returns the registry name of the inbound scan detector
+func (d *InboundScanDetector) Name() string {
+	return "inbound"
+}
+
+// Available reports whether inbound scan detection is enabled
+func (d *InboundScanDetector) Available() bool {
+	return d.config.InboundScanDetection
+}
+
+// Scan starts the listener on first use and reports the scans seen within the
+// window. Probes dropped at the limits are returned as an error with the results.
+func (d *InboundScanDetector) Scan(ctx context.Context) ([]interface{}, []models.Attack, error) {
+	if err := d.startListener(); err != nil {
+		return nil, nil, err
+	}
+	attacks := d.DetectInboundScans(time.Now())
+
+	d.mu.Lock()
+	dropped := d.dropped
+	d.dropped = 0
+	d.mu.Unlock()
+	if dropped > 0 {
+		return nil, attacks, fmt.Errorf("inbound listener dropped %d probes beyond %d sources, %d ports or %d ARP targets per source",
+			dropped, inboundMaxSources, inboundMaxPorts, inboundMaxARPTargets)
+	}
+	return nil, attacks, nil
+}
+
+// startListener runs the packet listener in the background once, until Close.
+// If a previous listener died, its error is returned and the next call starts a
+// new one.
+func (d *InboundScanDetector) startListener() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.lastErr; err != nil {
+		d.lastErr = nil
+		return fmt.Errorf("inbound listener stopped: %v", err)
+	}
+	if d.listening || d.closed {
+		return nil
+	}
+	d.listening = true
+
+	ctx, cancel := context.WithCancel(context.Background())
+	d.stopListener = cancel
+	go func() {
+		err := listenInbound(ctx, d.config.InboundInterfaces, d.record)
+		stopped := ctx.Err() != nil
+		cancel()
+
+		d.mu.Lock()
+		d.listening = false
+		if !stopped {
+			d.lastErr = err
+		}
+		d.mu.Unlock()
+	}()
+	return nil
+}
+
+// Close stops the packet listener
+func (d *InboundScanDetector) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.closed = true
+	if d.stopListener != nil {
+		d.stopListener()
+		d.stopListener = nil
+	}
+	return nil
+}
+
+// record notes one inbound probe
+func (d *InboundScanDetector) record(probe inboundProbe) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, ignored := range d.config.InboundIgnore {
+		if strings.EqualFold(ignored, probe.Source) || strings.EqualFold(ignored, probe.MAC) {
+			return
+		}
+	}
+
+	now := time.Now()
+	if probe.ARPTarget != "" {
+		source := d.trackSource(probe)
+		if source == nil {
+			return
+		}
+		if _, ok := source.arpTarget[probe.ARPTarget]; !ok && len(source.arpTarget) >= inboundMaxARPTargets {
+			d.dropped++
+			return
+		}
+		source.arpTarget[probe.ARPTarget] = now
+		return
+	}
+
+	var port *inboundPort
+	if source := d.sources[probe.Source]; source != nil {
+		port = source.ports[probe.Port]
+	}
+	switch {
+	case probe.Flags&(tcpSYN|tcpACK) == tcpSYN:
+		// A new connection attempt
+		if port == nil {
+			if port = d.trackPort(probe); port == nil {
+				return
+			}
+		}
+		port.crafted = probe.HeaderLen <= craftedSYNHeaderLen
+	case probe.Flags&(tcpSYN|tcpACK|tcpRST) == 0:
+		// NULL, FIN and Xmas probes never occur in real connections
+		if port == nil {
+			if port = d.trackPort(probe); port == nil {
+				return
+			}
+		}
+		port.stealth = true
+	case probe.Flags&tcpACK != 0 && port != nil:
+		// The source completed the handshake, as connect() does. Raw scanners
+		// answer a SYN-ACK with a bare RST instead.
+		port.acked = true
+	default:
+		return
+	}
+	port.lastSeen = now
+}
+
+// trackSource returns the activity of the probe's source, adding it unless the
+// source limit is reached. Callers hold mu.
+func (d *InboundScanDetector) trackSource(probe inboundProbe) *inboundSource {
+	source, ok := d.sources[probe.Source]
+	if !ok {
+		if len(d.sources) >= inboundMaxSources {
+			d.dropped++
+			return nil
+		}
+		source = &inboundSource{
+			ports:     make(map[int]*inboundPort),
+			arpTarget: make(map[string]time.Time),
+		}
+		d.sources[probe.Source] = source
+	}
+	source.mac = probe.MAC
+	source.iface = probe.Interface
+	return source
+}
+
+// trackPort adds the probed port to its source unless a limit is reached.
+// Callers hold mu.
+func (d *InboundScanDetector) trackPort(probe inboundProbe) *inboundPort {
+	source := d.trackSource(probe)
+	if source == nil {
+		return nil
+	}
+	if len(source.ports) >= inboundMaxPorts {
+		d.dropped++
+		return nil
+	}
+	port := &inboundPort{}
+	source.ports[probe.Port] = port
+	return port
+}
+
+// DetectInboundScans raises INBOUND_PORT_SCAN for every source that probed more
+// than the threshold of ports, or asked for more than the threshold of addresses,
+// within the window. Each source and style is reported once per window.
+func (d *InboundScanDetector) DetectInboundScans(now time.Time) []models.Attack {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var attacks []models.Attack
+	window := d.config.InboundScanWindow
+
+	for key, at := range d.alerted {
+		if now.Sub(at) > window {
+			delete(d.alerted, key)
+		}
+	}
+
+	addresses := make([]string, 0, len(d.sources))
+	for address := range d.sources {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+
+	for _, address := range addresses {
+		source := d.sources[address]
+
+		var crafted, stealth, acked int
+		for number, port := range source.ports {
+			if now.Sub(port.lastSeen) > window {
+				delete(source.ports, number)
+				continue
+			}
+			if port.crafted {
+				crafted++
+			}
+			if port.stealth {
+				stealth++
+			}
+			if port.acked {
+				acked++
+			}
+		}
+		for target, seen := range source.arpTarget {
+			if now.Sub(seen) > window {
+				delete(source.arpTarget, target)
+			}
+		}
+		if len(source.ports) == 0 && len(source.arpTarget) == 0 {
+			delete(d.sources, address)
+			continue
+		}
+
+		if count := len(source.ports); count >= d.config.InboundPortThreshold {
+			style := scanStyleConnect
+			switch {
+			case stealth*2 > count:
+				style = scanStyleStealth
+			case acked == 0 && crafted*2 > count:
+				style = scanStyleSYN
+			}
+			attacks = d.appendScan(attacks, address, source, style,
+				fmt.Sprintf("%d ports in %v", count, window), now)
+		}
+
+		if count := len(source.arpTarget); count >= d.config.InboundSweepThreshold {
+			attacks = d.appendScan(attacks, address, source, scanStyleSweep,
+				fmt.Sprintf("ARP requests for %d addresses in %v", count, window), now)
+		}
+	}
+
+	return attacks
+}
+
+// appendScan adds an INBOUND_PORT_SCAN alert unless the source was already
+// reported for the same style within the window. Callers hold mu.
+func (d *InboundScanDetector) appendScan(attacks []models.Attack, address string, source *inboundSource, style, detail string, now time.Time) []models.Attack {
+	key := address + "%" + style
+	if _, ok := d.alerted[key]; ok {
+		return attacks
+	}
+	d.alerted[key] = now
+
+	mac := source.mac
+	if mac == "" {
+		mac = "unknown"
+	}
+	vendor := d.vendors.Lookup(source.mac)
+	return append(attacks, models.Attack{
+		Type:     "INBOUND_PORT_SCAN",
+		Severity: models.SeverityHigh,
+		Description: fmt.Sprintf("Inbound %s scan from %s (MAC: %s%s) on %s: %s",
+			style, address, mac, deviceLabel(vendor, "", IsRandomizedMAC(source.mac)), source.iface, detail),
+		Target:    address,
+		Timestamp: now,
+		Vendor:    vendor,
+	})
+}
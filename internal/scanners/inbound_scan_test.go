@@ -0,0 +1,79 @@
+package scanners
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+)
+
+func newTestInboundDetector(t *testing.T) *InboundScanDetector {
+	t.Helper()
+	config := models.DefaultConfig()
+	config.InboundScanDetection = true
+	d, err := NewInboundScanDetector(config, &VendorDB{vendors: make(map[string]string)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestInboundRecordIgnoresUncountedProbes(t *testing.T) {
+	d := newTestInboundDetector(t)
+
+	// Resets and stray ACKs from unknown sources are not probes
+	for i := 0; i < 100; i++ {
+		d.record(inboundProbe{Source: fmt.Sprintf("10.0.%d.%d", i/256, i%256), Port: 80, Flags: tcpRST})
+		d.record(inboundProbe{Source: fmt.Sprintf("10.1.%d.%d", i/256, i%256), Port: 80, Flags: tcpACK})
+	}
+	if len(d.sources) != 0 {
+		t.Errorf("got %d sources, want none", len(d.sources))
+	}
+
+	// A SYN scan is still counted
+	for port := 1; port <= 20; port++ {
+		d.record(inboundProbe{Source: "10.0.0.9", MAC: "AA:BB:CC:00:00:09", Port: port, Flags: tcpSYN, HeaderLen: 24})
+	}
+	attacks := d.DetectInboundScans(time.Now())
+	if len(attacks) != 1 || attacks[0].Target != "10.0.0.9" {
+		t.Errorf("SYN scan not reported: %+v", attacks)
+	}
+}
+
+func TestInboundRecordLimits(t *testing.T) {
+	d := newTestInboundDetector(t)
+
+	// Spoofed SYNs from more sources than the limit
+	for i := 0; i < inboundMaxSources+50; i++ {
+		d.record(inboundProbe{Source: fmt.Sprintf("10.%d.%d.1", i/256, i%256), Port: 22, Flags: tcpSYN})
+	}
+	// One source probing every port and sweeping a /16
+	for port := 1; port <= inboundMaxPorts+10; port++ {
+		d.record(inboundProbe{Source: "10.0.0.1", Port: port, Flags: tcpFIN})
+	}
+	for i := 0; i < inboundMaxARPTargets+5; i++ {
+		d.record(inboundProbe{Source: "10.0.0.1", ARPTarget: fmt.Sprintf("10.9.%d.%d", i/256, i%256)})
+	}
+
+	source := d.sources["10.0.0.1"]
+	if len(d.sources) != inboundMaxSources || len(source.ports) != inboundMaxPorts || len(source.arpTarget) != inboundMaxARPTargets {
+		t.Errorf("got %d sources, %d ports and %d ARP targets, want %d, %d and %d", len(d.sources), len(source.ports),
+			len(source.arpTarget), inboundMaxSources, inboundMaxPorts, inboundMaxARPTargets)
+	}
+	// The source already had port 22, so its FIN there took no new slot
+	if want := 50 + 10 + 5; d.dropped != want {
+		t.Errorf("dropped %d probes, want %d", d.dropped, want)
+	}
+}
+
+func TestNewInboundScanDetectorRejectsThresholds(t *testing.T) {
+	config := models.DefaultConfig()
+	config.InboundScanDetection = true
+	for _, tt := range []struct{ ports, sweep int }{{0, 30}, {15, 0}, {inboundMaxPorts + 1, 30}, {15, inboundMaxARPTargets + 1}} {
+		config.InboundPortThreshold, config.InboundSweepThreshold = tt.ports, tt.sweep
+		if _, err := NewInboundScanDetector(config, nil); err == nil {
+			t.Errorf("thresholds %d and %d accepted", tt.ports, tt.sweep)
+		}
+	}
+}
@@ -0,0 +1,367 @@
+package scanners
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+)
+
+// ifInet6Path lists the host's IPv6 addresses together with their DAD state
+const ifInet6Path = "/proc/net/if_inet6"
+
+// ipv6ProbeWindow is how long to collect echo replies and router advertisements
+const ipv6ProbeWindow = 2 * time.Second
+
+// maxNmapPrefixIPv6 is the largest IPv6 range handed to nmap -6 for a sweep
+const maxNmapPrefixIPv6 = 120
+
+// ICMPv6 message types used for discovery
+const (
+	icmpv6EchoRequest         = 128
+	icmpv6EchoReply           = 129
+	icmpv6RouterSolicitation  = 133
+	icmpv6RouterAdvertisement = 134
+)
+
+// Neighbor discovery option types from RFC 4861
+const (
+	ndOptSourceLinkAddr = 1
+	ndOptPrefixInfo     = 3
+)
+
+// ifaFlagDADFailed marks an address whose duplicate address detection failed
+const ifaFlagDADFailed = 0x08
+
+// errIPv6ProbeUnsupported is returned where raw ICMPv6 sockets are unavailable
+var errIPv6ProbeUnsupported = errors.New("ipv6 link probing not supported on this platform")
+
+// RouterAdvertisement is a router advertisement observed on a link
+type RouterAdvertisement struct {
+	Router    string
+	MAC       string
+	Interface string
+	Lifetime  time.Duration
+	Prefixes  []RAPrefix
+}
+
+// RAPrefix is a prefix information option carried in a router advertisement
+type RAPrefix struct {
+	Prefix     string
+	Autonomous bool
+}
+
+// ipv6LinkProbe is what one multicast probe of a link turned up
+type ipv6LinkProbe struct {
+	Responders     []string
+	Advertisements []RouterAdvertisement
+}
+
+// parseRouterAdvertisement decodes an ICMPv6 router advertisement message
+func parseRouterAdvertisement(msg []byte, router, iface string) (RouterAdvertisement, bool) {
+	if len(msg) < 16 || msg[0] != icmpv6RouterAdvertisement {
+		return RouterAdvertisement{}, false
+	}
+
+	ra := RouterAdvertisement{
+		Router:    router,
+		Interface: iface,
+		Lifetime:  time.Duration(uint16(msg[6])<<8|uint16(msg[7])) * time.Second,
+	}
+
+	options := msg[16:]
+	for len(options) >= 2 {
+		optType := options[0]
+		optLen := int(options[1]) * 8
+		if optLen == 0 || optLen > len(options) {
+			break
+		}
+		opt := options[:optLen]
+
+		switch optType {
+		case ndOptSourceLinkAddr:
+			if len(opt) >= 8 {
+				ra.MAC = strings.ToUpper(net.HardwareAddr(opt[2:8]).String())
+			}
+		case ndOptPrefixInfo:
+			if len(opt) >= 32 {
+				prefixLen := int(opt[2])
+				if prefixLen <= 128 {
+					prefix := &net.IPNet{IP: net.IP(opt[16:32]), Mask: net.CIDRMask(prefixLen, 128)}
+					ra.Prefixes = append(ra.Prefixes, RAPrefix{
+						Prefix:     prefix.String(),
+						Autonomous: opt[3]&0x40 != 0,
+					})
+				}
+			}
+		}
+
+		options = options[optLen:]
+	}
+
+	return ra, true
+}
+
+// parsePingResponders extracts the replying addresses from ping -6 output
+func parsePingResponders(output string) []string {
+	var responders []string
+	seen := make(map[string]bool)
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		idx := strings.Index(line, " from ")
+		if idx < 0 {
+			continue
+		}
+
+		fields := strings.Fields(line[idx+len(" from "):])
+		if len(fields) == 0 {
+			continue
+		}
+
+		addr := strings.TrimSuffix(fields[0], ":")
+		if zone := strings.Index(addr, "%"); zone >= 0 {
+			addr = addr[:zone]
+		}
+		if ip := net.ParseIP(addr); ip != nil && ip.To4() == nil && !seen[addr] {
+			seen[addr] = true
+			responders = append(responders, addr)
+		}
+	}
+
+	return responders
+}
+
+// parseDADFailures returns "address%interface" for every address whose DAD failed
+func parseDADFailures(output string) []string {
+	var failures []string
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		// address, ifindex, prefix length, scope, flags, interface
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 6 || len(fields[0]) != 32 {
+			continue
+		}
+
+		var flags uint32
+		if _, err := fmt.Sscanf(fields[4], "%x", &flags); err != nil {
+			continue
+		}
+		if flags&ifaFlagDADFailed == 0 {
+			continue
+		}
+
+		var ip net.IP
+		for i := 0; i < 32; i += 2 {
+			var b byte
+			if _, err := fmt.Sscanf(fields[0][i:i+2], "%02x", &b); err != nil {
+				ip = nil
+				break
+			}
+			ip = append(ip, b)
+		}
+		if len(ip) == net.IPv6len {
+			failures = append(failures, ip.String()+"%"+fields[5])
+		}
+	}
+
+	return failures
+}
+
+// probeIPv6 probes every interface at most once per scan pass
+func (ns *NetworkScanner) probeIPv6(ctx context.Context, iface string) (*ipv6LinkProbe, error) {
+	if probe, ok := ns.ipv6Probes[iface]; ok {
+		return probe, nil
+	}
+
+	probe, err := probeIPv6Link(ctx, iface, ipv6ProbeWindow)
+	if err != nil {
+		// Without raw sockets fall back to ping; router advertisements stay unseen
+		output, pingErr := ns.runner.Output(ctx, "ping", "-6", "-c", "2", "-w", "2", "ff02::1%"+iface)
+		if pingErr != nil && len(output) == 0 {
+			return nil, err
+		}
+		probe = &ipv6LinkProbe{Responders: parsePingResponders(string(output))}
+	}
+
+	ns.ipv6Probes[iface] = probe
+	return probe, nil
+}
+
+// probeIPv6Links probes every up, non-loopback interface with an IPv6 address
+// that has not been probed yet this pass, so router advertisements are checked
+// whichever method discovered the hosts
+func (ns *NetworkScanner) probeIPv6Links(ctx context.Context) {
+	targets, err := localInterfaceTargets(true)
+	if err != nil {
+		return
+	}
+	for _, target := range targets {
+		if ctx.Err() != nil {
+			return
+		}
+		if target.IsIPv6() && target.Interface != "" {
+			ns.probeIPv6(ctx, target.Interface)
+		}
+	}
+}
+
+// scanIPv6Link discovers IPv6 hosts with an all-nodes multicast probe and the neighbor table
+func (ns *NetworkScanner) scanIPv6Link(ctx context.Context, target ScanTarget) ([]models.NetworkDevice, error) {
+	if !target.IsIPv6() {
+		return nil, fmt.Errorf("%s is not an IPv6 range", target)
+	}
+
+	var responders []string
+	if target.Interface != "" {
+		if probe, err := ns.probeIPv6(ctx, target.Interface); err == nil {
+			responders = probe.Responders
+		}
+	}
+
+	neighbors, _ := ReadNeighborTable()
+	neighborIndex := neighborsByIP(neighbors)
+
+	var devices []models.NetworkDevice
+	seen := make(map[string]bool)
+
+	for _, neighbor := range neighbors {
+		ip := net.ParseIP(neighbor.IP)
+		if ip == nil || !target.Network.Contains(ip) || seen[neighbor.IP] {
+			continue
+		}
+		seen[neighbor.IP] = true
+		devices = append(devices, models.NetworkDevice{
+			IP:        neighbor.IP,
+			MAC:       neighbor.MAC,
+			State:     "up",
+			Interface: neighbor.Interface,
+		})
+	}
+
+	for _, responder := range responders {
+		ip := net.ParseIP(responder)
+		if ip == nil || !target.Network.Contains(ip) || seen[responder] {
+			continue
+		}
+		seen[responder] = true
+		devices = append(devices, models.NetworkDevice{
+			IP:    responder,
+			MAC:   neighborIndex[responder].MAC,
+			State: "up",
+		})
+	}
+
+	sortDevicesByIP(devices)
+	return devices, nil
+}
+
+// DetectIPv6Attacks applies the IPv6 rules to the router advertisements seen this
+// pass and to the host's own addresses: rogue routers, unexpected SLAAC prefixes
+// and failed duplicate address detection. Without configured allow-lists, the
+// routers and prefixes seen on the first pass that observes any advertisement
+// become the baseline.
+func (ns *NetworkScanner) DetectIPv6Attacks() []models.Attack {
+	var attacks []models.Attack
+
+	authorizedRouters := make(map[string]bool)
+	for _, router := range ns.config.IPv6AuthorizedRouters {
+		authorizedRouters[strings.ToLower(router)] = true
+	}
+
+	var authorizedPrefixes []*net.IPNet
+	for _, prefix := range ns.config.IPv6AuthorizedPrefixes {
+		if _, network, err := net.ParseCIDR(prefix); err == nil {
+			authorizedPrefixes = append(authorizedPrefixes, network)
+		}
+	}
+
+	if !ns.ipv6Baselined {
+		for _, probe := range ns.ipv6Probes {
+			for _, ra := range probe.Advertisements {
+				ns.ipv6Baselined = true
+				ns.learnedRouters[strings.ToLower(ra.Router)] = true
+				for _, prefix := range ra.Prefixes {
+					ns.learnedPrefixes[prefix.Prefix] = true
+				}
+			}
+		}
+	}
+
+	for iface, probe := range ns.ipv6Probes {
+		for _, ra := range probe.Advertisements {
+			var authorized bool
+			if len(authorizedRouters) > 0 {
+				authorized = authorizedRouters[strings.ToLower(ra.Router)] ||
+					(ra.MAC != "" && authorizedRouters[strings.ToLower(ra.MAC)])
+			} else {
+				authorized = ns.learnedRouters[strings.ToLower(ra.Router)]
+			}
+
+			if !authorized {
+				attacks = append(attacks, models.Attack{
+					Type:        "ROGUE_ROUTER_ADVERTISEMENT",
+					Severity:    models.SeverityHigh,
+					Description: fmt.Sprintf("Unauthorized IPv6 router advertisement from %s (MAC: %s) on %s, router lifetime %s", ra.Router, ra.MAC, iface, ra.Lifetime),
+					Target:      ra.Router,
+					Timestamp:   time.Now(),
+				})
+			}
+
+			for _, prefix := range ra.Prefixes {
+				if !prefix.Autonomous || ns.isExpectedPrefix(prefix.Prefix, authorizedPrefixes) {
+					continue
+				}
+				attacks = append(attacks, models.Attack{
+					Type:        "UNEXPECTED_SLAAC_PREFIX",
+					Severity:    models.SeverityHigh,
+					Description: fmt.Sprintf("Router %s on %s advertises unexpected SLAAC prefix %s", ra.Router, iface, prefix.Prefix),
+					Target:      ra.Router,
+					Timestamp:   time.Now(),
+				})
+			}
+		}
+	}
+
+	data, err := os.ReadFile(ifInet6Path)
+	if err == nil {
+		for _, address := range parseDADFailures(string(data)) {
+			attacks = append(attacks, models.Attack{
+				Type:        "IPV6_DAD_CONFLICT",
+				Severity:    models.SeverityHigh,
+				Description: fmt.Sprintf("Duplicate address detection failed for %s: another host claims this address", address),
+				Target:      address,
+				Timestamp:   time.Now(),
+			})
+		}
+	}
+
+	return attacks
+}
+
+// isExpectedPrefix checks a SLAAC prefix against the allow-list, or the learned
+// baseline when no allow-list is configured
+func (ns *NetworkScanner) isExpectedPrefix(prefix string, authorized []*net.IPNet) bool {
+	if len(authorized) == 0 {
+		return ns.learnedPrefixes[prefix]
+	}
+
+	ip, _, err := net.ParseCIDR(prefix)
+	if err != nil {
+		return false
+	}
+	for _, network := range authorized {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+//go:build linux
+
+package scanners
+
+import (
+	"context"
+	"net"
+	"os"
+	"syscall"
+	"time"
+)
+
+// probeIPv6Link pings the all-nodes group and solicits routers on one interface,
+// collecting echo replies and router advertisements until the window closes.
+// It needs a raw ICMPv6 socket, so it fails without CAP_NET_RAW.
+func probeIPv6Link(ctx context.Context, iface string, window time.Duration) (*ipv6LinkProbe, error) {
+	ifi, err := net.InterfaceByName(iface)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.ListenPacket("ip6:ipv6-icmp", "::")
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	// Routers ignore solicitations that did not arrive with a hop limit of 255
+	if raw, err := conn.(*net.IPConn).SyscallConn(); err == nil {
+		raw.Control(func(fd uintptr) {
+			syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IPV6, syscall.IPV6_MULTICAST_HOPS, 255)
+			syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IPV6, syscall.IPV6_MULTICAST_IF, ifi.Index)
+		})
+	}
+
+	id := os.Getpid() & 0xffff
+	echo := []byte{icmpv6EchoRequest, 0, 0, 0, byte(id >> 8), byte(id), 0, 1}
+	solicit := []byte{icmpv6RouterSolicitation, 0, 0, 0, 0, 0, 0, 0}
+
+	if _, err := conn.WriteTo(echo, &net.IPAddr{IP: net.ParseIP("ff02::1"), Zone: iface}); err != nil {
+		return nil, err
+	}
+	conn.WriteTo(solicit, &net.IPAddr{IP: net.ParseIP("ff02::2"), Zone: iface})
+
+	deadline := time.Now().Add(window)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	conn.SetReadDeadline(deadline)
+
+	probe := &ipv6LinkProbe{}
+	seen := make(map[string]bool)
+	buf := make([]byte, 1500)
+
+	for ctx.Err() == nil {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			break
+		}
+
+		src, ok := addr.(*net.IPAddr)
+		if !ok || n == 0 || (src.Zone != "" && src.Zone != iface) {
+			continue
+		}
+		source := src.IP.String()
+
+		switch buf[0] {
+		case icmpv6EchoReply:
+			if !seen[source] {
+				seen[source] = true
+				probe.Responders = append(probe.Responders, source)
+			}
+		case icmpv6RouterAdvertisement:
+			if ra, ok := parseRouterAdvertisement(buf[:n], source, iface); ok {
+				probe.Advertisements = append(probe.Advertisements, ra)
+			}
+		}
+	}
+
+	return probe, nil
+}
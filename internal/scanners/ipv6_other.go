@@ -0,0 +1,13 @@
+//go:build !linux
+
+package scanners
+
+import (
+	"context"
+	"time"
+)
+
+// probeIPv6Link is only implemented on Linux
+func probeIPv6Link(ctx context.Context, iface string, window time.Duration) (*ipv6LinkProbe, error) {
+	return nil, errIPv6ProbeUnsupported
+}
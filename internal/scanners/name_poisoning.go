@@ -0,0 +1,465 @@
+package scanners
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+)
+
+// Destinations and record types of the name resolution protocols Responder poisons
+const (
+	llmnrGroup   = "224.0.0.252"
+	llmnrPort    = 5355
+	mdnsGroup    = "224.0.0.251"
+	mdnsPort     = 5353
+	nbnsPort     = 137
+	dnsTypeA     = 1
+	dnsClassIN   = 1
+	mdnsUnicast  = 0x8000
+	nbnsTypeNB   = 0x20
+	nbnsSuffix   = 0x20 // file server service, the one clients look up for shares
+	nbnsNameLen  = 15
+	dnsHeaderLen = 12
+)
+
+// NameAnswer is a host answering a decoy name that must not exist
+type NameAnswer struct {
+	Protocol  string
+	Name      string
+	Responder string
+	MAC       string
+	Interface string
+	Addresses []string
+}
+
+// nameProbe is one decoy query and how to read answers to it
+type nameProbe struct {
+	protocol string
+	name     string
+	dest     *net.UDPAddr
+	query    []byte
+	parse    func(packet []byte, id uint16) ([]string, bool)
+}
+
+// NamePoisoningScanner sends decoy LLMNR, NBT-NS and mDNS queries and reports
+// every host that answers them
+type NamePoisoningScanner struct {
+	config  *models.AttackDetectorConfig
+	vendors *VendorDB
+}
+
+// NewNamePoisoningScanner creates a new name poisoning scanner
+func NewNamePoisoningScanner(config *models.AttackDetectorConfig, vendors *VendorDB) *NamePoisoningScanner {
+	return &NamePoisoningScanner{
+		config:  config,
+		vendors: vendors,
+	}
+}
+
+// Name returns the registry name of the name poisoning scanner
+func (ps *NamePoisoningScanner) Name() string {
+	return "poisoning"
+}
+
+// Available reports whether decoy probing is enabled
+func (ps *NamePoisoningScanner) Available() bool {
+	return ps.config.PoisoningProbe
+}
+
+// Scan probes every configured interface, or the default route when none are set
+func (ps *NamePoisoningScanner) Scan(ctx context.Context) ([]interface{}, []models.Attack, error) {
+	interfaces := ps.config.PoisoningInterfaces
+	if len(interfaces) == 0 {
+		interfaces = []string{""}
+	}
+
+	var answers []NameAnswer
+	var errs []string
+
+	for _, iface := range interfaces {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+		ifaceAnswers, err := ps.ProbeInterface(ctx, iface)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", interfaceName(iface), err))
+			continue
+		}
+		answers = append(answers, ifaceAnswers...)
+	}
+
+	if len(errs) == len(interfaces) {
+		return nil, nil, fmt.Errorf("name poisoning probe failed: %s", strings.Join(errs, "; "))
+	}
+
+	return nil, ps.DetectPoisoning(answers), nil
+}
+
+// ProbeInterface sends one decoy query per protocol on iface and collects every
+// answer that arrives before the timeout
+func (ps *NamePoisoningScanner) ProbeInterface(ctx context.Context, iface string) ([]NameAnswer, error) {
+	broadcast, err := nbnsBroadcast(iface)
+	if err != nil {
+		return nil, err
+	}
+
+	var idBytes [2]byte
+	if _, err := rand.Read(idBytes[:]); err != nil {
+		return nil, err
+	}
+	id := binary.BigEndian.Uint16(idBytes[:])
+
+	name, err := ps.decoyName()
+	if err != nil {
+		return nil, err
+	}
+
+	// Querying mDNS from an ephemeral port makes compliant responders answer by unicast
+	probes := []nameProbe{
+		{
+			protocol: "LLMNR",
+			name:     name,
+			dest:     &net.UDPAddr{IP: net.ParseIP(llmnrGroup), Port: llmnrPort},
+			query:    buildDNSQuery(id, name, dnsClassIN),
+			parse:    parseDNSAnswers,
+		},
+		{
+			protocol: "mDNS",
+			name:     name + ".local",
+			dest:     &net.UDPAddr{IP: net.ParseIP(mdnsGroup), Port: mdnsPort},
+			query:    buildDNSQuery(id, name+".local", dnsClassIN|mdnsUnicast),
+			parse:    parseDNSAnswers,
+		},
+		{
+			protocol: "NBT-NS",
+			name:     strings.ToUpper(name),
+			dest:     &net.UDPAddr{IP: broadcast, Port: nbnsPort},
+			query:    buildNBNSQuery(id, name),
+			parse:    parseNBNSAnswers,
+		},
+	}
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		answers []NameAnswer
+		errs    []string
+	)
+	for _, probe := range probes {
+		wg.Add(1)
+		go func(probe nameProbe) {
+			defer wg.Done()
+			probeAnswers, err := ps.runProbe(ctx, iface, id, probe)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %v", probe.protocol, err))
+				return
+			}
+			answers = append(answers, probeAnswers...)
+		}(probe)
+	}
+	wg.Wait()
+
+	if len(errs) == len(probes) {
+		return nil, fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+
+	neighbors, _ := ReadNeighborTable()
+	neighborIndex := neighborsByIP(neighbors)
+	for i := range answers {
+		answers[i].MAC = neighborIndex[answers[i].Responder].MAC
+	}
+
+	return answers, nil
+}
+
+// runProbe sends a single decoy query and reads answers until the timeout
+func (ps *NamePoisoningScanner) runProbe(ctx context.Context, iface string, id uint16, probe nameProbe) ([]NameAnswer, error) {
+	conn, err := listenDecoyUDP(ctx, iface)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(ps.config.PoisoningTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := conn.SetReadDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	if _, err := conn.WriteToUDP(probe.query, probe.dest); err != nil {
+		return nil, err
+	}
+
+	var answers []NameAnswer
+	buf := make([]byte, 1500)
+	for {
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				break
+			}
+			return answers, err
+		}
+
+		addresses, ok := probe.parse(buf[:n], id)
+		if !ok {
+			continue
+		}
+		answers = append(answers, NameAnswer{
+			Protocol:  probe.protocol,
+			Name:      probe.name,
+			Responder: from.IP.String(),
+			Interface: iface,
+			Addresses: addresses,
+		})
+	}
+
+	return answers, nil
+}
+
+// decoyName picks a configured decoy name, or makes up a random host name that
+// cannot exist on the network
+func (ps *NamePoisoningScanner) decoyName() (string, error) {
+	var b [4]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+
+	if names := ps.config.PoisoningDecoyNames; len(names) > 0 {
+		return names[int(binary.BigEndian.Uint32(b[:])%uint32(len(names)))], nil
+	}
+	return "ws" + hex.EncodeToString(b[:]), nil
+}
+
+// DetectPoisoning raises NAME_POISONING for every host that answered a decoy query
+func (ps *NamePoisoningScanner) DetectPoisoning(answers []NameAnswer) []models.Attack {
+	var attacks []models.Attack
+
+	seen := make(map[string]bool)
+	sort.Slice(answers, func(i, j int) bool {
+		if answers[i].Responder != answers[j].Responder {
+			return answers[i].Responder < answers[j].Responder
+		}
+		return answers[i].Protocol < answers[j].Protocol
+	})
+	for _, answer := range answers {
+		key := answer.Protocol + "%" + answer.Responder + "%" + answer.Interface
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		mac := answer.MAC
+		if mac == "" {
+			mac = "unknown"
+		}
+		description := fmt.Sprintf("%s poisoning: %s (MAC: %s%s) answered decoy name %q with %s",
+			answer.Protocol, answer.Responder, mac, deviceLabel(ps.vendors.Lookup(answer.MAC), "", false),
+			answer.Name, listOrNone(answer.Addresses))
+		if answer.Interface != "" {
+			description += " on " + answer.Interface
+		}
+
+		attacks = append(attacks, models.Attack{
+			Type:        "NAME_POISONING",
+			Severity:    models.SeverityHigh,
+			Description: description,
+			Target:      answer.Responder,
+			Timestamp:   time.Now(),
+			Vendor:      ps.vendors.Lookup(answer.MAC),
+		})
+	}
+
+	return attacks
+}
+
+// buildDNSQuery creates a single-question A query in DNS wire format, as used by
+// both LLMNR and mDNS
+func buildDNSQuery(id uint16, name string, class uint16) []byte {
+	packet := make([]byte, dnsHeaderLen, dnsHeaderLen+len(name)+6)
+	binary.BigEndian.PutUint16(packet[0:2], id)
+	binary.BigEndian.PutUint16(packet[4:6], 1)
+
+	for _, label := range strings.Split(strings.Trim(name, "."), ".") {
+		packet = append(packet, byte(len(label)))
+		packet = append(packet, label...)
+	}
+	packet = append(packet, 0)
+	packet = binary.BigEndian.AppendUint16(packet, dnsTypeA)
+	packet = binary.BigEndian.AppendUint16(packet, class)
+	return packet
+}
+
+// parseDNSAnswers reads the A records of a response to query id. It reports false
+// when the packet is not a response carrying answers.
+func parseDNSAnswers(packet []byte, id uint16) ([]string, bool) {
+	if len(packet) < dnsHeaderLen || binary.BigEndian.Uint16(packet[0:2]) != id || packet[2]&0x80 == 0 {
+		return nil, false
+	}
+	questions := int(binary.BigEndian.Uint16(packet[4:6]))
+	answerCount := int(binary.BigEndian.Uint16(packet[6:8]))
+	if answerCount == 0 {
+		return nil, false
+	}
+
+	offset := dnsHeaderLen
+	for i := 0; i < questions; i++ {
+		var ok bool
+		if offset, ok = skipDNSName(packet, offset); !ok || offset+4 > len(packet) {
+			return nil, true
+		}
+		offset += 4
+	}
+
+	var addresses []string
+	for i := 0; i < answerCount; i++ {
+		var ok bool
+		if offset, ok = skipDNSName(packet, offset); !ok || offset+10 > len(packet) {
+			break
+		}
+		recordType := binary.BigEndian.Uint16(packet[offset : offset+2])
+		length := int(binary.BigEndian.Uint16(packet[offset+8 : offset+10]))
+		offset += 10
+		if offset+length > len(packet) {
+			break
+		}
+		if recordType == dnsTypeA && length == 4 {
+			addresses = append(addresses, net.IP(packet[offset:offset+4]).String())
+		}
+		offset += length
+	}
+
+	return addresses, true
+}
+
+// skipDNSName returns the offset just past the possibly compressed name at offset
+func skipDNSName(packet []byte, offset int) (int, bool) {
+	for offset < len(packet) {
+		length := int(packet[offset])
+		switch {
+		case length == 0:
+			return offset + 1, true
+		case length&0xc0 == 0xc0:
+			return offset + 2, offset+2 <= len(packet)
+		default:
+			offset += 1 + length
+		}
+	}
+	return 0, false
+}
+
+// buildNBNSQuery creates a broadcast NetBIOS name query for the file server service
+func buildNBNSQuery(id uint16, name string) []byte {
+	packet := make([]byte, dnsHeaderLen, dnsHeaderLen+38)
+	binary.BigEndian.PutUint16(packet[0:2], id)
+	binary.BigEndian.PutUint16(packet[2:4], 0x0110) // recursion desired, broadcast
+	binary.BigEndian.PutUint16(packet[4:6], 1)
+
+	packet = append(packet, 32)
+	packet = append(packet, encodeNetBIOSName(name, nbnsSuffix)...)
+	packet = append(packet, 0)
+	packet = binary.BigEndian.AppendUint16(packet, nbnsTypeNB)
+	packet = binary.BigEndian.AppendUint16(packet, dnsClassIN)
+	return packet
+}
+
+// encodeNetBIOSName applies the RFC 1001 first-level encoding to a padded name
+func encodeNetBIOSName(name string, suffix byte) []byte {
+	padded := []byte(strings.ToUpper(name))
+	if len(padded) > nbnsNameLen {
+		padded = padded[:nbnsNameLen]
+	}
+	for len(padded) < nbnsNameLen {
+		padded = append(padded, ' ')
+	}
+	padded = append(padded, suffix)
+
+	encoded := make([]byte, 0, 32)
+	for _, b := range padded {
+		encoded = append(encoded, 'A'+(b>>4), 'A'+(b&0x0f))
+	}
+	return encoded
+}
+
+// parseNBNSAnswers reads the addresses of a positive name query response to id
+func parseNBNSAnswers(packet []byte, id uint16) ([]string, bool) {
+	if len(packet) < dnsHeaderLen || binary.BigEndian.Uint16(packet[0:2]) != id || packet[2]&0x80 == 0 {
+		return nil, false
+	}
+	if binary.BigEndian.Uint16(packet[6:8]) == 0 {
+		return nil, false
+	}
+
+	offset, ok := skipDNSName(packet, dnsHeaderLen)
+	if !ok || offset+10 > len(packet) {
+		return nil, true
+	}
+	recordType := binary.BigEndian.Uint16(packet[offset : offset+2])
+	length := int(binary.BigEndian.Uint16(packet[offset+8 : offset+10]))
+	offset += 10
+	if recordType != nbnsTypeNB || offset+length > len(packet) {
+		return nil, true
+	}
+
+	// Each entry is two bytes of flags followed by an IPv4 address
+	var addresses []string
+	for entry := packet[offset : offset+length]; len(entry) >= 6; entry = entry[6:] {
+		addresses = append(addresses, net.IP(entry[2:6]).String())
+	}
+	return addresses, true
+}
+
+// nbnsBroadcast returns the IPv4 broadcast address NetBIOS queries go to on iface
+func nbnsBroadcast(iface string) (net.IP, error) {
+	if iface == "" {
+		return net.IPv4bcast, nil
+	}
+
+	ifi, err := net.InterfaceByName(iface)
+	if err != nil {
+		return nil, err
+	}
+	addrs, err := ifi.Addrs()
+	if err != nil {
+		return nil, err
+	}
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.To4() == nil {
+			continue
+		}
+		ip := ipNet.IP.To4()
+		mask := net.IP(ipNet.Mask).To4()
+		if mask == nil {
+			continue
+		}
+		broadcast := make(net.IP, 4)
+		for i := range broadcast {
+			broadcast[i] = ip[i] | ^mask[i]
+		}
+		return broadcast, nil
+	}
+	return nil, fmt.Errorf("%s has no IPv4 address", iface)
+}
+
+// interfaceName names an interface in messages, where empty means the default route
+func interfaceName(iface string) string {
+	if iface == "" {
+		return "default"
+	}
+	return iface
+}
@@ -0,0 +1,46 @@
+//go:build linux
+
+package scanners
+
+import (
+	"context"
+	"net"
+	"syscall"
+)
+
+// listenDecoyUDP opens an ephemeral UDP socket whose multicast and broadcast
+// queries leave through iface, or the default route when iface is empty. Binding
+// to an interface needs root or CAP_NET_RAW.
+func listenDecoyUDP(ctx context.Context, iface string) (*net.UDPConn, error) {
+	if iface == "" {
+		return net.ListenUDP("udp4", &net.UDPAddr{})
+	}
+
+	ifi, err := net.InterfaceByName(iface)
+	if err != nil {
+		return nil, err
+	}
+
+	lc := net.ListenConfig{
+		Control: func(network, address string, c syscall.RawConn) error {
+			var sockErr error
+			err := c.Control(func(fd uintptr) {
+				sockErr = syscall.SetsockoptIPMreqn(int(fd), syscall.IPPROTO_IP, syscall.IP_MULTICAST_IF,
+					&syscall.IPMreqn{Ifindex: int32(ifi.Index)})
+				if sockErr == nil {
+					sockErr = syscall.BindToDevice(int(fd), iface)
+				}
+			})
+			if err != nil {
+				return err
+			}
+			return sockErr
+		},
+	}
+
+	conn, err := lc.ListenPacket(ctx, "udp4", ":0")
+	if err != nil {
+		return nil, err
+	}
+	return conn.(*net.UDPConn), nil
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package scanners
+
+import (
+	"context"
+	"net"
+)
+
+// listenDecoyUDP opens an ephemeral UDP socket. Queries always leave through the
+// default route since binding to an interface is only implemented on Linux.
+func listenDecoyUDP(ctx context.Context, iface string) (*net.UDPConn, error) {
+	return net.ListenUDP("udp4", &net.UDPAddr{})
+}
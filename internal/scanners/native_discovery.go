@@ -0,0 +1,163 @@
+package scanners
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+)
+
+// maxProbeHosts caps how many addresses a single range probe will touch
+const maxProbeHosts = 4096
+
+// scanWithNeighborTable discovers devices from the kernel neighbor table without
+// external tools. When native probing is enabled the range is swept with TCP
+// connects first so that live hosts get resolved into the table.
+func (ns *NetworkScanner) scanWithNeighborTable(ctx context.Context, target ScanTarget) ([]models.NetworkDevice, error) {
+	alive := make(map[string]bool)
+	if ns.config.NativeProbe {
+		hosts := hostsInNetwork(target.Network, maxProbeHosts)
+		for _, ip := range probeHosts(ctx, hosts, ns.config.NativeProbePorts, ns.config.NativeProbeTimeout, ns.config.NativeProbeWorkers) {
+			alive[ip] = true
+		}
+	}
+
+	neighbors, err := ReadNeighborTable()
+	if err != nil && len(neighbors) == 0 && len(alive) == 0 {
+		return nil, err
+	}
+
+	var devices []models.NetworkDevice
+	seen := make(map[string]bool)
+
+	for _, neighbor := range neighbors {
+		ip := net.ParseIP(neighbor.IP)
+		if ip == nil || !target.Network.Contains(ip) || seen[neighbor.IP] {
+			continue
+		}
+		seen[neighbor.IP] = true
+		devices = append(devices, models.NetworkDevice{
+			IP:        neighbor.IP,
+			MAC:       neighbor.MAC,
+			State:     "up",
+			Interface: neighbor.Interface,
+		})
+	}
+
+	for ip := range alive {
+		if !seen[ip] {
+			devices = append(devices, models.NetworkDevice{
+				IP:    ip,
+				State: "up",
+			})
+		}
+	}
+
+	sortDevicesByIP(devices)
+	return devices, nil
+}
+
+// probeHosts reports which hosts answer a TCP connect on any of the ports.
+// A refused connection counts as alive since the host had to answer it.
+func probeHosts(ctx context.Context, hosts []string, ports []int, timeout time.Duration, workers int) []string {
+	if len(hosts) == 0 || len(ports) == 0 {
+		return nil
+	}
+	if workers <= 0 {
+		workers = 1
+	}
+
+	jobs := make(chan string)
+	var mu sync.Mutex
+	var alive []string
+	var wg sync.WaitGroup
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for host := range jobs {
+				if probeHost(ctx, host, ports, timeout) {
+					mu.Lock()
+					alive = append(alive, host)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+feed:
+	for _, host := range hosts {
+		select {
+		case <-ctx.Done():
+			break feed
+		case jobs <- host:
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	return alive
+}
+
+// probeHost tries each port until the host shows any sign of life
+func probeHost(ctx context.Context, host string, ports []int, timeout time.Duration) bool {
+	dialer := net.Dialer{Timeout: timeout}
+	for _, port := range ports {
+		conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+		if err == nil {
+			conn.Close()
+			return true
+		}
+		if errors.Is(err, syscall.ECONNREFUSED) {
+			return true
+		}
+		if ctx.Err() != nil {
+			return false
+		}
+	}
+	return false
+}
+
+// hostsInNetwork lists the usable IPv4 host addresses of a network, up to limit
+func hostsInNetwork(network *net.IPNet, limit int) []string {
+	ip4 := network.IP.To4()
+	if ip4 == nil {
+		return nil
+	}
+
+	ones, bits := network.Mask.Size()
+	size := uint64(1) << uint(bits-ones)
+
+	start := uint64(ip4[0])<<24 | uint64(ip4[1])<<16 | uint64(ip4[2])<<8 | uint64(ip4[3])
+	first, last := start, start+size-1
+	// Skip the network and broadcast addresses on anything larger than a /31
+	if size > 2 {
+		first++
+		last--
+	}
+
+	var hosts []string
+	for n := first; n <= last && len(hosts) < limit; n++ {
+		hosts = append(hosts, net.IPv4(byte(n>>24), byte(n>>16), byte(n>>8), byte(n)).String())
+	}
+	return hosts
+}
+
+// sortDevicesByIP orders devices numerically by address
+func sortDevicesByIP(devices []models.NetworkDevice) {
+	sort.Slice(devices, func(i, j int) bool {
+		a, b := net.ParseIP(devices[i].IP), net.ParseIP(devices[j].IP)
+		if a == nil || b == nil {
+			return devices[i].IP < devices[j].IP
+		}
+		return bytes.Compare(a.To16(), b.To16()) < 0
+	})
+}
@@ -0,0 +1,87 @@
+package scanners
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"strings"
+)
+
+// arpTablePath is where the kernel exposes the IPv4 neighbor (ARP) table
+const arpTablePath = "/proc/net/arp"
+
+// Neighbor is one resolved entry of the kernel neighbor table
+type Neighbor struct {
+	IP        string
+	MAC       string
+	Interface string
+}
+
+// ReadNeighborTable returns the resolved IPv4 and IPv6 neighbors known to the kernel
+func ReadNeighborTable() ([]Neighbor, error) {
+	data, err := os.ReadFile(arpTablePath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	neighbors := parseARPTable(string(data))
+
+	ipv6Neighbors, err := readIPv6Neighbors()
+	if err != nil {
+		return neighbors, err
+	}
+
+	return append(neighbors, ipv6Neighbors...), nil
+}
+
+// parseARPTable parses the contents of /proc/net/arp
+func parseARPTable(output string) []Neighbor {
+	var neighbors []Neighbor
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	// Skip header line
+	scanner.Scan()
+
+	for scanner.Scan() {
+		// IP address, HW type, Flags, HW address, Mask, Device
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 6 {
+			continue
+		}
+
+		// Flags 0x0 marks an incomplete entry still waiting for a reply
+		if fields[2] == "0x0" || net.ParseIP(fields[0]) == nil {
+			continue
+		}
+
+		mac, err := net.ParseMAC(fields[3])
+		if err != nil || isZeroMAC(mac) {
+			continue
+		}
+
+		neighbors = append(neighbors, Neighbor{
+			IP:        fields[0],
+			MAC:       strings.ToUpper(mac.String()),
+			Interface: fields[5],
+		})
+	}
+
+	return neighbors
+}
+
+// neighborsByIP indexes neighbors for MAC lookups
+func neighborsByIP(neighbors []Neighbor) map[string]Neighbor {
+	index := make(map[string]Neighbor, len(neighbors))
+	for _, neighbor := range neighbors {
+		index[neighbor.IP] = neighbor
+	}
+	return index
+}
+
+func isZeroMAC(mac net.HardwareAddr) bool {
+	for _, b := range mac {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,102 @@
+//go:build linux
+
+package scanners
+
+import (
+	"encoding/binary"
+	"net"
+	"strings"
+	"syscall"
+)
+
+// Neighbor message layout from linux/neighbour.h
+const (
+	sizeofNdMsg = 12
+
+	ndaDst    = 1
+	ndaLLAddr = 2
+
+	nudIncomplete = 0x01
+	nudFailed     = 0x20
+)
+
+// readIPv6Neighbors dumps the IPv6 neighbor cache over rtnetlink
+func readIPv6Neighbors() ([]Neighbor, error) {
+	rib, err := syscall.NetlinkRIB(syscall.RTM_GETNEIGH, syscall.AF_INET6)
+	if err != nil {
+		return nil, err
+	}
+
+	messages, err := syscall.ParseNetlinkMessage(rib)
+	if err != nil {
+		return nil, err
+	}
+
+	interfaceNames := make(map[int]string)
+	if ifaces, err := net.Interfaces(); err == nil {
+		for _, iface := range ifaces {
+			interfaceNames[iface.Index] = iface.Name
+		}
+	}
+
+	var neighbors []Neighbor
+	for _, msg := range messages {
+		if msg.Header.Type != syscall.RTM_NEWNEIGH || len(msg.Data) < sizeofNdMsg {
+			continue
+		}
+
+		family := msg.Data[0]
+		ifindex := int(int32(binary.NativeEndian.Uint32(msg.Data[4:8])))
+		state := binary.NativeEndian.Uint16(msg.Data[8:10])
+		if family != syscall.AF_INET6 || state&(nudIncomplete|nudFailed) != 0 {
+			continue
+		}
+
+		var ip net.IP
+		var mac net.HardwareAddr
+		for _, attr := range parseNetlinkAttrs(msg.Data[sizeofNdMsg:]) {
+			switch attr.Attr.Type {
+			case ndaDst:
+				ip = net.IP(attr.Value)
+			case ndaLLAddr:
+				mac = net.HardwareAddr(attr.Value)
+			}
+		}
+
+		if ip == nil || len(mac) != 6 || isZeroMAC(mac) {
+			continue
+		}
+
+		neighbors = append(neighbors, Neighbor{
+			IP:        ip.String(),
+			MAC:       strings.ToUpper(mac.String()),
+			Interface: interfaceNames[ifindex],
+		})
+	}
+
+	return neighbors, nil
+}
+
+// parseNetlinkAttrs splits a buffer of rtnetlink attributes
+func parseNetlinkAttrs(b []byte) []syscall.NetlinkRouteAttr {
+	var attrs []syscall.NetlinkRouteAttr
+	for len(b) >= syscall.SizeofRtAttr {
+		length := int(binary.NativeEndian.Uint16(b[0:2]))
+		attrType := binary.NativeEndian.Uint16(b[2:4])
+		if length < syscall.SizeofRtAttr || length > len(b) {
+			break
+		}
+
+		attrs = append(attrs, syscall.NetlinkRouteAttr{
+			Attr:  syscall.RtAttr{Len: uint16(length), Type: attrType},
+			Value: b[syscall.SizeofRtAttr:length],
+		})
+
+		aligned := (length + syscall.RTA_ALIGNTO - 1) &^ (syscall.RTA_ALIGNTO - 1)
+		if aligned > len(b) {
+			break
+		}
+		b = b[aligned:]
+	}
+	return attrs
+}
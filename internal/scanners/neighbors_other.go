@@ -0,0 +1,8 @@
+//go:build !linux
+
+package scanners
+
+// readIPv6Neighbors is only implemented on Linux
+func readIPv6Neighbors() ([]Neighbor, error) {
+	return nil, nil
+}
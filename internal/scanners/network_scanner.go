@@ -2,12 +2,12 @@ package scanners
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"os"
-	"os/exec"
-	"regexp"
 	"strings"
 	"time"
 
@@ -16,47 +16,212 @@ import (
 
 // NetworkScanner handles network device discovery and port scanning
 type NetworkScanner struct {
+	config       *models.AttackDetectorConfig
 	knownDevices map[string]bool
+	runner       CommandRunner
+	vendors      *VendorDB
+	arp          *arpWatch
+	services     *serviceWatch
+
+	// Background listeners run until Close cancels listenCtx
+	listenCtx     context.Context
+	stopListeners context.CancelFunc
+
+	// Port scan list and policies, parsed once from configuration
+	ports    []int
+	policies []portPolicy
+
+	// IPv6 link probes of the current pass and the learned router baseline
+	ipv6Probes      map[string]*ipv6LinkProbe
+	ipv6Baselined   bool
+	learnedRouters  map[string]bool
+	learnedPrefixes map[string]bool
 }
 
-// NewNetworkScanner creates a new network scanner
-func NewNetworkScanner(knownDevices []string) *NetworkScanner {
+// NewNetworkScanner creates a new network scanner. It fails when the port list
+// or port policies in config are invalid.
+func NewNetworkScanner(config *models.AttackDetectorConfig, knownDevices []string, runner CommandRunner, vendors *VendorDB) (*NetworkScanner, error) {
+	ports, err := ParsePortList(config.PortScanPorts)
+	if err != nil {
+		return nil, fmt.Errorf("invalid port scan ports: %v", err)
+	}
+	policies, err := compilePortPolicies(config.PortPolicies)
+	if err != nil {
+		return nil, err
+	}
+
 	knownMap := make(map[string]bool)
 	for _, device := range knownDevices {
 		knownMap[device] = true
 	}
+	listenCtx, stopListeners := context.WithCancel(context.Background())
 	return &NetworkScanner{
-		knownDevices: knownMap,
+		config:          config,
+		knownDevices:    knownMap,
+		runner:          runner,
+		vendors:         vendors,
+		arp:             newARPWatch(),
+		services:        newServiceWatch(),
+		listenCtx:       listenCtx,
+		stopListeners:   stopListeners,
+		ports:           ports,
+		policies:        policies,
+		ipv6Probes:      make(map[string]*ipv6LinkProbe),
+		learnedRouters:  make(map[string]bool),
+		learnedPrefixes: make(map[string]bool),
+	}, nil
+}
+
+// Name returns the registry name of the network scanner
+func (ns *NetworkScanner) Name() string {
+	return "network"
+}
+
+// Available reports whether network discovery can run. The built-in neighbor
+// table discovery needs no external tools, so it always can.
+func (ns *NetworkScanner) Available() bool {
+	return true
+}
+
+// Close stops the background listeners
+func (ns *NetworkScanner) Close() error {
+	ns.stopListeners()
+	return nil
+}
+
+// Scan discovers network devices and scans their ports. Errors of the
+// background listeners are returned along with the results.
+func (ns *NetworkScanner) Scan(ctx context.Context) ([]interface{}, []models.Attack, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	if ns.config.PassiveDiscovery {
+		ns.startServiceListeners()
+	}
+
+	devices, attacks, err := ns.ScanNetwork(ctx)
+	if err != nil {
+		return nil, nil, err
 	}
+
+	var errs []string
+	attacks = append(attacks, ns.DetectARPAttacks(devices)...)
+	if err := ns.arp.listenerError(); err != nil {
+		errs = append(errs, err.Error())
+	}
+	errs = append(errs, ns.services.listenerErrors()...)
+	attacks = append(attacks, ns.DetectUPnPPortMapping(devices)...)
+
+	if ns.config.ScanIPv6 {
+		ns.probeIPv6Links(ctx)
+		attacks = append(attacks, ns.DetectIPv6Attacks()...)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return toInterfaces(devices), attacks, err
+	}
+
+	devices, portAttacks, err := ns.ScanPorts(ctx, devices)
+	attacks = append(attacks, portAttacks...)
+	annotateNetworkAttacks(attacks, devices)
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	if len(errs) > 0 {
+		return toInterfaces(devices), attacks, errors.New(strings.Join(errs, "; "))
+	}
+	return toInterfaces(devices), attacks, nil
 }
 
-// ScanNetwork discovers devices on the network using various methods
-func (ns *NetworkScanner) ScanNetwork() ([]models.NetworkDevice, []models.Attack, error) {
+// ScanNetwork discovers devices in every configured range using various methods
+func (ns *NetworkScanner) ScanNetwork(ctx context.Context) ([]models.NetworkDevice, []models.Attack, error) {
 	var devices []models.NetworkDevice
 	var attacks []models.Attack
 
+	targets, err := ResolveTargets(ns.config.ScanTargets, ns.config.ScanIPv6)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(targets) == 0 {
+		return nil, nil, fmt.Errorf("no scan targets resolved from %v", ns.config.ScanTargets)
+	}
+
+	exclusions, err := ParseExclusions(ns.config.ScanExclusions)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	// Try different scanning methods in order of preference
-	deviceLists := []func() ([]models.NetworkDevice, error){
+	deviceLists := []func(context.Context, ScanTarget) ([]models.NetworkDevice, error){
 		ns.scanWithNmap,
 		ns.scanWithPing,
 		ns.scanWithNetdiscover,
+		ns.scanWithNeighborTable,
 	}
 
-	for _, scanMethod := range deviceLists {
-		devList, err := scanMethod()
-		if err == nil && len(devList) > 0 {
-			devices = devList
+	// IPv6 ranges are too large to sweep, so only nmap on small ranges and link probing apply
+	ipv6DeviceLists := []func(context.Context, ScanTarget) ([]models.NetworkDevice, error){
+		ns.scanWithNmap,
+		ns.scanIPv6Link,
+	}
+
+	// Each pass probes every IPv6 link afresh
+	ns.ipv6Probes = make(map[string]*ipv6LinkProbe)
+
+	// The neighbor table supplies MAC addresses the external tools don't report
+	neighbors, _ := ReadNeighborTable()
+	neighborIndex := neighborsByIP(neighbors)
+
+	seen := make(map[string]bool)
+	for _, target := range targets {
+		if err := ctx.Err(); err != nil {
+			return devices, attacks, err
+		}
+
+		methods := deviceLists
+		if target.IsIPv6() {
+			methods = ipv6DeviceLists
+		}
+
+		for _, scanMethod := range methods {
+			devList, err := scanMethod(ctx, target)
+			if err != nil || len(devList) == 0 {
+				continue
+			}
+
+			// Tag devices with their origin and drop exclusions and duplicates
+			for _, device := range devList {
+				if seen[device.IP] || isExcluded(device.IP, exclusions) {
+					continue
+				}
+				seen[device.IP] = true
+				if neighbor, ok := neighborIndex[device.IP]; ok && device.MAC == "" {
+					device.MAC = neighbor.MAC
+				}
+				device.Range = target.String()
+				if device.Interface == "" {
+					device.Interface = target.Interface
+				}
+				devices = append(devices, device)
+			}
 			break
 		}
 	}
 
+	for i := range devices {
+		ns.services.enrich(&devices[i])
+		classifyNetworkDevice(&devices[i], ns.vendors)
+	}
+
 	// Check for unknown devices
 	for _, device := range devices {
 		if !ns.knownDevices[device.IP] {
 			attacks = append(attacks, models.Attack{
 				Type:        "UNKNOWN_DEVICE",
 				Severity:    models.SeverityHigh,
-				Description: fmt.Sprintf("Unknown device detected: %s", device.IP),
+				Description: fmt.Sprintf("Unknown device detected: %s%s", device.IP, deviceLabel(device.Vendor, device.Class, device.RandomizedMAC)),
 				Target:      device.IP,
 				Timestamp:   time.Now(),
 			})
@@ -67,28 +232,40 @@ func (ns *NetworkScanner) ScanNetwork() ([]models.NetworkDevice, []models.Attack
 }
 
 // scanWithNmap uses nmap to scan for devices
-func (ns *NetworkScanner) scanWithNmap() ([]models.NetworkDevice, error) {
-	if !isCommandAvailable("nmap") {
+func (ns *NetworkScanner) scanWithNmap(ctx context.Context, target ScanTarget) ([]models.NetworkDevice, error) {
+	if !isCommandAvailable(ns.runner, "nmap") {
 		return nil, fmt.Errorf("nmap not available")
 	}
 
-	cmd := exec.Command("nmap", "-sn", "192.168.1.0/24")
-	output, err := cmd.CombinedOutput()
+	args := []string{"-sn", "-oX", "-", target.String()}
+	if target.IsIPv6() {
+		if ones, _ := target.Network.Mask.Size(); ones < maxNmapPrefixIPv6 {
+			return nil, fmt.Errorf("%s is too large for an nmap sweep", target)
+		}
+		args = append([]string{"-6"}, args...)
+	}
+	if len(ns.config.ScanExclusions) > 0 {
+		args = append(args, "--exclude", strings.Join(ns.config.ScanExclusions, ","))
+	}
+
+	output, err := ns.runner.Output(ctx, "nmap", args...)
 	if err != nil {
 		return nil, err
 	}
 
-	return ns.parseNmapOutput(string(output)), nil
+	return parseNmapXML(output)
 }
 
 // scanWithPing uses ping to discover devices
-func (ns *NetworkScanner) scanWithPing() ([]models.NetworkDevice, error) {
-	if !isCommandAvailable("fping") {
+func (ns *NetworkScanner) scanWithPing(ctx context.Context, target ScanTarget) ([]models.NetworkDevice, error) {
+	if !isCommandAvailable(ns.runner, "fping") {
 		return nil, fmt.Errorf("fping not available")
 	}
+	if target.IsIPv6() {
+		return nil, fmt.Errorf("fping cannot sweep IPv6 range %s", target)
+	}
 
-	cmd := exec.Command("fping", "-a", "-g", "192.168.1.0/24", "-r", "1")
-	output, err := cmd.CombinedOutput()
+	output, err := ns.runner.Output(ctx, "fping", "-a", "-g", target.String(), "-r", "1")
 	if err != nil {
 		return nil, err
 	}
@@ -97,27 +274,13 @@ func (ns *NetworkScanner) scanWithPing() ([]models.NetworkDevice, error) {
 }
 
 // scanWithNetdiscover uses a custom script if available
-func (ns *NetworkScanner) scanWithNetdiscover() ([]models.NetworkDevice, error) {
-	scripts := []string{
-		"./scripts/netdiscover.sh",
-		"../scripts/netdiscover.sh",
-		"netdiscover.sh",
-	}
-
-	var scriptPath string
-	for _, path := range scripts {
-		if _, err := os.Stat(path); err == nil {
-			scriptPath = path
-			break
-		}
-	}
-
+func (ns *NetworkScanner) scanWithNetdiscover(ctx context.Context, target ScanTarget) ([]models.NetworkDevice, error) {
+	scriptPath := findNetdiscoverScript()
 	if scriptPath == "" {
 		return nil, fmt.Errorf("netdiscover script not found")
 	}
 
-	cmd := exec.Command("/bin/bash", scriptPath)
-	output, err := cmd.CombinedOutput()
+	output, err := ns.runner.Output(ctx, "/bin/bash", scriptPath, target.String())
 	if err != nil {
 		return nil, err
 	}
@@ -125,27 +288,20 @@ func (ns *NetworkScanner) scanWithNetdiscover() ([]models.NetworkDevice, error)
 	return ns.parseNetdiscoverOutput(string(output)), nil
 }
 
-// parseNmapOutput parses nmap scan output
-func (ns *NetworkScanner) parseNmapOutput(output string) []models.NetworkDevice {
-	var devices []models.NetworkDevice
-
-	scanner := bufio.NewScanner(strings.NewReader(output))
-	for scanner.Scan() {
-		line := scanner.Text()
+// findNetdiscoverScript returns the path of the first netdiscover script found, or ""
+func findNetdiscoverScript() string {
+	scripts := []string{
+		"./scripts/netdiscover.sh",
+		"../scripts/netdiscover.sh",
+		"netdiscover.sh",
+	}
 
-		if strings.Contains(line, "Nmap scan report for") {
-			ipRegex := regexp.MustCompile(`(\d+\.\d+\.\d+\.\d+)`)
-			matches := ipRegex.FindStringSubmatch(line)
-			if len(matches) > 1 {
-				devices = append(devices, models.NetworkDevice{
-					IP:    matches[1],
-					State: "up",
-				})
-			}
+	for _, path := range scripts {
+		if _, err := os.Stat(path); err == nil {
+			return path
 		}
 	}
-
-	return devices
+	return ""
 }
 
 // parseFpingOutput parses fping output
@@ -168,122 +324,152 @@ func (ns *NetworkScanner) parseFpingOutput(output string) []models.NetworkDevice
 	return devices
 }
 
-// parseNetdiscoverOutput parses netdiscover script output
+// parseNetdiscoverOutput parses the netdiscover script, which prints nmap-style
+// "Nmap scan report for" and "MAC Address:" lines
 func (ns *NetworkScanner) parseNetdiscoverOutput(output string) []models.NetworkDevice {
-	return ns.parseNmapOutput(output) // Use same parser for now
-}
+	var devices []models.NetworkDevice
 
-// ScanPorts scans for open ports on discovered devices
-func (ns *NetworkScanner) ScanPorts(devices []models.NetworkDevice) ([]models.NetworkDevice, []models.Attack, error) {
-	var attacks []models.Attack
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
 
-	for i, device := range devices {
-		ports, err := ns.scanDevicePorts(device.IP)
-		if err != nil {
+		// "Nmap scan report for host (addr)" or "Nmap scan report for addr"
+		if idx := strings.Index(line, "Nmap scan report for "); idx >= 0 {
+			report := strings.TrimSpace(line[idx+len("Nmap scan report for "):])
+			name := ""
+			addr := report
+			if open := strings.LastIndex(report, "("); open >= 0 && strings.HasSuffix(report, ")") {
+				name = strings.TrimSpace(report[:open])
+				addr = report[open+1 : len(report)-1]
+			}
+			if net.ParseIP(addr) != nil {
+				devices = append(devices, models.NetworkDevice{
+					IP:    addr,
+					Name:  name,
+					State: "up",
+				})
+			}
 			continue
 		}
 
-		devices[i].Ports = ports
-
-		// Check for suspicious ports
-		for _, port := range ports {
-			suspiciousPorts := map[int]string{
-				21:  "FTP",
-				23:  "Telnet",
-				3389: "RDP",
-				445: "SMB",
-			}
-
-			if port.State == "open" {
-				if service, exists := suspiciousPorts[port.Number]; exists {
-					attacks = append(attacks, models.Attack{
-						Type:        "SUSPICIOUS_PORT",
-						Severity:    models.SeverityMedium,
-						Description: fmt.Sprintf("Suspicious open port detected: %s:%d (%s)", device.IP, port.Number, service),
-						Target:      device.IP,
-						Timestamp:   time.Now(),
-					})
+		// "MAC Address: AA:BB:CC:DD:EE:FF (Vendor)" follows its report line
+		if strings.HasPrefix(line, "MAC Address: ") && len(devices) > 0 {
+			fields := strings.Fields(strings.TrimPrefix(line, "MAC Address: "))
+			if len(fields) > 0 {
+				if mac, err := net.ParseMAC(fields[0]); err == nil {
+					devices[len(devices)-1].MAC = strings.ToUpper(mac.String())
 				}
 			}
 		}
 	}
 
-	return devices, attacks, nil
+	return devices
 }
 
-// scanDevicePorts scans ports on a specific device
-func (ns *NetworkScanner) scanDevicePorts(ip string) ([]models.Port, error) {
-	if !isCommandAvailable("nmap") {
-		return nil, fmt.Errorf("nmap not available for port scanning")
-	}
+// ScanPorts scans for open ports on discovered devices
+func (ns *NetworkScanner) ScanPorts(ctx context.Context, devices []models.NetworkDevice) ([]models.NetworkDevice, []models.Attack, error) {
+	var attacks []models.Attack
 
-	cmd := exec.Command("nmap", "-p", "21,22,23,25,53,80,110,143,443,993,995,3389,445", "--open", ip)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return nil, err
-	}
+	ports, policies := ns.ports, ns.policies
 
-	return ns.parsePortScanOutput(string(output)), nil
-}
+	if isCommandAvailable(ns.runner, "nmap") {
+		for i, device := range devices {
+			scanned, err := ns.scanDevicePorts(ctx, device.IP, ports)
+			if err != nil {
+				continue
+			}
 
-// parsePortScanOutput parses nmap port scan output
-func (ns *NetworkScanner) parsePortScanOutput(output string) []models.Port {
-	var ports []models.Port
+			devices[i].Ports = scanned.Ports
+			devices[i].PortsScanned = true
+			mergeDeviceDetails(&devices[i], scanned)
+		}
+	} else {
+		// Without nmap every device is scanned at once by the built-in connect scanner
+		hosts := make([]string, len(devices))
+		for i, device := range devices {
+			hosts[i] = device.IP
+		}
 
-	scanner := bufio.NewScanner(strings.NewReader(output))
-	inPortSection := false
+		open := connectScan(ctx, hosts, ports, ns.config)
+		complete := ctx.Err() == nil
+		for i, device := range devices {
+			devices[i].Ports = open[device.IP]
+			devices[i].PortsScanned = complete
+		}
+	}
 
-	for scanner.Scan() {
-		line := scanner.Text()
+	if ns.config.GrabBanners {
+		ns.grabBanners(ctx, devices)
+	}
 
-		if strings.Contains(line, "PORT") && strings.Contains(line, "STATE") && strings.Contains(line, "SERVICE") {
-			inPortSection = true
-			continue
-		}
+	// Open ports often identify the kind of device better than its name
+	for i := range devices {
+		classifyNetworkDevice(&devices[i], ns.vendors)
+	}
 
-		if inPortSection && strings.TrimSpace(line) != "" && !strings.HasPrefix(line, "MAC") {
-			parts := strings.Fields(line)
-			if len(parts) >= 3 {
-				ports = append(ports, models.Port{
-					Number: parsePortNumber(parts[0]),
-					Protocol: parseProtocol(parts[0]),
-					State:   parts[1],
-					Service: parts[2],
-				})
-			}
-		}
+	for _, device := range devices {
+		attacks = append(attacks, ns.DetectServiceIssues(device)...)
 
-		if strings.Contains(line, "Nmap done") {
-			break
+		// Check open ports against the first policy covering the device
+		if policy := policyForDevice(policies, device); policy != nil {
+			attacks = append(attacks, policy.evaluate(device)...)
 		}
 	}
 
-	return ports
+	return devices, attacks, nil
 }
 
-// Helper functions
+// scanDevicePorts scans ports on a specific device
+func (ns *NetworkScanner) scanDevicePorts(ctx context.Context, ip string, ports []int) (*models.NetworkDevice, error) {
+	if !isCommandAvailable(ns.runner, "nmap") {
+		return nil, fmt.Errorf("nmap not available for port scanning")
+	}
 
-func isCommandAvailable(cmd string) bool {
-	_, err := exec.LookPath(cmd)
-	return err == nil
-}
+	args := []string{"-p", formatPortList(ports), "--open", "-oX", "-"}
+	if ns.config.NmapServiceVersions {
+		args = append(args, "-sV")
+	}
+	if ns.config.NmapOSDetection {
+		args = append(args, "-O")
+	}
+	if strings.Contains(ip, ":") {
+		args = append(args, "-6")
+	}
+	args = append(args, ip)
+
+	output, err := ns.runner.Output(ctx, "nmap", args...)
+	if err != nil {
+		return nil, err
+	}
 
-func parsePortNumber(portStr string) int {
-	parts := strings.Split(portStr, "/")
-	if len(parts) > 0 {
-		if portNum, err := fmt.Sscanf(parts[0], "%d"); err == nil {
-			return portNum
+	scanned, err := parseNmapXML(output)
+	if err != nil {
+		return nil, err
+	}
+	if len(scanned) == 0 {
+		// A host that is up with nothing open is a valid, empty result
+		if nmapHostsUp(output) > 0 {
+			return &models.NetworkDevice{IP: ip, State: "up"}, nil
 		}
+		return nil, fmt.Errorf("no port scan results for %s", ip)
 	}
-	return 0
+	return &scanned[0], nil
 }
 
-func parseProtocol(portStr string) string {
-	parts := strings.Split(portStr, "/")
-	if len(parts) > 1 {
-		return parts[1]
+// mergeDeviceDetails fills in identity details a port scan learned about a device
+func mergeDeviceDetails(device *models.NetworkDevice, scanned *models.NetworkDevice) {
+	if device.MAC == "" {
+		device.MAC = scanned.MAC
+	}
+	if device.Vendor == "" {
+		device.Vendor = scanned.Vendor
+	}
+	if device.Name == "" {
+		device.Name = scanned.Name
+	}
+	if len(scanned.OSGuesses) > 0 {
+		device.OSGuesses = scanned.OSGuesses
 	}
-	return "tcp"
 }
 
 // LoadKnownDevices loads known network devices from file
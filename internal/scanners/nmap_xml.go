@@ -0,0 +1,151 @@
+package scanners
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+)
+
+// nmapRun mirrors the parts of nmap's -oX document the scanner uses
+type nmapRun struct {
+	XMLName  xml.Name   `xml:"nmaprun"`
+	Hosts    []nmapHost `xml:"host"`
+	RunStats struct {
+		Hosts struct {
+			Up int `xml:"up,attr"`
+		} `xml:"hosts"`
+	} `xml:"runstats"`
+}
+
+type nmapHost struct {
+	Status struct {
+		State string `xml:"state,attr"`
+	} `xml:"status"`
+	Addresses []nmapAddress  `xml:"address"`
+	Hostnames []nmapHostname `xml:"hostnames>hostname"`
+	Ports     []nmapPort     `xml:"ports>port"`
+	OSMatches []nmapOSMatch  `xml:"os>osmatch"`
+}
+
+type nmapAddress struct {
+	Addr     string `xml:"addr,attr"`
+	AddrType string `xml:"addrtype,attr"`
+	Vendor   string `xml:"vendor,attr"`
+}
+
+type nmapHostname struct {
+	Name string `xml:"name,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type nmapPort struct {
+	Protocol string `xml:"protocol,attr"`
+	PortID   int    `xml:"portid,attr"`
+	State    struct {
+		State string `xml:"state,attr"`
+	} `xml:"state"`
+	Service struct {
+		Name      string `xml:"name,attr"`
+		Product   string `xml:"product,attr"`
+		Version   string `xml:"version,attr"`
+		ExtraInfo string `xml:"extrainfo,attr"`
+		Tunnel    string `xml:"tunnel,attr"`
+	} `xml:"service"`
+}
+
+type nmapOSMatch struct {
+	Name     string `xml:"name,attr"`
+	Accuracy int    `xml:"accuracy,attr"`
+}
+
+// maxOSGuesses limits how many of nmap's OS matches are kept per device
+const maxOSGuesses = 3
+
+// decodeNmapRun decodes an nmap -oX document
+func decodeNmapRun(output []byte) (*nmapRun, error) {
+	// Output is combined with stderr, so skip any warnings ahead of the document
+	if start := bytes.Index(output, []byte("<?xml")); start > 0 {
+		output = output[start:]
+	}
+
+	var run nmapRun
+	if err := xml.NewDecoder(bytes.NewReader(output)).Decode(&run); err != nil {
+		return nil, fmt.Errorf("invalid nmap XML output: %v", err)
+	}
+	return &run, nil
+}
+
+// nmapHostsUp returns how many hosts nmap's run statistics count as up. With
+// --open, hosts that are up but have no open ports are counted but not listed.
+func nmapHostsUp(output []byte) int {
+	run, err := decodeNmapRun(output)
+	if err != nil {
+		return 0
+	}
+	return run.RunStats.Hosts.Up
+}
+
+// parseNmapXML decodes nmap -oX output into the hosts that were up
+func parseNmapXML(output []byte) ([]models.NetworkDevice, error) {
+	run, err := decodeNmapRun(output)
+	if err != nil {
+		return nil, err
+	}
+
+	var devices []models.NetworkDevice
+	for _, host := range run.Hosts {
+		if host.Status.State != "" && host.Status.State != "up" {
+			continue
+		}
+
+		device := models.NetworkDevice{
+			State: "up",
+		}
+
+		for _, addr := range host.Addresses {
+			switch addr.AddrType {
+			case "ipv4", "ipv6":
+				device.IP = addr.Addr
+			case "mac":
+				device.MAC = strings.ToUpper(addr.Addr)
+				device.Vendor = addr.Vendor
+			}
+		}
+		if device.IP == "" {
+			continue
+		}
+
+		// Prefer a user-supplied name over a reverse lookup
+		for _, hostname := range host.Hostnames {
+			if device.Name == "" || hostname.Type == "user" {
+				device.Name = hostname.Name
+			}
+		}
+
+		for _, port := range host.Ports {
+			device.Ports = append(device.Ports, models.Port{
+				Number:   port.PortID,
+				Protocol: port.Protocol,
+				Service:  port.Service.Name,
+				Tunnel:   port.Service.Tunnel,
+				State:    port.State.State,
+				Product:  port.Service.Product,
+				Version:  strings.TrimSpace(port.Service.Version + " " + port.Service.ExtraInfo),
+			})
+		}
+
+		for i, match := range host.OSMatches {
+			if i >= maxOSGuesses {
+				break
+			}
+			device.OSGuesses = append(device.OSGuesses, fmt.Sprintf("%s (%d%%)", match.Name, match.Accuracy))
+		}
+
+		devices = append(devices, device)
+	}
+
+	return devices, nil
+}
@@ -0,0 +1,94 @@
+package scanners
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+)
+
+// readNmapFixture returns an nmap -oX document from testdata/nmap
+func readNmapFixture(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile("testdata/nmap/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestParseNmapXML(t *testing.T) {
+	// The fixture starts with a warning nmap printed to stderr
+	devices, err := parseNmapXML([]byte(readNmapFixture(t, "host-services.xml")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(devices) != 1 {
+		t.Fatalf("got %d devices, want 1", len(devices))
+	}
+
+	device := devices[0]
+	if device.IP != "192.168.1.20" || device.MAC != "B8:27:EB:12:34:56" ||
+		device.Vendor != "Raspberry Pi Foundation" || device.Name != "sensor-pi" || device.State != "up" {
+		t.Errorf("unexpected device identity: %+v", device)
+	}
+
+	wantPorts := []models.Port{
+		{Number: 22, Protocol: "tcp", Service: "ssh", State: "open", Product: "OpenSSH", Version: "7.9p1 protocol 2.0"},
+		{Number: 443, Protocol: "tcp", Service: "http", Tunnel: "ssl", State: "open", Product: "nginx", Version: "1.14.2"},
+		{Number: 8443, Protocol: "tcp", Service: "https-alt", State: "open"},
+	}
+	if !reflect.DeepEqual(device.Ports, wantPorts) {
+		t.Errorf("got ports %+v, want %+v", device.Ports, wantPorts)
+	}
+
+	// Only the best matches are kept
+	wantOS := []string{"Linux 4.15 - 5.8 (96%)", "Linux 5.0 - 5.4 (95%)", "Linux 2.6.32 (94%)"}
+	if !reflect.DeepEqual(device.OSGuesses, wantOS) {
+		t.Errorf("got OS guesses %q, want %q", device.OSGuesses, wantOS)
+	}
+}
+
+func TestScanDevicePortsHostStates(t *testing.T) {
+	config := models.DefaultConfig()
+	config.NmapServiceVersions = false
+	config.NmapOSDetection = false
+
+	tests := []struct {
+		fixture string
+		ip      string
+		wantErr bool
+		ports   int
+	}{
+		{"host-services.xml", "192.168.1.20", false, 3},
+		// With --open a host that is up with nothing open is counted but not listed
+		{"host-no-open.xml", "192.168.1.30", false, 0},
+		{"host-down.xml", "192.168.1.40", true, 0},
+	}
+	for _, tt := range tests {
+		runner := &sequenceRunner{outputs: map[string][]string{
+			"nmap -p 22,443 --open -oX - " + tt.ip: {readNmapFixture(t, tt.fixture)},
+		}}
+		ns, err := NewNetworkScanner(config, nil, runner, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		device, err := ns.scanDevicePorts(context.Background(), tt.ip, []int{22, 443})
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: host that is down returned %+v", tt.fixture, device)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.fixture, err)
+			continue
+		}
+		if device.IP != tt.ip || device.State != "up" || len(device.Ports) != tt.ports {
+			t.Errorf("%s: got %+v, want %s up with %d ports", tt.fixture, device, tt.ip, tt.ports)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package scanners
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+)
+
+// portPolicy is a configured models.PortPolicy with its lists parsed
+type portPolicy struct {
+	name      string
+	subnets   []*net.IPNet
+	classes   map[string]bool
+	allowed   map[int]bool
+	forbidden map[int]portRule
+	maxOpen   int
+	severity  models.Severity
+}
+
+// portRule is the label and severity given to one forbidden port
+type portRule struct {
+	service  string
+	severity models.Severity
+}
+
+// compilePortPolicies parses the configured port policies, keeping their order
+func compilePortPolicies(policies []models.PortPolicy) ([]portPolicy, error) {
+	var compiled []portPolicy
+
+	for i, policy := range policies {
+		name := policy.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i+1)
+		}
+
+		p := portPolicy{
+			name:      name,
+			classes:   make(map[string]bool),
+			allowed:   make(map[int]bool),
+			forbidden: make(map[int]portRule),
+			maxOpen:   policy.MaxOpenPorts,
+			severity:  models.SeverityMedium,
+		}
+
+		if policy.Severity != "" {
+			severity, err := models.ParseSeverity(policy.Severity)
+			if err != nil {
+				return nil, fmt.Errorf("port policy %s: %v", name, err)
+			}
+			p.severity = severity
+		}
+
+		for _, entry := range policy.Subnets {
+			entry = strings.TrimSpace(entry)
+			if entry == "" {
+				continue
+			}
+			subnet, err := parseNetwork(entry)
+			if err != nil {
+				return nil, fmt.Errorf("port policy %s: invalid subnet %q: %v", name, entry, err)
+			}
+			p.subnets = append(p.subnets, subnet)
+		}
+
+		for _, class := range policy.DeviceClasses {
+			p.classes[strings.ToLower(class)] = true
+		}
+
+		allowed, err := ParsePortList(policy.AllowedPorts)
+		if err != nil {
+			return nil, fmt.Errorf("port policy %s: allowed ports: %v", name, err)
+		}
+		for _, port := range allowed {
+			p.allowed[port] = true
+		}
+
+		for _, rule := range policy.ForbiddenPorts {
+			ports, err := ParsePortList([]string{rule.Ports})
+			if err != nil {
+				return nil, fmt.Errorf("port policy %s: forbidden ports: %v", name, err)
+			}
+			severity := p.severity
+			if rule.Severity != "" {
+				if severity, err = models.ParseSeverity(rule.Severity); err != nil {
+					return nil, fmt.Errorf("port policy %s: %v", name, err)
+				}
+			}
+			for _, port := range ports {
+				p.forbidden[port] = portRule{service: rule.Service, severity: severity}
+			}
+		}
+
+		compiled = append(compiled, p)
+	}
+
+	return compiled, nil
+}
+
+// matches reports whether the policy covers the device
+func (p *portPolicy) matches(device models.NetworkDevice) bool {
+	if len(p.subnets) > 0 && !isExcluded(device.IP, p.subnets) {
+		return false
+	}
+	if len(p.classes) > 0 && !p.classes[device.Class] {
+		return false
+	}
+	return true
+}
+
+// policyForDevice returns the first policy covering the device, or nil
+func policyForDevice(policies []portPolicy, device models.NetworkDevice) *portPolicy {
+	for i := range policies {
+		if policies[i].matches(device) {
+			return &policies[i]
+		}
+	}
+	return nil
+}
+
+// evaluate checks a device's open ports against the policy
+func (p *portPolicy) evaluate(device models.NetworkDevice) []models.Attack {
+	var attacks []models.Attack
+	label := deviceLabel(device.Vendor, device.Class, device.RandomizedMAC)
+
+	openPorts := 0
+	for _, port := range device.Ports {
+		if port.State != "open" {
+			continue
+		}
+		openPorts++
+
+		if rule, forbidden := p.forbidden[port.Number]; forbidden {
+			service := rule.service
+			if service == "" {
+				service = port.Service
+			}
+			attacks = append(attacks, models.Attack{
+				Type:        "SUSPICIOUS_PORT",
+				Severity:    rule.severity,
+				Description: fmt.Sprintf("Suspicious open port detected: %s:%d (%s)%s, forbidden by policy %s", device.IP, port.Number, service, label, p.name),
+				Target:      device.IP,
+				Timestamp:   time.Now(),
+			})
+			continue
+		}
+
+		if len(p.allowed) > 0 && !p.allowed[port.Number] {
+			attacks = append(attacks, models.Attack{
+				Type:        "UNEXPECTED_PORT",
+				Severity:    p.severity,
+				Description: fmt.Sprintf("Open port %s:%d (%s)%s is not allowed by policy %s", device.IP, port.Number, port.Service, label, p.name),
+				Target:      device.IP,
+				Timestamp:   time.Now(),
+			})
+		}
+	}
+
+	if p.maxOpen > 0 && openPorts > p.maxOpen {
+		attacks = append(attacks, models.Attack{
+			Type:        "EXCESSIVE_OPEN_PORTS",
+			Severity:    p.severity,
+			Description: fmt.Sprintf("%d open ports on %s%s, more than the %d allowed by policy %s", openPorts, device.IP, label, p.maxOpen, p.name),
+			Target:      device.IP,
+			Timestamp:   time.Now(),
+		})
+	}
+
+	return attacks
+}
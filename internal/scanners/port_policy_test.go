@@ -0,0 +1,31 @@
+package scanners
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+)
+
+func TestCompilePortPoliciesErrors(t *testing.T) {
+	tests := []struct {
+		policy models.PortPolicy
+		want   string
+	}{
+		{models.PortPolicy{Name: "cameras", Subnets: []string{"10.0.0.0/33"}}, `port policy cameras: invalid subnet "10.0.0.0/33"`},
+		{models.PortPolicy{Subnets: []string{"printers"}}, `port policy #1: invalid subnet "printers"`},
+		{models.PortPolicy{Name: "iot", AllowedPorts: []string{"70000"}}, "port policy iot: allowed ports:"},
+		{models.PortPolicy{Name: "iot", ForbiddenPorts: []models.PortRule{{Ports: "x"}}}, "port policy iot: forbidden ports:"},
+	}
+	for _, tt := range tests {
+		_, err := compilePortPolicies([]models.PortPolicy{tt.policy})
+		if err == nil || !strings.HasPrefix(err.Error(), tt.want) || strings.Contains(err.Error(), "scan exclusion") {
+			t.Errorf("got %v, want an error starting with %s", err, tt.want)
+		}
+	}
+
+	policies, err := compilePortPolicies([]models.PortPolicy{{Name: "lan", Subnets: []string{"10.0.0.0/24", "10.0.1.5", " "}}})
+	if err != nil || len(policies[0].subnets) != 2 {
+		t.Errorf("valid subnets rejected: %v", err)
+	}
+}
@@ -0,0 +1,216 @@
+package scanners
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+)
+
+// wellKnownServices names the services commonly found on scanned ports
+var wellKnownServices = map[int]string{
+	21:   "ftp",
+	22:   "ssh",
+	23:   "telnet",
+	25:   "smtp",
+	53:   "domain",
+	80:   "http",
+	110:  "pop3",
+	139:  "netbios-ssn",
+	143:  "imap",
+	443:  "https",
+	445:  "microsoft-ds",
+	993:  "imaps",
+	995:  "pop3s",
+	1883: "mqtt",
+	3306: "mysql",
+	3389: "ms-wbt-server",
+	5432: "postgresql",
+	5900: "vnc",
+	8080: "http-proxy",
+	8443: "https-alt",
+}
+
+// ParsePortList expands port entries such as "22", "80,443" or "8000-8100" into a
+// sorted list of unique ports
+func ParsePortList(entries []string) ([]int, error) {
+	seen := make(map[int]bool)
+	var ports []int
+
+	for _, entry := range entries {
+		for _, part := range strings.Split(entry, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+
+			low, high := part, part
+			if dash := strings.Index(part, "-"); dash >= 0 {
+				low, high = part[:dash], part[dash+1:]
+			}
+
+			first, err := strconv.Atoi(strings.TrimSpace(low))
+			if err != nil {
+				return nil, fmt.Errorf("invalid port %q", part)
+			}
+			last, err := strconv.Atoi(strings.TrimSpace(high))
+			if err != nil {
+				return nil, fmt.Errorf("invalid port %q", part)
+			}
+			if first < 1 || last > 65535 || first > last {
+				return nil, fmt.Errorf("invalid port range %q", part)
+			}
+
+			for port := first; port <= last; port++ {
+				if !seen[port] {
+					seen[port] = true
+					ports = append(ports, port)
+				}
+			}
+		}
+	}
+
+	sort.Ints(ports)
+	return ports, nil
+}
+
+// formatPortList renders ports in the comma-separated form nmap's -p expects
+func formatPortList(ports []int) string {
+	parts := make([]string, len(ports))
+	for i, port := range ports {
+		parts[i] = strconv.Itoa(port)
+	}
+	return strings.Join(parts, ",")
+}
+
+// rateLimiter paces events to at most a fixed number per second.
+// A nil limiter never waits.
+type rateLimiter struct {
+	ticker *time.Ticker
+}
+
+// newRateLimiter returns a limiter for perSecond events, or nil when unlimited
+func newRateLimiter(perSecond int) *rateLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	// Rates above one event per nanosecond cannot be paced any faster
+	interval := time.Second / time.Duration(perSecond)
+	if interval <= 0 {
+		interval = time.Nanosecond
+	}
+	return &rateLimiter{ticker: time.NewTicker(interval)}
+}
+
+// Wait blocks until the next event is allowed or ctx is done
+func (l *rateLimiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-l.ticker.C:
+		return nil
+	}
+}
+
+// Stop releases the limiter's ticker
+func (l *rateLimiter) Stop() {
+	if l != nil {
+		l.ticker.Stop()
+	}
+}
+
+// portProbe is one host and port pair for the connect scanner
+type portProbe struct {
+	host string
+	port int
+}
+
+// connectScan finds the open TCP ports of every host with plain connects.
+// A bounded pool of workers shares a global rate limit, and each host has its own
+// limit so that no single device is hammered. Probes are ordered port by port
+// across hosts so the per-host limits rarely stall the pool.
+func connectScan(ctx context.Context, hosts []string, ports []int, cfg *models.AttackDetectorConfig) map[string][]models.Port {
+	results := make(map[string][]models.Port)
+	if len(hosts) == 0 || len(ports) == 0 {
+		return results
+	}
+
+	workers := cfg.PortScanWorkers
+	if workers <= 0 {
+		workers = 1
+	}
+
+	globalLimit := newRateLimiter(cfg.PortScanRate)
+	defer globalLimit.Stop()
+
+	hostLimits := make(map[string]*rateLimiter, len(hosts))
+	for _, host := range hosts {
+		hostLimits[host] = newRateLimiter(cfg.PortScanHostRate)
+	}
+	defer func() {
+		for _, limit := range hostLimits {
+			limit.Stop()
+		}
+	}()
+
+	dialer := net.Dialer{Timeout: cfg.PortScanTimeout}
+	jobs := make(chan portProbe)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for probe := range jobs {
+				if hostLimits[probe.host].Wait(ctx) != nil || globalLimit.Wait(ctx) != nil {
+					continue
+				}
+
+				conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(probe.host, strconv.Itoa(probe.port)))
+				if err != nil {
+					continue
+				}
+				conn.Close()
+
+				mu.Lock()
+				results[probe.host] = append(results[probe.host], models.Port{
+					Number:   probe.port,
+					Protocol: "tcp",
+					Service:  wellKnownServices[probe.port],
+					State:    "open",
+				})
+				mu.Unlock()
+			}
+		}()
+	}
+
+feed:
+	for _, port := range ports {
+		for _, host := range hosts {
+			select {
+			case <-ctx.Done():
+				break feed
+			case jobs <- portProbe{host: host, port: port}:
+			}
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	for host := range results {
+		sort.Slice(results[host], func(i, j int) bool {
+			return results[host][i].Number < results[host][j].Number
+		})
+	}
+	return results
+}
@@ -0,0 +1,17 @@
+package scanners
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestRateLimiterAboveOnePerNanosecond(t *testing.T) {
+	for _, rate := range []int{1e9 + 1, math.MaxInt} {
+		limiter := newRateLimiter(rate)
+		if err := limiter.Wait(context.Background()); err != nil {
+			t.Errorf("rate %d: %v", rate, err)
+		}
+		limiter.Stop()
+	}
+}
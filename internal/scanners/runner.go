@@ -0,0 +1,315 @@
+package scanners
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Command runner modes selectable from configuration
+const (
+	CommandModeLive   = "live"
+	CommandModeRecord = "record"
+	CommandModeReplay = "replay"
+)
+
+// CommandRunner executes the external tools the scanners depend on
+type CommandRunner interface {
+	// LookPath reports where the named tool is installed
+	LookPath(file string) (string, error)
+
+	// Output runs a command to completion and returns its combined stdout and stderr
+	Output(ctx context.Context, name string, args ...string) ([]byte, error)
+
+	// Stream starts a long-running command and returns its stdout.
+	// Closing the stream stops the command.
+	Stream(ctx context.Context, name string, args ...string) (io.ReadCloser, error)
+}
+
+// NewCommandRunner builds the runner for the given mode.
+// Record and replay modes keep their fixtures in dir.
+func NewCommandRunner(mode, dir string) (CommandRunner, error) {
+	switch mode {
+	case "", CommandModeLive:
+		return NewExecRunner(), nil
+	case CommandModeRecord:
+		return NewRecordingRunner(NewExecRunner(), dir)
+	case CommandModeReplay:
+		return NewReplayRunner(dir)
+	default:
+		return nil, fmt.Errorf("unknown command mode: %s", mode)
+	}
+}
+
+// isCommandAvailable reports whether the runner can execute the named tool
+func isCommandAvailable(runner CommandRunner, cmd string) bool {
+	_, err := runner.LookPath(cmd)
+	return err == nil
+}
+
+// ExecRunner runs commands on the host with os/exec
+type ExecRunner struct{}
+
+// NewExecRunner creates a runner that executes real commands
+func NewExecRunner() *ExecRunner {
+	return &ExecRunner{}
+}
+
+// LookPath searches PATH for the named tool
+func (r *ExecRunner) LookPath(file string) (string, error) {
+	return exec.LookPath(file)
+}
+
+// Output runs the command and returns its combined output
+func (r *ExecRunner) Output(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return exec.CommandContext(ctx, name, args...).CombinedOutput()
+}
+
+// Stream starts the command and returns its stdout
+func (r *ExecRunner) Stream(ctx context.Context, name string, args ...string) (io.ReadCloser, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	return &execStream{ReadCloser: stdout, cmd: cmd}, nil
+}
+
+// execStream stops the underlying process when closed
+type execStream struct {
+	io.ReadCloser
+	cmd *exec.Cmd
+}
+
+func (s *execStream) Close() error {
+	if s.cmd.Process != nil {
+		_ = s.cmd.Process.Kill()
+	}
+	s.ReadCloser.Close()
+	_ = s.cmd.Wait()
+	return nil
+}
+
+// CommandFixture is the on-disk form of one recorded command invocation
+type CommandFixture struct {
+	Command string   `json:"command"`
+	Args    []string `json:"args"`
+	Output  string   `json:"output"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// fixtureKey identifies all recordings of the same command line
+func fixtureKey(name string, args []string) string {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	for _, arg := range args {
+		h.Write([]byte{0})
+		h.Write([]byte(arg))
+	}
+	return fmt.Sprintf("%s-%08x", filepath.Base(name), h.Sum32())
+}
+
+// fixturePath returns the file holding the n-th recording of a command line
+func fixturePath(dir, key string, n int) string {
+	return filepath.Join(dir, fmt.Sprintf("%s.%03d.json", key, n))
+}
+
+// RecordingRunner wraps another runner and saves every command's output as a fixture
+type RecordingRunner struct {
+	runner CommandRunner
+	dir    string
+	mu     sync.Mutex
+	seq    map[string]int
+}
+
+// NewRecordingRunner creates a runner that records runner's output into dir
+func NewRecordingRunner(runner CommandRunner, dir string) (*RecordingRunner, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("record mode requires a fixtures directory")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &RecordingRunner{
+		runner: runner,
+		dir:    dir,
+		seq:    make(map[string]int),
+	}, nil
+}
+
+// LookPath delegates to the wrapped runner
+func (r *RecordingRunner) LookPath(file string) (string, error) {
+	return r.runner.LookPath(file)
+}
+
+// Output runs the command and records its output
+func (r *RecordingRunner) Output(ctx context.Context, name string, args ...string) ([]byte, error) {
+	output, err := r.runner.Output(ctx, name, args...)
+	if saveErr := r.save(name, args, output, err); saveErr != nil {
+		return output, fmt.Errorf("failed to record %s: %v", name, saveErr)
+	}
+	return output, err
+}
+
+// Stream starts the command and records everything read from it once closed
+func (r *RecordingRunner) Stream(ctx context.Context, name string, args ...string) (io.ReadCloser, error) {
+	stream, err := r.runner.Stream(ctx, name, args...)
+	if err != nil {
+		_ = r.save(name, args, nil, err)
+		return nil, err
+	}
+	return &recordingStream{stream: stream, recorder: r, name: name, args: args}, nil
+}
+
+func (r *RecordingRunner) save(name string, args []string, output []byte, runErr error) error {
+	key := fixtureKey(name, args)
+
+	r.mu.Lock()
+	r.seq[key]++
+	n := r.seq[key]
+	r.mu.Unlock()
+
+	fixture := CommandFixture{
+		Command: name,
+		Args:    args,
+		Output:  string(output),
+	}
+	if runErr != nil {
+		fixture.Error = runErr.Error()
+	}
+
+	data, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fixturePath(r.dir, key, n), data, 0644)
+}
+
+// recordingStream tees a live stream and saves it as a fixture on close
+type recordingStream struct {
+	stream   io.ReadCloser
+	recorder *RecordingRunner
+	name     string
+	args     []string
+	buf      bytes.Buffer
+}
+
+func (s *recordingStream) Read(p []byte) (int, error) {
+	n, err := s.stream.Read(p)
+	s.buf.Write(p[:n])
+	return n, err
+}
+
+func (s *recordingStream) Close() error {
+	err := s.stream.Close()
+	if saveErr := s.recorder.save(s.name, s.args, s.buf.Bytes(), nil); saveErr != nil {
+		return saveErr
+	}
+	return err
+}
+
+// ReplayRunner answers commands from previously recorded fixtures.
+// Repeated invocations of a command replay its recordings in order and
+// keep returning the last one once they run out.
+type ReplayRunner struct {
+	dir string
+	mu  sync.Mutex
+	seq map[string]int
+}
+
+// NewReplayRunner creates a runner that replays fixtures from dir
+func NewReplayRunner(dir string) (*ReplayRunner, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("fixtures directory unavailable: %v", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("fixtures path %s is not a directory", dir)
+	}
+	return &ReplayRunner{
+		dir: dir,
+		seq: make(map[string]int),
+	}, nil
+}
+
+// LookPath reports a tool as installed when any recording of it exists
+func (r *ReplayRunner) LookPath(file string) (string, error) {
+	matches, _ := filepath.Glob(filepath.Join(r.dir, filepath.Base(file)+"-*.json"))
+	if len(matches) == 0 {
+		return "", &exec.Error{Name: file, Err: exec.ErrNotFound}
+	}
+	return file, nil
+}
+
+// Output returns the next recorded output for the command line
+func (r *ReplayRunner) Output(ctx context.Context, name string, args ...string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	fixture, err := r.next(name, args)
+	if err != nil {
+		return nil, err
+	}
+
+	output := []byte(fixture.Output)
+	if fixture.Error != "" {
+		return output, errors.New(fixture.Error)
+	}
+	return output, nil
+}
+
+// Stream returns the next recorded output for the command line as a stream
+func (r *ReplayRunner) Stream(ctx context.Context, name string, args ...string) (io.ReadCloser, error) {
+	output, err := r.Output(ctx, name, args...)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(output)), nil
+}
+
+func (r *ReplayRunner) next(name string, args []string) (*CommandFixture, error) {
+	key := fixtureKey(name, args)
+
+	r.mu.Lock()
+	r.seq[key]++
+	n := r.seq[key]
+	r.mu.Unlock()
+
+	path := fixturePath(r.dir, key, n)
+	for n > 1 {
+		if _, err := os.Stat(path); err == nil {
+			break
+		}
+		n--
+		path = fixturePath(r.dir, key, n)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("no recorded fixture for: %s %s", name, strings.Join(args, " "))
+		}
+		return nil, err
+	}
+
+	var fixture CommandFixture
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		return nil, fmt.Errorf("invalid fixture %s: %v", path, err)
+	}
+	return &fixture, nil
+}
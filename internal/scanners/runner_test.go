@@ -0,0 +1,165 @@
+package scanners
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+)
+
+// sequenceRunner answers each command line with its outputs in turn
+type sequenceRunner struct {
+	outputs map[string][]string
+}
+
+func (r *sequenceRunner) LookPath(file string) (string, error) {
+	return "/usr/bin/" + file, nil
+}
+
+func (r *sequenceRunner) Output(ctx context.Context, name string, args ...string) ([]byte, error) {
+	key := strings.Join(append([]string{name}, args...), " ")
+	outputs := r.outputs[key]
+	if len(outputs) == 0 {
+		return nil, fmt.Errorf("unexpected command: %s", key)
+	}
+	r.outputs[key] = outputs[1:]
+	return []byte(outputs[0]), nil
+}
+
+func (r *sequenceRunner) Stream(ctx context.Context, name string, args ...string) (io.ReadCloser, error) {
+	output, err := r.Output(ctx, name, args...)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(strings.NewReader(string(output))), nil
+}
+
+func TestReplayWiFiScanFromFixtures(t *testing.T) {
+	runner, err := NewReplayRunner("testdata/replay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ws, err := NewWiFiScanner(models.DefaultConfig(), runner)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// wlan1 is busy in the recording, so only wlan0's results come back
+	devices, err := ws.ScanWiFiNetworks(context.Background())
+	if err != nil {
+		t.Fatalf("ScanWiFiNetworks: %v", err)
+	}
+	if len(devices) != 2 {
+		t.Fatalf("got %d devices, want 2: %+v", len(devices), devices)
+	}
+
+	corp, cafe := devices[0], devices[1]
+	if corp.Address != "AA:BB:CC:00:00:01" || corp.SSID != "CorpNet" || corp.Security != "WPA2" ||
+		corp.Signal != -48 || corp.Channel != "6" || corp.Band != wifiBand24GHz {
+		t.Errorf("unexpected first network: %+v", corp)
+	}
+	if cafe.SSID != "Free Cafe WiFi" || cafe.Security != wifiSecurityOpen || cafe.Channel != "36" {
+		t.Errorf("unexpected second network: %+v", cafe)
+	}
+
+	attacks := ws.DetectWiFiAttacks(context.Background(), devices)
+	var open bool
+	for _, attack := range attacks {
+		open = open || (attack.Type == "OPEN_NETWORK" && strings.Contains(attack.Description, cafe.Address))
+	}
+	if !open {
+		t.Errorf("replayed open network not reported: %+v", attacks)
+	}
+}
+
+func TestReplayRunnerTools(t *testing.T) {
+	runner, err := NewReplayRunner("testdata/replay")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := runner.LookPath("iw"); err != nil {
+		t.Errorf("recorded tool iw not found: %v", err)
+	}
+	if _, err := runner.LookPath("nmcli"); err == nil {
+		t.Error("unrecorded tool nmcli reported as installed")
+	}
+
+	output, err := runner.Output(context.Background(), "iw", "dev", "wlan1", "scan")
+	if err == nil || err.Error() != "exit status 240" {
+		t.Errorf("recorded error not replayed, got %v", err)
+	}
+	if !strings.Contains(string(output), "Device or resource busy") {
+		t.Errorf("output of failed command not replayed: %q", output)
+	}
+
+	if _, err := runner.Output(context.Background(), "iw", "dev", "wlan9", "scan"); err == nil {
+		t.Error("command without fixture succeeded")
+	}
+}
+
+func TestRecordThenReplay(t *testing.T) {
+	dir := t.TempDir()
+	live := &sequenceRunner{outputs: map[string][]string{
+		"nmap -sn 10.0.0.0/24": {"first", "second"},
+		"bluetoothctl devices": {"Device 00:11:22:33:44:55 Speaker"},
+	}}
+
+	recorder, err := NewRecordingRunner(live, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if _, err := recorder.Output(ctx, "nmap", "-sn", "10.0.0.0/24"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stream, err := recorder.Stream(ctx, "bluetoothctl", "devices")
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.ReadAll(stream)
+	stream.Close()
+
+	replay, err := NewReplayRunner(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Recordings replay in order and the last one repeats once they run out
+	for _, want := range []string{"first", "second", "second"} {
+		output, err := replay.Output(ctx, "nmap", "-sn", "10.0.0.0/24")
+		if err != nil || string(output) != want {
+			t.Errorf("got %q, %v; want %q", output, err, want)
+		}
+	}
+
+	replayed, err := replay.Stream(ctx, "bluetoothctl", "devices")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(replayed)
+	if string(data) != "Device 00:11:22:33:44:55 Speaker" {
+		t.Errorf("stream replayed %q", data)
+	}
+
+	if _, err := replay.Output(ctx, "nmap", "-sn", "10.0.1.0/24"); err == nil {
+		t.Error("different command line replayed another command's fixture")
+	}
+}
+
+func TestNewCommandRunnerModes(t *testing.T) {
+	if _, err := NewCommandRunner(CommandModeRecord, ""); err == nil {
+		t.Error("record mode without fixtures directory accepted")
+	}
+	if _, err := NewCommandRunner(CommandModeReplay, "testdata/missing"); err == nil {
+		t.Error("replay mode with missing fixtures directory accepted")
+	}
+	if _, err := NewCommandRunner("bogus", ""); err == nil {
+		t.Error("unknown mode accepted")
+	}
+}
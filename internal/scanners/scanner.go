@@ -0,0 +1,140 @@
+package scanners
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+)
+
+// Scanner is a single source of devices and attacks driven by the detector
+type Scanner interface {
+	// Name returns the unique name the scanner is registered under
+	Name() string
+
+	// Available reports whether the tools or hardware the scanner needs are present
+	Available() bool
+
+	// Scan performs one scan pass and returns the discovered devices and detected attacks.
+	// A scanner whose pass succeeded while a background source failed returns its
+	// results together with the error; the detector reports the error and keeps them.
+	Scan(ctx context.Context) ([]interface{}, []models.Attack, error)
+}
+
+// Options carries everything a scanner factory may need to build its scanner
+type Options struct {
+	Config                *models.AttackDetectorConfig
+	Runner                CommandRunner
+	Vendors               *VendorDB
+	KnownDevices          []string
+	KnownBluetoothDevices []models.BluetoothDevice
+}
+
+// Factory builds a scanner from the shared options
+type Factory func(opts Options) (Scanner, error)
+
+type registration struct {
+	name    string
+	order   int
+	factory Factory
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]registration)
+)
+
+// Register makes a scanner factory available to the detector under the given name.
+// Scanners run in registration order. Register panics if the name is already taken,
+// so in-house sensors should call it from an init function.
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if factory == nil {
+		panic("scanners: Register factory is nil")
+	}
+	if _, exists := registry[name]; exists {
+		panic("scanners: Register called twice for scanner " + name)
+	}
+
+	registry[name] = registration{
+		name:    name,
+		order:   len(registry),
+		factory: factory,
+	}
+}
+
+// Registered returns the names of all registered scanners in registration order
+func Registered() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	regs := make([]registration, 0, len(registry))
+	for _, reg := range registry {
+		regs = append(regs, reg)
+	}
+	sort.Slice(regs, func(i, j int) bool { return regs[i].order < regs[j].order })
+
+	names := make([]string, len(regs))
+	for i, reg := range regs {
+		names[i] = reg.name
+	}
+	return names
+}
+
+// Build constructs every registered scanner in registration order
+func Build(opts Options) ([]Scanner, error) {
+	var built []Scanner
+
+	for _, name := range Registered() {
+		registryMu.RLock()
+		reg := registry[name]
+		registryMu.RUnlock()
+
+		scanner, err := reg.factory(opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build %s scanner: %v", name, err)
+		}
+		built = append(built, scanner)
+	}
+
+	return built, nil
+}
+
+// Built-in scanners are registered here rather than in each file's init so the
+// network scan always runs first, matching the original detection flow.
+func init() {
+	Register("network", func(opts Options) (Scanner, error) {
+		return NewNetworkScanner(opts.Config, opts.KnownDevices, opts.Runner, opts.Vendors)
+	})
+	Register("dhcp", func(opts Options) (Scanner, error) {
+		return NewDHCPScanner(opts.Config, opts.Vendors)
+	})
+	Register("poisoning", func(opts Options) (Scanner, error) {
+		return NewNamePoisoningScanner(opts.Config, opts.Vendors), nil
+	})
+	Register("inbound", func(opts Options) (Scanner, error) {
+		return NewInboundScanDetector(opts.Config, opts.Vendors)
+	})
+	Register("honeypot", func(opts Options) (Scanner, error) {
+		return NewHoneypotScanner(opts.Config, opts.Vendors), nil
+	})
+	Register("bluetooth", func(opts Options) (Scanner, error) {
+		return NewBluetoothScanner(opts.KnownBluetoothDevices, opts.Runner, opts.Vendors), nil
+	})
+	Register("wifi", func(opts Options) (Scanner, error) {
+		return NewWiFiScanner(opts.Config, opts.Runner)
+	})
+}
+
+// toInterfaces converts a typed device slice for use in Scan results
+func toInterfaces[T any](devices []T) []interface{} {
+	out := make([]interface{}, len(devices))
+	for i, device := range devices {
+		out[i] = device
+	}
+	return out
+}
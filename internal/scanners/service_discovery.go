@@ -0,0 +1,664 @@
+package scanners
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+)
+
+// Announcement protocols the passive listener joins, and the records it reads
+const (
+	ssdpGroup          = "239.255.255.250"
+	ssdpPort           = 1900
+	nbdsPort           = 138
+	dnsTypePTR         = 12
+	dnsTypeTXT         = 16
+	dnsTypeSRV         = 33
+	upnpFetchTimeout   = 3 * time.Second
+	upnpMaxDescription = 1 << 20
+
+	// Bounds on what announcements can make the listener remember
+	announcedMaxHosts     = 4096
+	announcedMaxLocations = 8
+)
+
+// upnpPortMappingServices are the IGD services that let any host open ports on a router
+var upnpPortMappingServices = []string{":service:WANIPConnection:", ":service:WANPPPConnection:"}
+
+// mdnsModelKeys are the TXT record keys that carry a device model, most specific first
+var mdnsModelKeys = []string{"usb_mdl", "md", "model", "ty", "am", "product"}
+
+// announcedHost is what one host has said about itself in announcements
+type announcedHost struct {
+	mdnsName    string
+	netbiosName string
+	model       string
+	services    map[string]bool
+	upnp        *models.UPnPDevice
+	locations   map[string]bool
+	lastHeard   time.Time
+}
+
+// name returns the most specific name the host announced
+func (h *announcedHost) name() string {
+	switch {
+	case h.mdnsName != "":
+		return h.mdnsName
+	case h.netbiosName != "":
+		return h.netbiosName
+	case h.upnp != nil:
+		return h.upnp.FriendlyName
+	}
+	return ""
+}
+
+// serviceWatch collects the mDNS, SSDP and NetBIOS announcements heard on the network
+type serviceWatch struct {
+	mu        sync.Mutex
+	listening bool
+	ctx       context.Context
+	errs      []string
+	hosts     map[string]*announcedHost
+	client    *http.Client
+}
+
+func newServiceWatch() *serviceWatch {
+	return &serviceWatch{
+		hosts: make(map[string]*announcedHost),
+		client: &http.Client{
+			Timeout: upnpFetchTimeout,
+			// A description must come from the announcing host, so redirects elsewhere are not followed
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// host returns the announcements of ip, creating them on first use. When the
+// table is full the host heard from least recently is forgotten. Callers hold mu.
+func (w *serviceWatch) host(ip string) *announcedHost {
+	host, ok := w.hosts[ip]
+	if !ok {
+		if len(w.hosts) >= announcedMaxHosts {
+			var oldest string
+			for address, known := range w.hosts {
+				if oldest == "" || known.lastHeard.Before(w.hosts[oldest].lastHeard) {
+					oldest = address
+				}
+			}
+			delete(w.hosts, oldest)
+		}
+		host = &announcedHost{services: make(map[string]bool), locations: make(map[string]bool)}
+		w.hosts[ip] = host
+	}
+	host.lastHeard = time.Now()
+	return host
+}
+
+// listenerError notes a listener that failed. Callers hold mu.
+func (w *serviceWatch) listenerError(format string, args ...interface{}) {
+	w.errs = append(w.errs, fmt.Sprintf(format, args...))
+}
+
+// listenerErrors returns the listener failures since the last call
+func (w *serviceWatch) listenerErrors() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	errs := w.errs
+	w.errs = nil
+	return errs
+}
+
+// recordMDNS notes the host name, services and model in an mDNS response from source
+func (w *serviceWatch) recordMDNS(packet []byte, source string) {
+	records, ok := parseDNSRecords(packet)
+	if !ok || len(records) == 0 {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	host := w.host(source)
+	for _, record := range records {
+		switch record.Type {
+		case dnsTypeA:
+			if len(record.Data) == 4 && net.IP(record.Data).String() == source {
+				host.mdnsName = strings.TrimSuffix(record.Name, ".local")
+			}
+		case dnsTypePTR:
+			if service := mdnsServiceType(record.Name); service != "" && service != "_dns-sd._udp" {
+				host.services[service] = true
+			}
+			if service := mdnsServiceType(record.Target); service != "" {
+				host.services[service] = true
+			}
+		case dnsTypeSRV:
+			if service := mdnsServiceType(record.Name); service != "" {
+				host.services[service] = true
+			}
+			if host.mdnsName == "" && record.Target != "" {
+				host.mdnsName = strings.TrimSuffix(record.Target, ".local")
+			}
+		case dnsTypeTXT:
+			if model := txtModel(record.Data); model != "" {
+				host.model = model
+			}
+		}
+	}
+}
+
+// recordSSDP notes an SSDP announcement from source and fetches the device
+// description it points to the first time it is seen
+func (w *serviceWatch) recordSSDP(packet []byte, source string) {
+	headers, ok := parseSSDPMessage(packet)
+	if !ok || headers["NTS"] == "ssdp:byebye" {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	host := w.host(source)
+	for _, key := range []string{"NT", "ST"} {
+		if strings.HasPrefix(headers[key], "urn:") {
+			host.services[headers[key]] = true
+		}
+	}
+
+	location := headers["LOCATION"]
+	if location != "" && !host.locations[location] && len(host.locations) < announcedMaxLocations {
+		host.locations[location] = true
+		go w.fetchDescription(w.ctx, location, source)
+	}
+}
+
+// recordNetBIOSName notes the name a host registers by broadcast on port 137
+func (w *serviceWatch) recordNetBIOSName(packet []byte, source string) {
+	name, ok := parseNetBIOSRegistration(packet)
+	if !ok {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.host(source).netbiosName = name
+}
+
+// recordNetBIOSDatagram notes the source name of a NetBIOS datagram on port 138
+func (w *serviceWatch) recordNetBIOSDatagram(packet []byte, source string) {
+	name, ip, ok := parseNetBIOSDatagram(packet)
+	if !ok {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.host(ip).netbiosName = name
+}
+
+// fetchDescription downloads a UPnP device description. Only descriptions served
+// by the announcing host itself are fetched.
+func (w *serviceWatch) fetchDescription(ctx context.Context, location, source string) {
+	u, err := url.Parse(location)
+	if err != nil || u.Scheme != "http" || u.Hostname() != source {
+		return
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return
+	}
+
+	description, err := parseUPnPDescription(io.LimitReader(resp.Body, upnpMaxDescription))
+	if err != nil {
+		return
+	}
+	description.Location = location
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	host := w.host(source)
+	host.upnp = description
+	for _, service := range description.Services {
+		host.services[service] = true
+	}
+}
+
+// enrich copies what a device announced about itself onto it
+func (w *serviceWatch) enrich(device *models.NetworkDevice) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	host, ok := w.hosts[device.IP]
+	if !ok {
+		return
+	}
+
+	if device.Name == "" {
+		device.Name = host.name()
+	}
+	switch {
+	case host.model != "":
+		device.Model = host.model
+	case host.upnp != nil:
+		device.Model = strings.TrimSpace(strings.Join([]string{host.upnp.Manufacturer, host.upnp.ModelName, host.upnp.ModelNumber}, " "))
+	}
+	device.UPnP = host.upnp
+
+	device.Services = device.Services[:0]
+	for service := range host.services {
+		device.Services = append(device.Services, service)
+	}
+	sort.Strings(device.Services)
+}
+
+// startServiceListeners joins the mDNS and SSDP groups on the discovery interfaces
+// and listens for NetBIOS broadcasts, once, until Close. Listeners that cannot be
+// opened, for example because another daemon holds the NetBIOS ports, are skipped
+// and their errors kept for Scan.
+func (ns *NetworkScanner) startServiceListeners() {
+	ns.services.mu.Lock()
+	defer ns.services.mu.Unlock()
+
+	if ns.services.listening {
+		return
+	}
+	ns.services.listening = true
+	ctx := ns.listenCtx
+	ns.services.ctx = ctx
+
+	interfaces := []*net.Interface{nil}
+	if len(ns.config.DiscoveryInterfaces) > 0 {
+		interfaces = nil
+		for _, name := range ns.config.DiscoveryInterfaces {
+			ifi, err := net.InterfaceByName(name)
+			if err != nil {
+				ns.services.listenerError("service discovery: %v", err)
+				continue
+			}
+			interfaces = append(interfaces, ifi)
+		}
+	}
+
+	listen := func(protocol string, conn net.PacketConn, err error, handle func([]byte, string)) {
+		if err != nil {
+			ns.services.listenerError("%s listener not started: %v", protocol, err)
+			return
+		}
+		go func() {
+			err := readAnnouncements(ctx, conn, handle)
+			if ctx.Err() != nil {
+				return
+			}
+			ns.services.mu.Lock()
+			ns.services.listenerError("%s listener stopped: %v", protocol, err)
+			ns.services.mu.Unlock()
+		}()
+	}
+
+	for _, ifi := range interfaces {
+		conn, err := net.ListenMulticastUDP("udp4", ifi, &net.UDPAddr{IP: net.ParseIP(mdnsGroup), Port: mdnsPort})
+		listen("mDNS", conn, err, ns.services.recordMDNS)
+		conn, err = net.ListenMulticastUDP("udp4", ifi, &net.UDPAddr{IP: net.ParseIP(ssdpGroup), Port: ssdpPort})
+		listen("SSDP", conn, err, ns.services.recordSSDP)
+	}
+
+	conn, err := net.ListenPacket("udp4", fmt.Sprintf(":%d", nbnsPort))
+	listen("NetBIOS name", conn, err, ns.services.recordNetBIOSName)
+	conn, err = net.ListenPacket("udp4", fmt.Sprintf(":%d", nbdsPort))
+	listen("NetBIOS datagram", conn, err, ns.services.recordNetBIOSDatagram)
+}
+
+// readAnnouncements passes every packet read from conn to handle with its source
+// IP until ctx is done
+func readAnnouncements(ctx context.Context, conn net.PacketConn, handle func([]byte, string)) error {
+	defer conn.Close()
+
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	buf := make([]byte, 9000)
+	for {
+		n, from, err := conn.ReadFrom(buf)
+		if err != nil {
+			return err
+		}
+		if udpAddr, ok := from.(*net.UDPAddr); ok {
+			handle(buf[:n], udpAddr.IP.String())
+		}
+	}
+}
+
+// DetectUPnPPortMapping raises UPNP_PORT_MAPPING for devices offering an IGD port
+// mapping service that are not allowed to. Without a configured allow-list only
+// the default gateways may offer it.
+func (ns *NetworkScanner) DetectUPnPPortMapping(devices []models.NetworkDevice) []models.Attack {
+	var attacks []models.Attack
+
+	allowed := make(map[string]bool)
+	for _, entry := range ns.config.UPnPPortMappingAllowed {
+		allowed[strings.ToUpper(entry)] = true
+	}
+	if len(allowed) == 0 {
+		routes, _ := os.ReadFile(routeTablePath)
+		for _, gateway := range parseDefaultGateways(string(routes)) {
+			allowed[gateway] = true
+		}
+	}
+
+	for _, device := range devices {
+		if allowed[device.IP] || (device.MAC != "" && allowed[strings.ToUpper(device.MAC)]) {
+			continue
+		}
+		for _, service := range device.Services {
+			if !isPortMappingService(service) {
+				continue
+			}
+			attacks = append(attacks, models.Attack{
+				Type:        "UPNP_PORT_MAPPING",
+				Severity:    models.SeverityMedium,
+				Description: fmt.Sprintf("Unexpected UPnP port mapping service %s on %s%s", service, device.IP, deviceLabel(device.Vendor, device.Class, device.RandomizedMAC)),
+				Target:      device.IP,
+				Timestamp:   time.Now(),
+			})
+			break
+		}
+	}
+
+	return attacks
+}
+
+// isPortMappingService reports whether a UPnP service type lets hosts map ports
+func isPortMappingService(service string) bool {
+	for _, mapping := range upnpPortMappingServices {
+		if strings.Contains(service, mapping) {
+			return true
+		}
+	}
+	return false
+}
+
+// dnsRecord is one resource record of a DNS message, with the decompressed
+// target name of PTR and SRV records
+type dnsRecord struct {
+	Name   string
+	Type   uint16
+	Data   []byte
+	Target string
+}
+
+// parseDNSRecords returns every answer, authority and additional record of a DNS
+// response. It reports false for queries and truncated headers.
+func parseDNSRecords(packet []byte) ([]dnsRecord, bool) {
+	if len(packet) < dnsHeaderLen || packet[2]&0x80 == 0 {
+		return nil, false
+	}
+	questions := int(binary.BigEndian.Uint16(packet[4:6]))
+	total := int(binary.BigEndian.Uint16(packet[6:8])) +
+		int(binary.BigEndian.Uint16(packet[8:10])) +
+		int(binary.BigEndian.Uint16(packet[10:12]))
+
+	offset := dnsHeaderLen
+	for i := 0; i < questions; i++ {
+		var ok bool
+		if offset, ok = skipDNSName(packet, offset); !ok || offset+4 > len(packet) {
+			return nil, true
+		}
+		offset += 4
+	}
+
+	var records []dnsRecord
+	for i := 0; i < total; i++ {
+		name, next, ok := readDNSName(packet, offset)
+		if !ok || next+10 > len(packet) {
+			break
+		}
+		offset = next
+		recordType := binary.BigEndian.Uint16(packet[offset : offset+2])
+		length := int(binary.BigEndian.Uint16(packet[offset+8 : offset+10]))
+		offset += 10
+		if offset+length > len(packet) {
+			break
+		}
+
+		record := dnsRecord{Name: name, Type: recordType, Data: packet[offset : offset+length]}
+		switch recordType {
+		case dnsTypePTR:
+			record.Target, _, _ = readDNSName(packet, offset)
+		case dnsTypeSRV:
+			if length > 6 {
+				record.Target, _, _ = readDNSName(packet, offset+6)
+			}
+		}
+		records = append(records, record)
+		offset += length
+	}
+
+	return records, true
+}
+
+// readDNSName decodes the possibly compressed name at offset and returns it with
+// the offset just past it
+func readDNSName(packet []byte, offset int) (string, int, bool) {
+	var labels []string
+	next := -1
+
+	for jumps := 0; jumps < 16; {
+		if offset >= len(packet) {
+			return "", 0, false
+		}
+		length := int(packet[offset])
+		switch {
+		case length == 0:
+			if next < 0 {
+				next = offset + 1
+			}
+			return strings.Join(labels, "."), next, true
+		case length&0xc0 == 0xc0:
+			if offset+2 > len(packet) {
+				return "", 0, false
+			}
+			if next < 0 {
+				next = offset + 2
+			}
+			offset = int(binary.BigEndian.Uint16(packet[offset:offset+2]) & 0x3fff)
+			jumps++
+		default:
+			if offset+1+length > len(packet) {
+				return "", 0, false
+			}
+			labels = append(labels, string(packet[offset+1:offset+1+length]))
+			offset += 1 + length
+		}
+	}
+
+	return "", 0, false
+}
+
+// mdnsServiceType extracts the DNS-SD service type, like "_ipp._tcp", from a name
+func mdnsServiceType(name string) string {
+	labels := strings.Split(name, ".")
+	for i := 1; i < len(labels); i++ {
+		if (labels[i] == "_tcp" || labels[i] == "_udp") && strings.HasPrefix(labels[i-1], "_") {
+			return labels[i-1] + "." + labels[i]
+		}
+	}
+	return ""
+}
+
+// txtModel returns the device model from the key=value strings of a TXT record
+func txtModel(data []byte) string {
+	values := make(map[string]string)
+	for len(data) > 0 {
+		length := int(data[0])
+		if 1+length > len(data) {
+			break
+		}
+		if key, value, found := strings.Cut(string(data[1:1+length]), "="); found {
+			values[strings.ToLower(key)] = value
+		}
+		data = data[1+length:]
+	}
+
+	for _, key := range mdnsModelKeys {
+		if value := strings.Trim(values[key], "() "); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// parseSSDPMessage returns the headers of an SSDP NOTIFY or search response,
+// with header names upper-cased
+func parseSSDPMessage(packet []byte) (map[string]string, bool) {
+	scanner := bufio.NewScanner(strings.NewReader(string(packet)))
+	if !scanner.Scan() {
+		return nil, false
+	}
+	startLine := strings.TrimSpace(scanner.Text())
+	if !strings.HasPrefix(startLine, "NOTIFY ") && !strings.HasPrefix(startLine, "HTTP/1.1 200") {
+		return nil, false
+	}
+
+	headers := make(map[string]string)
+	for scanner.Scan() {
+		key, value, found := strings.Cut(scanner.Text(), ":")
+		if !found {
+			continue
+		}
+		headers[strings.ToUpper(strings.TrimSpace(key))] = strings.TrimSpace(value)
+	}
+	return headers, true
+}
+
+// upnpDeviceXML is a device element of a UPnP device description
+type upnpDeviceXML struct {
+	DeviceType   string `xml:"deviceType"`
+	FriendlyName string `xml:"friendlyName"`
+	Manufacturer string `xml:"manufacturer"`
+	ModelName    string `xml:"modelName"`
+	ModelNumber  string `xml:"modelNumber"`
+	Services     []struct {
+		ServiceType string `xml:"serviceType"`
+	} `xml:"serviceList>service"`
+	Devices []upnpDeviceXML `xml:"deviceList>device"`
+}
+
+// parseUPnPDescription decodes a device description, collecting the services of
+// the root device and every embedded device
+func parseUPnPDescription(r io.Reader) (*models.UPnPDevice, error) {
+	var root struct {
+		Device upnpDeviceXML `xml:"device"`
+	}
+	if err := xml.NewDecoder(r).Decode(&root); err != nil {
+		return nil, fmt.Errorf("failed to parse UPnP description: %v", err)
+	}
+
+	description := &models.UPnPDevice{
+		DeviceType:   strings.TrimSpace(root.Device.DeviceType),
+		FriendlyName: strings.TrimSpace(root.Device.FriendlyName),
+		Manufacturer: strings.TrimSpace(root.Device.Manufacturer),
+		ModelName:    strings.TrimSpace(root.Device.ModelName),
+		ModelNumber:  strings.TrimSpace(root.Device.ModelNumber),
+	}
+
+	var collect func(device upnpDeviceXML)
+	collect = func(device upnpDeviceXML) {
+		for _, service := range device.Services {
+			if serviceType := strings.TrimSpace(service.ServiceType); serviceType != "" {
+				description.Services = append(description.Services, serviceType)
+			}
+		}
+		for _, embedded := range device.Devices {
+			collect(embedded)
+		}
+	}
+	collect(root.Device)
+
+	return description, nil
+}
+
+// parseNetBIOSRegistration returns the unique name in a broadcast NetBIOS name
+// registration or refresh. Group names such as workgroups are skipped.
+func parseNetBIOSRegistration(packet []byte) (string, bool) {
+	if len(packet) < dnsHeaderLen+38 {
+		return "", false
+	}
+	flags := binary.BigEndian.Uint16(packet[2:4])
+	opcode := flags >> 11 & 0x0f
+	if flags&0x8000 != 0 || (opcode != 5 && opcode != 8 && opcode != 9) ||
+		binary.BigEndian.Uint16(packet[4:6]) != 1 || packet[dnsHeaderLen] != 32 {
+		return "", false
+	}
+
+	name, suffix := decodeNetBIOSName(packet[dnsHeaderLen+1 : dnsHeaderLen+33])
+	if name == "" || (suffix != 0x00 && suffix != 0x20) {
+		return "", false
+	}
+
+	// The additional record carries the NB flags, whose top bit marks group names
+	offset, ok := skipDNSName(packet, dnsHeaderLen+38)
+	if !ok || offset+12 > len(packet) {
+		return "", false
+	}
+	if binary.BigEndian.Uint16(packet[offset+10:offset+12])&0x8000 != 0 {
+		return "", false
+	}
+
+	return name, true
+}
+
+// parseNetBIOSDatagram returns the source name and address of a NetBIOS datagram
+func parseNetBIOSDatagram(packet []byte) (string, string, bool) {
+	if len(packet) < 14+34 || packet[0] < 0x10 || packet[0] > 0x12 || packet[14] != 32 {
+		return "", "", false
+	}
+
+	name, suffix := decodeNetBIOSName(packet[15:47])
+	if name == "" || (suffix != 0x00 && suffix != 0x20) {
+		return "", "", false
+	}
+	return name, net.IP(packet[4:8]).String(), true
+}
+
+// decodeNetBIOSName reverses the RFC 1001 first-level encoding of a 32-byte name
+func decodeNetBIOSName(encoded []byte) (string, byte) {
+	if len(encoded) != 32 {
+		return "", 0
+	}
+
+	decoded := make([]byte, 16)
+	for i := range decoded {
+		high, low := encoded[2*i]-'A', encoded[2*i+1]-'A'
+		if high > 0x0f || low > 0x0f {
+			return "", 0
+		}
+		decoded[i] = high<<4 | low
+	}
+	return strings.TrimRight(string(decoded[:nbnsNameLen]), " "), decoded[nbnsNameLen]
+}
@@ -0,0 +1,205 @@
+package scanners
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// ScanTargetAuto derives scan targets from the host's local interfaces
+const ScanTargetAuto = "auto"
+
+// maxAutoPrefixIPv4 limits automatically derived IPv4 ranges so a host on a
+// large network does not sweep tens of thousands of addresses every scan
+const maxAutoPrefixIPv4 = 24
+
+// autoPrefixIPv6 is the on-link prefix length assumed for IPv6 interface addresses
+const autoPrefixIPv6 = 64
+
+// ScanTarget is one address range the network scanner sweeps
+type ScanTarget struct {
+	Network   *net.IPNet
+	Interface string
+}
+
+// String returns the range in CIDR notation
+func (t ScanTarget) String() string {
+	return t.Network.String()
+}
+
+// IsIPv6 reports whether the range is an IPv6 network
+func (t ScanTarget) IsIPv6() bool {
+	return t.Network.IP.To4() == nil
+}
+
+// ResolveTargets expands the configured targets into concrete ranges.
+// Each entry is a CIDR, a single IP or "auto" for the local interface subnets;
+// includeIPv6 controls whether "auto" also yields the IPv6 on-link prefixes.
+func ResolveTargets(targets []string, includeIPv6 bool) ([]ScanTarget, error) {
+	var resolved []ScanTarget
+	seen := make(map[string]bool)
+
+	add := func(target ScanTarget) {
+		// Link-local prefixes repeat on every interface, so key on both
+		key := target.String() + "%" + target.Interface
+		if !seen[key] {
+			seen[key] = true
+			resolved = append(resolved, target)
+		}
+	}
+
+	for _, entry := range targets {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if strings.EqualFold(entry, ScanTargetAuto) {
+			autoTargets, err := localInterfaceTargets(includeIPv6)
+			if err != nil {
+				return nil, err
+			}
+			for _, target := range autoTargets {
+				add(target)
+			}
+			continue
+		}
+
+		network, err := parseNetwork(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid scan target %q: %v", entry, err)
+		}
+		add(ScanTarget{
+			Network:   network,
+			Interface: interfaceForNetwork(network),
+		})
+	}
+
+	return resolved, nil
+}
+
+// ParseExclusions parses excluded IPs and CIDRs
+func ParseExclusions(exclusions []string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, entry := range exclusions {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		network, err := parseNetwork(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid scan exclusion %q: %v", entry, err)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// isExcluded reports whether ip falls in any excluded network
+func isExcluded(ip string, exclusions []*net.IPNet) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, network := range exclusions {
+		if network.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseNetwork accepts either CIDR notation or a bare IP address
+func parseNetwork(entry string) (*net.IPNet, error) {
+	if strings.Contains(entry, "/") {
+		_, network, err := net.ParseCIDR(entry)
+		return network, err
+	}
+
+	ip := net.ParseIP(entry)
+	if ip == nil {
+		return nil, fmt.Errorf("not an IP address or CIDR")
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}, nil
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+}
+
+// localInterfaceTargets returns the subnets of every up, non-loopback interface
+func localInterfaceTargets(includeIPv6 bool) ([]ScanTarget, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list interfaces: %v", err)
+	}
+
+	var targets []ScanTarget
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if !ok {
+				continue
+			}
+			ip4 := ipNet.IP.To4()
+			if ip4 == nil {
+				if includeIPv6 {
+					mask := net.CIDRMask(autoPrefixIPv6, 128)
+					targets = append(targets, ScanTarget{
+						Network:   &net.IPNet{IP: ipNet.IP.Mask(mask), Mask: mask},
+						Interface: iface.Name,
+					})
+				}
+				continue
+			}
+			if ip4.IsLinkLocalUnicast() {
+				continue
+			}
+
+			ones, _ := ipNet.Mask.Size()
+			if ones < maxAutoPrefixIPv4 {
+				ones = maxAutoPrefixIPv4
+			}
+			mask := net.CIDRMask(ones, 32)
+			targets = append(targets, ScanTarget{
+				Network:   &net.IPNet{IP: ip4.Mask(mask), Mask: mask},
+				Interface: iface.Name,
+			})
+		}
+	}
+
+	return targets, nil
+}
+
+// interfaceForNetwork returns the local interface attached to network, or ""
+func interfaceForNetwork(network *net.IPNet) string {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return ""
+	}
+
+	for _, iface := range ifaces {
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if !ok {
+				continue
+			}
+			if network.Contains(ipNet.IP) || ipNet.Contains(network.IP) {
+				return iface.Name
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,147 @@
+package scanners
+
+import (
+	"bufio"
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// builtinOUIs is the vendor list compiled into the binary
+//
+//go:embed data/oui.txt
+var builtinOUIs string
+
+// VendorDB maps MAC address prefixes (OUIs) to vendor names
+type VendorDB struct {
+	vendors map[string]string
+}
+
+// NewVendorDB loads the built-in vendor list and then, if path is set, the entries
+// of that file on top of it. The file may be the IEEE oui.txt or oui.csv registry,
+// a Wireshark manuf file or plain "prefix vendor" lines.
+func NewVendorDB(path string) (*VendorDB, error) {
+	db := &VendorDB{vendors: make(map[string]string)}
+	db.load(strings.NewReader(builtinOUIs))
+
+	if path == "" {
+		return db, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open OUI database: %v", err)
+	}
+	defer file.Close()
+
+	db.load(file)
+	return db, nil
+}
+
+// load adds every recognizable entry from r, replacing existing ones
+func (db *VendorDB) load(r io.Reader) {
+	// Inside an IEEE oui.txt record, until the blank line that ends it
+	var ieeeRecord bool
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			ieeeRecord = false
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var prefix, vendor string
+		switch {
+		case strings.HasPrefix(line, "MA-L,"):
+			// IEEE oui.csv: "MA-L,00000C,Cisco Systems, Inc,<address>"
+			fields, err := csv.NewReader(strings.NewReader(line)).Read()
+			if err != nil || len(fields) < 3 {
+				continue
+			}
+			prefix, vendor = fields[1], fields[2]
+		case strings.Contains(line, "(hex)"), strings.Contains(line, "(base 16)"):
+			// IEEE oui.txt: "00-00-0C   (hex)		Cisco Systems, Inc", then
+			// "00000C     (base 16)		Cisco Systems, Inc" and the postal address
+			before, after, found := strings.Cut(line, "(hex)")
+			if !found {
+				before, after, _ = strings.Cut(line, "(base 16)")
+			}
+			prefix, vendor = before, after
+			ieeeRecord = true
+		case ieeeRecord:
+			continue
+		default:
+			// Wireshark manuf or plain: "00:00:0C<TAB>Cisco[<TAB>Cisco Systems, Inc]"
+			fields := strings.Fields(line)
+			if len(fields) < 2 {
+				continue
+			}
+			prefix = fields[0]
+			vendor = strings.TrimSpace(strings.SplitN(strings.TrimSpace(line[len(fields[0]):]), "\t", 2)[0])
+		}
+
+		// Longer MA-M and MA-S assignments are not tracked
+		if strings.Contains(prefix, "/") && !strings.HasSuffix(prefix, "/24") {
+			continue
+		}
+		key := normalizeOUI(strings.TrimSuffix(prefix, "/24"))
+		vendor = strings.TrimSpace(vendor)
+		if key != "" && vendor != "" {
+			db.vendors[key] = vendor
+		}
+	}
+}
+
+// Lookup returns the vendor registered for a MAC address, or ""
+func (db *VendorDB) Lookup(mac string) string {
+	if db == nil {
+		return ""
+	}
+	return db.vendors[normalizeOUI(mac)]
+}
+
+// Len returns the number of known prefixes
+func (db *VendorDB) Len() int {
+	return len(db.vendors)
+}
+
+// normalizeOUI reduces a MAC address or prefix to its first three octets as
+// six upper-case hex digits, or "" if it has fewer
+func normalizeOUI(mac string) string {
+	var digits []byte
+	for i := 0; i < len(mac) && len(digits) < 6; i++ {
+		c := mac[i]
+		switch {
+		case c >= '0' && c <= '9', c >= 'A' && c <= 'F':
+			digits = append(digits, c)
+		case c >= 'a' && c <= 'f':
+			digits = append(digits, c-'a'+'A')
+		case c == ':' || c == '-' || c == '.':
+		default:
+			return ""
+		}
+	}
+	if len(digits) < 6 {
+		return ""
+	}
+	return string(digits)
+}
+
+// IsRandomizedMAC reports whether a MAC address is locally administered, as used by
+// phones and laptops that randomize their address per network
+func IsRandomizedMAC(mac string) bool {
+	oui := normalizeOUI(mac)
+	if oui == "" {
+		return false
+	}
+	var first byte
+	fmt.Sscanf(oui[:2], "%02X", &first)
+	return first&0x02 != 0
+}
@@ -0,0 +1,148 @@
+package scanners
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+)
+
+func TestVendorDBFormats(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		lookup map[string]string
+	}{
+		{
+			name: "IEEE oui.txt",
+			data: "OUI/MA-L                                                    Organization                                 \r\n" +
+				"company_id                                                  Organization                                 \r\n" +
+				"                                                            Address                                      \r\n" +
+				"\r\n" +
+				"00-00-0C   (hex)\t\tCisco Systems, Inc\r\n" +
+				"00000C     (base 16)\t\tCisco Systems, Inc\r\n" +
+				"\t\t\t\t170 West Tasman Dr.\r\n" +
+				"\t\t\t\tSan Jose  CA  95134\r\n" +
+				"\t\t\t\tUS\r\n" +
+				"\r\n" +
+				"AC-DE-48   (hex)\t\tPrivate\r\n" +
+				"ACDE48     (base 16)\t\tPrivate\r\n" +
+				"\t\t\t\tBEEFED 12\r\n" +
+				"\r\n",
+			lookup: map[string]string{
+				"00:00:0c:12:34:56": "Cisco Systems, Inc",
+				"AC:DE:48:00:00:01": "Private",
+				"BE:EF:ED:00:00:00": "",
+			},
+		},
+		{
+			name: "IEEE oui.txt base 16 lines only",
+			data: "F4F5D8     (base 16)\t\tGoogle, Inc.\n\t\t\t\t1600 Amphitheatre Parkway\n",
+			lookup: map[string]string{
+				"F4-F5-D8-01-02-03": "Google, Inc.",
+			},
+		},
+		{
+			name: "IEEE oui.csv",
+			data: "Registry,Assignment,Organization Name,Organization Address\n" +
+				"MA-L,002272,American Micro-Fuel Device Corp.,2181 Buchanan Loop Ferndale WA US 98248 \n" +
+				"MA-L,00D0EF,\"IGT, Inc\",9295 PROTOTYPE DRIVE RENO NV US 89511 \n",
+			lookup: map[string]string{
+				"00:22:72:aa:bb:cc": "American Micro-Fuel Device Corp.",
+				"00:D0:EF:00:00:00": "IGT, Inc",
+			},
+		},
+		{
+			name: "Wireshark manuf",
+			data: "# This file was generated by running ./tools/make-manuf.py.\n" +
+				"00:00:0C\tCisco\tCisco Systems, Inc\n" +
+				"00:1B:C5:00:00:00/36\tConverg\tConverging Systems Inc.\n" +
+				"00:50:C2/24\tIeeeRegi\tIEEE Registration Authority\n",
+			lookup: map[string]string{
+				"00:00:0C:01:02:03": "Cisco",
+				"00:1B:C5:00:00:01": "",
+				"00:50:C2:12:34:56": "IeeeRegi",
+			},
+		},
+		{
+			name: "plain prefix and vendor",
+			data: "B8:27:EB Raspberry Pi Foundation\n" +
+				"0017f2\tApple\n",
+			lookup: map[string]string{
+				"b8:27:eb:11:22:33": "Raspberry Pi Foundation",
+				"00:17:F2:00:00:00": "Apple",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &VendorDB{vendors: make(map[string]string)}
+			db.load(strings.NewReader(tt.data))
+			for mac, want := range tt.lookup {
+				if got := db.Lookup(mac); got != want {
+					t.Errorf("Lookup(%s) = %q, want %q", mac, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestNewVendorDBOverridesBuiltin(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "oui.txt")
+	data := "00-00-0C   (hex)\t\tCisco Systems, Inc\n00000C     (base 16)\t\tCisco Systems, Inc\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	builtin, err := NewVendorDB("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := NewVendorDB(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := builtin.Lookup("00:00:0C:00:00:01"); got != "Cisco" {
+		t.Errorf("built-in vendor = %q, want Cisco", got)
+	}
+	if got := db.Lookup("00:00:0C:00:00:01"); got != "Cisco Systems, Inc" {
+		t.Errorf("file vendor = %q, want Cisco Systems, Inc", got)
+	}
+	if db.Len() != builtin.Len() {
+		t.Errorf("file entries added %d prefixes, want 0", db.Len()-builtin.Len())
+	}
+	if _, err := NewVendorDB(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("missing OUI file accepted")
+	}
+}
+
+func TestClassifyDeviceKeywords(t *testing.T) {
+	tests := []struct {
+		vendor, name string
+		want         string
+	}{
+		{"", "Johns-iPhone", models.DeviceClassPhone},
+		{"", "printer2", models.DeviceClassPrinter},
+		{"", "HP LaserJet Pro M404", models.DeviceClassPrinter},
+		{"Hikvision", "", models.DeviceClassCamera},
+		{"TP-LINK TECHNOLOGIES CO.,LTD.", "", models.DeviceClassRouter},
+		{"", "Kitchen Echo", models.DeviceClassIoT},
+		{"Google, Inc.", "nest-thermostat", models.DeviceClassIoT},
+
+		// Substrings of other words and vendors that make many kinds of devices
+		{"", "honest-abe", ""},
+		{"", "echoserver", ""},
+		{"Apple, Inc.", "MacBook-Pro", ""},
+		{"Samsung Electronics Co.,Ltd", "", ""},
+		{"Huawei Technologies", "", ""},
+	}
+
+	for _, tt := range tests {
+		if got := ClassifyDevice(tt.vendor, tt.name, nil, false); got != tt.want {
+			t.Errorf("ClassifyDevice(%q, %q) = %q, want %q", tt.vendor, tt.name, got, tt.want)
+		}
+	}
+}
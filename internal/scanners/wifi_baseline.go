@@ -0,0 +1,178 @@
+package scanners
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+)
+
+// DetectEvilTwins compares access points against the authorized network baseline.
+// An access point serving an authorized SSID is reported when its BSSID, channel or
+// security differs from what the baseline allows. SSIDs outside the baseline are only
+// reported when their access points disagree on security, as an open or downgraded
+// clone of a protected network does.
+func DetectEvilTwins(devices []models.WiFiDevice, networks []models.AuthorizedNetwork) []models.Attack {
+	var attacks []models.Attack
+
+	baseline := make(map[string]models.AuthorizedNetwork)
+	for _, network := range networks {
+		baseline[network.SSID] = network
+	}
+
+	unlisted := make(map[string][]models.WiFiDevice)
+	for _, device := range devices {
+		if device.SSID == "" {
+			continue
+		}
+		network, ok := baseline[device.SSID]
+		if !ok {
+			unlisted[device.SSID] = append(unlisted[device.SSID], device)
+			continue
+		}
+		if attack, ok := checkAuthorizedNetwork(device, network); ok {
+			attacks = append(attacks, attack)
+		}
+	}
+
+	ssids := make([]string, 0, len(unlisted))
+	for ssid := range unlisted {
+		ssids = append(ssids, ssid)
+	}
+	sort.Strings(ssids)
+	for _, ssid := range ssids {
+		attacks = append(attacks, detectMixedSecurity(ssid, unlisted[ssid])...)
+	}
+
+	return attacks
+}
+
+// checkAuthorizedNetwork lists how an access point deviates from its baseline entry
+func checkAuthorizedNetwork(device models.WiFiDevice, network models.AuthorizedNetwork) (models.Attack, bool) {
+	var reasons []string
+	severity := models.SeverityMedium
+
+	if len(network.BSSIDs) > 0 && !bssidAuthorized(device.Address, network.BSSIDs) {
+		reasons = append(reasons, "unknown BSSID")
+		severity = models.SeverityHigh
+	}
+	if len(network.Security) > 0 && device.Security != "" && !securityAuthorized(device.Security, network.Security) {
+		reasons = append(reasons, fmt.Sprintf("security %s instead of %s", device.Security, strings.Join(network.Security, " or ")))
+		severity = models.SeverityHigh
+	}
+	if len(network.Channels) > 0 && device.Channel != "" {
+		channel, err := strconv.Atoi(device.Channel)
+		if err == nil && !containsInt(network.Channels, channel) {
+			reasons = append(reasons, fmt.Sprintf("unexpected channel %d", channel))
+		}
+	}
+	if len(reasons) == 0 {
+		return models.Attack{}, false
+	}
+
+	return models.Attack{
+		Type:     "EVIL_TWIN",
+		Severity: severity,
+		Description: fmt.Sprintf("Potential evil twin of authorized network '%s': BSSID %s %s",
+			device.SSID, device.Address, strings.Join(reasons, ", ")),
+		Target:    device.SSID,
+		Timestamp: time.Now(),
+	}, true
+}
+
+// detectMixedSecurity reports the access points of an SSID outside the baseline that
+// advertise weaker security than the strongest one serving the same SSID
+func detectMixedSecurity(ssid string, devices []models.WiFiDevice) []models.Attack {
+	strongest := ""
+	for _, device := range devices {
+		if securityStrength(device.Security) > securityStrength(strongest) {
+			strongest = device.Security
+		}
+	}
+
+	var attacks []models.Attack
+	for _, device := range devices {
+		if device.Security == "" || securityStrength(device.Security) >= securityStrength(strongest) {
+			continue
+		}
+		attacks = append(attacks, models.Attack{
+			Type:     "EVIL_TWIN",
+			Severity: models.SeverityHigh,
+			Description: fmt.Sprintf("Potential evil twin: SSID '%s' served by BSSID %s with %s while other access points use %s",
+				ssid, device.Address, device.Security, strongest),
+			Target:    ssid,
+			Timestamp: time.Now(),
+		})
+	}
+	return attacks
+}
+
+// securityStrength orders security modes by their strongest generation, with 0 for unknown
+func securityStrength(security string) int {
+	strength := 0
+	for _, mode := range strings.Split(security, "/") {
+		rank := 0
+		switch mode {
+		case wifiSecurityOpen:
+			rank = 1
+		case wifiSecurityWEP:
+			rank = 2
+		case wifiSecurityOWE, wifiSecurityWPA:
+			rank = 3
+		case wifiSecurityWPA2:
+			rank = 4
+		case wifiSecurityWPA3:
+			rank = 5
+		}
+		if rank > strength {
+			strength = rank
+		}
+	}
+	return strength
+}
+
+// bssidAuthorized matches an address against full BSSIDs and OUI prefixes
+func bssidAuthorized(address string, allowed []string) bool {
+	address = strings.ToUpper(address)
+	for _, entry := range allowed {
+		entry = strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(entry), "-", ":"))
+		if entry != "" && strings.HasPrefix(address, entry) {
+			return true
+		}
+	}
+	return false
+}
+
+// securityAuthorized matches a security mode such as "WPA2/WPA3" against the allowed
+// modes, case-insensitively. A mode is allowed when it is listed as a whole or when
+// every generation it offers is listed, so ["WPA2", "WPA3"] allows "WPA2/WPA3" while
+// ["WPA3"] does not.
+func securityAuthorized(security string, allowed []string) bool {
+	modes := make(map[string]bool)
+	for _, mode := range allowed {
+		modes[strings.ToUpper(strings.TrimSpace(mode))] = true
+	}
+	if modes[strings.ToUpper(security)] {
+		return true
+	}
+
+	for _, generation := range strings.Split(security, "/") {
+		if !modes[strings.ToUpper(strings.TrimSpace(generation))] {
+			return false
+		}
+	}
+	return true
+}
+
+// containsInt reports whether list holds value
+func containsInt(list []int, value int) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
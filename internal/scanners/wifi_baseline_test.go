@@ -0,0 +1,25 @@
+package scanners
+
+import "testing"
+
+func TestSecurityAuthorized(t *testing.T) {
+	tests := []struct {
+		security string
+		allowed  []string
+		want     bool
+	}{
+		{"WPA2/WPA3", []string{"WPA2", "WPA3"}, true},
+		{"WPA2/WPA3", []string{"wpa2/wpa3"}, true},
+		{"WPA2", []string{"WPA2", "WPA3"}, true},
+		{"WPA2/WPA3", []string{"WPA3"}, false},
+		{"WPA/WPA2", []string{"WPA2"}, false},
+		{"WPA2", []string{"WPA2/WPA3"}, false},
+		{"Open", []string{"WPA2"}, false},
+	}
+
+	for _, tt := range tests {
+		if got := securityAuthorized(tt.security, tt.allowed); got != tt.want {
+			t.Errorf("securityAuthorized(%q, %q) = %v, want %v", tt.security, tt.allowed, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package scanners
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+	"os"
+	"time"
+)
+
+// Magic numbers opening pcap files, with micro- or nanosecond timestamps, and the
+// pcapng section header block
+const (
+	pcapMagicMicros  = 0xa1b2c3d4
+	pcapMagicNanos   = 0xa1b23c4d
+	pcapngSectionHdr = 0x0a0d0d0a
+	pcapngByteOrder  = 0x1a2b3c4d
+)
+
+// pcapng block types the reader understands
+const (
+	pcapngInterfaceDesc  = 1
+	pcapngSimplePacket   = 3
+	pcapngEnhancedPacket = 6
+)
+
+// maxCaptureBlock bounds the records read from a capture file
+const maxCaptureBlock = 16 << 20
+
+// readCaptureFile passes every 802.11 management frame in a pcap or pcapng file
+// to handle, stamped with its capture time
+func readCaptureFile(path string, handle func(wifiFrame)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	r := bufio.NewReader(file)
+	head, err := r.Peek(4)
+	if err != nil {
+		return fmt.Errorf("%s: not a capture file: %v", path, err)
+	}
+
+	switch {
+	case binary.BigEndian.Uint32(head) == pcapngSectionHdr:
+		err = readPcapng(r, handle)
+	case binary.LittleEndian.Uint32(head) == pcapMagicMicros, binary.LittleEndian.Uint32(head) == pcapMagicNanos:
+		err = readPcap(r, binary.LittleEndian, handle)
+	case binary.BigEndian.Uint32(head) == pcapMagicMicros, binary.BigEndian.Uint32(head) == pcapMagicNanos:
+		err = readPcap(r, binary.BigEndian, handle)
+	default:
+		return fmt.Errorf("%s: not a pcap or pcapng file", path)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	return nil
+}
+
+// readPcap reads a classic pcap file in the given byte order
+func readPcap(r io.Reader, order binary.ByteOrder, handle func(wifiFrame)) error {
+	header := make([]byte, 24)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return err
+	}
+	nanos := order.Uint32(header[0:4]) == pcapMagicNanos
+	linkType := order.Uint32(header[20:24]) & 0xffff
+
+	record := make([]byte, 16)
+	for {
+		if _, err := io.ReadFull(r, record); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		seconds, fraction := int64(order.Uint32(record[0:4])), int64(order.Uint32(record[4:8]))
+		length := order.Uint32(record[8:12])
+		if length > maxCaptureBlock {
+			return fmt.Errorf("record of %d bytes too large", length)
+		}
+
+		data := make([]byte, length)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return err
+		}
+
+		if !nanos {
+			fraction *= 1000
+		}
+		if frame, ok := decodeWiFiFrame(linkType, data, time.Unix(seconds, fraction)); ok {
+			handle(frame)
+		}
+	}
+}
+
+// pcapngInterface is what the reader keeps of an interface description block.
+// Timestamps tick in 10^-exponent seconds, or 2^-exponent when binary is set.
+type pcapngInterface struct {
+	linkType uint32
+	exponent int
+	binary   bool
+}
+
+// readPcapng reads a pcapng file, which may hold several sections of either byte order
+func readPcapng(r io.Reader, handle func(wifiFrame)) error {
+	var order binary.ByteOrder = binary.LittleEndian
+	var interfaces []pcapngInterface
+
+	head := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(r, head); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		// A section header announces the byte order of everything up to the next one
+		if binary.BigEndian.Uint32(head[0:4]) == pcapngSectionHdr {
+			magic := make([]byte, 4)
+			if _, err := io.ReadFull(r, magic); err != nil {
+				return err
+			}
+			switch {
+			case binary.LittleEndian.Uint32(magic) == pcapngByteOrder:
+				order = binary.LittleEndian
+			case binary.BigEndian.Uint32(magic) == pcapngByteOrder:
+				order = binary.BigEndian
+			default:
+				return fmt.Errorf("bad pcapng byte-order magic")
+			}
+			interfaces = nil
+
+			length := order.Uint32(head[4:8])
+			if length < 16 || length > maxCaptureBlock {
+				return fmt.Errorf("bad pcapng section length %d", length)
+			}
+			if _, err := io.CopyN(io.Discard, r, int64(length)-12); err != nil {
+				return err
+			}
+			continue
+		}
+
+		blockType, length := order.Uint32(head[0:4]), order.Uint32(head[4:8])
+		if length < 12 || length%4 != 0 || length > maxCaptureBlock {
+			return fmt.Errorf("bad pcapng block length %d", length)
+		}
+		body := make([]byte, length-8)
+		if _, err := io.ReadFull(r, body); err != nil {
+			return err
+		}
+		body = body[:len(body)-4]
+
+		switch blockType {
+		case pcapngInterfaceDesc:
+			if len(body) < 8 {
+				return fmt.Errorf("short pcapng interface block")
+			}
+			resolution := pcapngResolution(body[8:], order)
+			interfaces = append(interfaces, pcapngInterface{
+				linkType: uint32(order.Uint16(body[0:2])),
+				exponent: int(resolution & 0x7f),
+				binary:   resolution&0x80 != 0,
+			})
+
+		case pcapngEnhancedPacket:
+			if len(body) < 20 {
+				continue
+			}
+			id := order.Uint32(body[0:4])
+			if int(id) >= len(interfaces) {
+				continue
+			}
+			ticks := uint64(order.Uint32(body[4:8]))<<32 | uint64(order.Uint32(body[8:12]))
+			captured := order.Uint32(body[12:16])
+			if uint32(len(body)-20) < captured {
+				continue
+			}
+			ifc := interfaces[id]
+			if frame, ok := decodeWiFiFrame(ifc.linkType, body[20:20+captured], ifc.time(ticks)); ok {
+				handle(frame)
+			}
+
+		case pcapngSimplePacket:
+			// Simple packets belong to the first interface and carry no timestamp
+			if len(body) < 4 || len(interfaces) == 0 {
+				continue
+			}
+			if frame, ok := decodeWiFiFrame(interfaces[0].linkType, body[4:], time.Time{}); ok {
+				handle(frame)
+			}
+		}
+	}
+}
+
+// pcapngResolution returns the if_tsresol option of an interface block, or 6
+// (microseconds) when absent. The high bit selects a negative power of two
+// instead of ten.
+func pcapngResolution(options []byte, order binary.ByteOrder) byte {
+	for len(options) >= 4 {
+		code, length := order.Uint16(options[0:2]), int(order.Uint16(options[2:4]))
+		if code == 0 || 4+length > len(options) {
+			break
+		}
+		if code == 9 && length >= 1 {
+			return options[4]
+		}
+		options = options[4+(length+3)&^3:]
+	}
+	return 6
+}
+
+// time converts a timestamp in ticks since the epoch
+func (ifc pcapngInterface) time(ticks uint64) time.Time {
+	perSecond := uint64(1)
+	for i := 0; i < ifc.exponent && perSecond < math.MaxUint64/10; i++ {
+		if ifc.binary {
+			perSecond *= 2
+		} else {
+			perSecond *= 10
+		}
+	}
+
+	fraction := ticks % perSecond
+	hi, lo := bits.Mul64(fraction, uint64(time.Second))
+	nanos, _ := bits.Div64(hi, lo, perSecond)
+	return time.Unix(int64(ticks/perSecond), int64(nanos))
+}
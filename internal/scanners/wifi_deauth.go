@@ -0,0 +1,160 @@
+package scanners
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+)
+
+// deauthReasons names the reason codes deauthentication tools commonly send
+var deauthReasons = map[uint16]string{
+	1: "unspecified",
+	2: "previous authentication no longer valid",
+	3: "station leaving",
+	4: "inactivity",
+	6: "class 2 frame from nonauthenticated station",
+	7: "class 3 frame from nonassociated station",
+	8: "station leaving BSS",
+}
+
+// deauthFrame is one deauthentication or disassociation frame of a flow
+type deauthFrame struct {
+	at       time.Time
+	disassoc bool
+	source   string
+	reason   uint16
+}
+
+// deauthFlow is the recent teardown traffic between one access point and one
+// client, or every client when sent to broadcast
+type deauthFlow struct {
+	frames  []deauthFrame
+	alerted time.Time
+}
+
+// deauthTracker counts deauthentication and disassociation frames per BSSID and
+// client and reports flows that exceed the threshold within the window. Frame
+// times come from the capture, so saved traces replay with their original timing.
+type deauthTracker struct {
+	window    time.Duration
+	threshold int
+	flows     map[string]*deauthFlow
+}
+
+// newDeauthTracker creates a tracker for the configured window and threshold
+func newDeauthTracker(window time.Duration, threshold int) *deauthTracker {
+	return &deauthTracker{
+		window:    window,
+		threshold: threshold,
+		flows:     make(map[string]*deauthFlow),
+	}
+}
+
+// record notes one frame and returns a WIFI_DEAUTH_ATTACK when its flow crosses
+// the threshold. Each flow is reported at most once per window.
+func (t *deauthTracker) record(frame wifiFrame) []models.Attack {
+	if frame.Subtype != wifiSubtypeDeauth && frame.Subtype != wifiSubtypeDisassoc {
+		return nil
+	}
+
+	// The client is whichever end is not the access point
+	client := frame.Receiver
+	if frame.Receiver == frame.BSSID {
+		client = frame.Transmitter
+	}
+
+	if len(t.flows) > 256 {
+		t.expire(frame.Time)
+	}
+
+	key := frame.BSSID + "|" + client
+	flow, ok := t.flows[key]
+	if !ok {
+		flow = &deauthFlow{}
+		t.flows[key] = flow
+	}
+
+	flow.frames = append(flow.frames, deauthFrame{
+		at:       frame.Time,
+		disassoc: frame.Subtype == wifiSubtypeDisassoc,
+		source:   frame.Transmitter,
+		reason:   frame.reasonCode(),
+	})
+	cutoff := frame.Time.Add(-t.window)
+	first := 0
+	for first < len(flow.frames) && flow.frames[first].at.Before(cutoff) {
+		first++
+	}
+	flow.frames = flow.frames[first:]
+
+	if len(flow.frames) < t.threshold || (!flow.alerted.IsZero() && frame.Time.Sub(flow.alerted) < t.window) {
+		return nil
+	}
+	flow.alerted = frame.Time
+	return []models.Attack{t.attack(frame.BSSID, client, flow.frames)}
+}
+
+// expire drops flows that have been quiet for a whole window
+func (t *deauthTracker) expire(now time.Time) {
+	for key, flow := range t.flows {
+		if len(flow.frames) == 0 || now.Sub(flow.frames[len(flow.frames)-1].at) > t.window {
+			delete(t.flows, key)
+		}
+	}
+}
+
+// attack describes a flow that crossed the threshold
+func (t *deauthTracker) attack(bssid, client string, frames []deauthFrame) models.Attack {
+	var deauth, disassoc int
+	sources := make(map[string]bool)
+	reasons := make(map[uint16]int)
+	for _, frame := range frames {
+		if frame.disassoc {
+			disassoc++
+		} else {
+			deauth++
+		}
+		sources[frame.source] = true
+		reasons[frame.reason]++
+	}
+
+	var sourceList []string
+	for source := range sources {
+		sourceList = append(sourceList, source)
+	}
+	sort.Strings(sourceList)
+
+	var reason uint16
+	for code, count := range reasons {
+		if count > reasons[reason] || (count == reasons[reason] && code < reason) {
+			reason = code
+		}
+	}
+	reasonText := fmt.Sprintf("reason %d", reason)
+	if name, ok := deauthReasons[reason]; ok {
+		reasonText += " (" + name + ")"
+	}
+
+	target := "client " + client
+	if client == wifiBroadcast {
+		target = "all clients"
+	}
+
+	span := frames[len(frames)-1].at.Sub(frames[0].at)
+	if span < time.Second {
+		span = time.Second
+	}
+	rate := float64(len(frames)) / span.Seconds()
+
+	return models.Attack{
+		Type:     "WIFI_DEAUTH_ATTACK",
+		Severity: models.SeverityHigh,
+		Description: fmt.Sprintf("Deauthentication attack on BSSID %s against %s: %d deauth and %d disassoc frames from %s in %v (%.1f/s), %s",
+			bssid, target, deauth, disassoc, strings.Join(sourceList, ", "), span.Round(time.Second), rate, reasonText),
+		Target:    bssid,
+		Timestamp: frames[len(frames)-1].at,
+	}
+}
@@ -0,0 +1,240 @@
+package scanners
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+)
+
+// The capture fixtures hold:
+//
+// deauth-radiotap.pcap: radiotap frames on 2437 MHz. A beacon for CorpNet from
+// 00:11:22:33:44:55, twelve deauthentications from it to broadcast within 1.1s
+// carrying an FCS, one data frame, and three unicast deauthentications to
+// 66:77:88:99:AA:BB without FCS.
+//
+// deauth-burst.pcapng: nanosecond radiotap frames on 5180 MHz between access
+// point 02:00:00:00:00:01 and client 12:34:56:78:9A:BC, alternating deauths from
+// the access point and disassociations from the client. Three bursts of ten
+// frames 450ms apart start at 0s, 5s and 60s.
+const (
+	radiotapCapture = "testdata/capture/deauth-radiotap.pcap"
+	pcapngCapture   = "testdata/capture/deauth-burst.pcapng"
+)
+
+// readFixture returns every management frame in a capture fixture
+func readFixture(t *testing.T, path string) []wifiFrame {
+	t.Helper()
+	var frames []wifiFrame
+	if err := readCaptureFile(path, func(frame wifiFrame) { frames = append(frames, frame) }); err != nil {
+		t.Fatalf("readCaptureFile(%s): %v", path, err)
+	}
+	return frames
+}
+
+// replayDeauth feeds frames to a new tracker and returns the alerts raised
+func replayDeauth(frames []wifiFrame, window time.Duration, threshold int) []models.Attack {
+	tracker := newDeauthTracker(window, threshold)
+	var attacks []models.Attack
+	for _, frame := range frames {
+		attacks = append(attacks, tracker.record(frame)...)
+	}
+	return attacks
+}
+
+func TestReadRadiotapPcap(t *testing.T) {
+	frames := readFixture(t, radiotapCapture)
+	if len(frames) != 16 {
+		t.Fatalf("got %d management frames, want 16", len(frames))
+	}
+
+	beacon := frames[0]
+	if beacon.Subtype != wifiSubtypeBeacon || beacon.BSSID != "00:11:22:33:44:55" ||
+		beacon.Signal != -42 || beacon.Frequency != 2437 || !beacon.Time.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("unexpected beacon: %+v", beacon)
+	}
+	if elements, ok := beacon.elements(); !ok || elements.SSID != "CorpNet" {
+		t.Errorf("beacon SSID = %q, want CorpNet", elements.SSID)
+	}
+
+	for _, frame := range frames[1:] {
+		if frame.Subtype != wifiSubtypeDeauth {
+			t.Fatalf("unexpected subtype %d", frame.Subtype)
+		}
+		// The checksum is stripped only where radiotap flags it, leaving the reason code
+		if len(frame.Body) != 2 {
+			t.Errorf("deauth to %s has a %d-byte body, want 2", frame.Receiver, len(frame.Body))
+		}
+	}
+	if got := frames[1].reasonCode(); got != 7 {
+		t.Errorf("broadcast reason code = %d, want 7", got)
+	}
+	if got := frames[13].reasonCode(); got != 3 {
+		t.Errorf("unicast reason code = %d, want 3", got)
+	}
+	if !frames[2].Time.Equal(time.Unix(1700000001, 100*int64(time.Millisecond))) {
+		t.Errorf("microsecond timestamp read as %v", frames[2].Time)
+	}
+}
+
+func TestRadiotapFCSFlag(t *testing.T) {
+	header := []byte{0, 0, 15, 0, 0x2a, 0, 0, 0, radiotapFlagFCS, 0, 0x85, 0x09, 0xa0, 0, 0xd6}
+	deauth := append(make([]byte, 24), 7, 0)
+	deauth[0] = wifiSubtypeDeauth << 4
+	data := append(append(header, deauth...), 0xde, 0xad, 0xbe, 0xef)
+
+	frame, ok := decodeWiFiFrame(linkTypeIEEE80211Radiotap, data, time.Time{})
+	if !ok || len(frame.Body) != 2 || frame.reasonCode() != 7 || frame.Signal != -42 || frame.Frequency != 2437 {
+		t.Errorf("flagged FCS not stripped: %+v", frame)
+	}
+
+	// Without the flag the trailing bytes are part of the frame
+	data[8] = 0
+	if frame, ok := decodeWiFiFrame(linkTypeIEEE80211Radiotap, data, time.Time{}); !ok || len(frame.Body) != 6 {
+		t.Errorf("unflagged frame body is %d bytes, want 6", len(frame.Body))
+	}
+}
+
+func TestReadPcapng(t *testing.T) {
+	frames := readFixture(t, pcapngCapture)
+	if len(frames) != 30 {
+		t.Fatalf("got %d management frames, want 30", len(frames))
+	}
+
+	first, second := frames[0], frames[1]
+	if first.Subtype != wifiSubtypeDeauth || first.Receiver != "12:34:56:78:9A:BC" || first.Transmitter != "02:00:00:00:00:01" ||
+		first.Frequency != 5180 || first.Signal != -60 || len(first.Body) != 2 {
+		t.Errorf("unexpected first frame: %+v", first)
+	}
+	if second.Subtype != wifiSubtypeDisassoc || second.Receiver != "02:00:00:00:00:01" || second.Transmitter != "12:34:56:78:9A:BC" {
+		t.Errorf("unexpected second frame: %+v", second)
+	}
+	if want := time.Unix(1700000000, 450*int64(time.Millisecond)); !second.Time.Equal(want) {
+		t.Errorf("nanosecond timestamp read as %v, want %v", second.Time, want)
+	}
+}
+
+func TestDeauthThresholdFromPcap(t *testing.T) {
+	frames := readFixture(t, radiotapCapture)
+
+	tests := []struct {
+		threshold int
+		want      int
+	}{
+		{10, 1},
+		{12, 1},
+		{13, 0},
+	}
+	for _, tt := range tests {
+		attacks := replayDeauth(frames, 30*time.Second, tt.threshold)
+		if len(attacks) != tt.want {
+			t.Errorf("threshold %d: got %d alerts, want %d: %+v", tt.threshold, len(attacks), tt.want, attacks)
+		}
+	}
+
+	// The broadcast burst is reported once, against every client; the three
+	// unicast frames stay below the threshold
+	attacks := replayDeauth(frames, 30*time.Second, 3)
+	if len(attacks) != 2 {
+		t.Fatalf("got %d alerts, want 2: %+v", len(attacks), attacks)
+	}
+	broadcast, unicast := attacks[0], attacks[1]
+	if broadcast.Target != "00:11:22:33:44:55" || !strings.Contains(broadcast.Description, "against all clients") ||
+		!strings.Contains(broadcast.Description, "3 deauth") || !strings.Contains(broadcast.Description, "reason 7") {
+		t.Errorf("unexpected broadcast alert: %+v", broadcast)
+	}
+	if !strings.Contains(unicast.Description, "against client 66:77:88:99:AA:BB") || !strings.Contains(unicast.Description, "reason 3") {
+		t.Errorf("unexpected unicast alert: %+v", unicast)
+	}
+}
+
+func TestDeauthOncePerWindowFromPcapng(t *testing.T) {
+	frames := readFixture(t, pcapngCapture)
+
+	// The second burst falls within the window of the first alert; the third
+	// comes after the window and is reported again
+	attacks := replayDeauth(frames, 30*time.Second, 10)
+	if len(attacks) != 2 {
+		t.Fatalf("got %d alerts, want 2: %+v", len(attacks), attacks)
+	}
+	for i, offset := range []time.Duration{4050 * time.Millisecond, 64050 * time.Millisecond} {
+		attack := attacks[i]
+		if want := time.Unix(1700000000, 0).Add(offset); !attack.Timestamp.Equal(want) {
+			t.Errorf("alert %d at %v, want %v", i, attack.Timestamp, want)
+		}
+		// Frames from either end belong to the same access point and client
+		if attack.Target != "02:00:00:00:00:01" || !strings.Contains(attack.Description, "against client 12:34:56:78:9A:BC") ||
+			!strings.Contains(attack.Description, "5 deauth and 5 disassoc") ||
+			!strings.Contains(attack.Description, "02:00:00:00:00:01, 12:34:56:78:9A:BC") {
+			t.Errorf("unexpected alert %d: %+v", i, attack)
+		}
+	}
+
+	// A window shorter than a burst reports it again once the window has passed:
+	// at its fourth and ninth frames
+	if attacks := replayDeauth(frames, 2*time.Second, 4); len(attacks) != 6 {
+		t.Errorf("2s window: got %d alerts, want 6", len(attacks))
+	}
+}
+
+func TestWiFiScanReplaysCaptureFile(t *testing.T) {
+	config := models.DefaultConfig()
+	config.WiFiCaptureFile = radiotapCapture
+	ws, err := NewWiFiScanner(config, &sequenceRunner{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The file is read on the first pass only
+	for pass, want := range []int{1, 0} {
+		_, attacks, err := ws.Scan(context.Background())
+		if err != nil {
+			t.Fatalf("Scan: %v", err)
+		}
+		var deauth int
+		for _, attack := range attacks {
+			if attack.Type == "WIFI_DEAUTH_ATTACK" {
+				deauth++
+			}
+		}
+		if deauth != want {
+			t.Errorf("pass %d: got %d deauth alerts, want %d: %+v", pass+1, deauth, want, attacks)
+		}
+	}
+}
+
+func TestWiFiScanReturnsCaptureErrors(t *testing.T) {
+	config := models.DefaultConfig()
+	config.WiFiCaptureFile = filepath.Join(t.TempDir(), "missing.pcap")
+	ws, err := NewWiFiScanner(config, &sequenceRunner{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := ws.Scan(context.Background()); err == nil || !strings.Contains(err.Error(), "missing.pcap") {
+		t.Errorf("capture error not returned, got %v", err)
+	}
+}
+
+func TestNewWiFiScannerRejectsThresholds(t *testing.T) {
+	config := models.DefaultConfig()
+	config.DeauthThreshold = 0
+	if _, err := NewWiFiScanner(config, &sequenceRunner{}); err != nil {
+		t.Errorf("threshold rejected without a frame source: %v", err)
+	}
+
+	config.WiFiCaptureFile = radiotapCapture
+	if _, err := NewWiFiScanner(config, &sequenceRunner{}); err == nil {
+		t.Error("zero deauth threshold accepted")
+	}
+
+	config.DeauthThreshold = 10
+	config.KarmaSSIDThreshold = -1
+	if _, err := NewWiFiScanner(config, &sequenceRunner{}); err == nil {
+		t.Error("negative KARMA threshold accepted")
+	}
+}
@@ -0,0 +1,192 @@
+package scanners
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"strings"
+	"time"
+)
+
+// 802.11 management frame subtypes
+const (
+	wifiSubtypeProbeRequest  = 4
+	wifiSubtypeProbeResponse = 5
+	wifiSubtypeBeacon        = 8
+	wifiSubtypeDisassoc      = 10
+	wifiSubtypeDeauth        = 12
+)
+
+// Capture link types that carry 802.11 frames
+const (
+	linkTypeIEEE80211         = 105
+	linkTypeIEEE80211Radiotap = 127
+)
+
+// errMonitorCaptureUnsupported is returned where AF_PACKET sockets are unavailable
+var errMonitorCaptureUnsupported = errors.New("monitor-mode capture not supported on this platform")
+
+// wifiBroadcast is the receiver address of frames sent to every station
+const wifiBroadcast = "FF:FF:FF:FF:FF:FF"
+
+// Radiotap fields up to the antenna signal, the last one the decoder reads
+const (
+	radiotapFlags     = 1
+	radiotapChannel   = 3
+	radiotapAntSignal = 5
+
+	// radiotapFlagFCS marks frames that still carry their 4-byte checksum
+	radiotapFlagFCS = 0x10
+)
+
+// radiotapFieldLayout is the size and alignment of radiotap fields 0 to 5
+var radiotapFieldLayout = [...]struct{ size, align int }{
+	{8, 8}, // TSFT
+	{1, 1}, // flags
+	{1, 1}, // rate
+	{4, 2}, // channel frequency and flags
+	{2, 1}, // FHSS
+	{1, 1}, // antenna signal dBm
+}
+
+// wifiFrame is a captured 802.11 management frame reduced to what the WiFi
+// detectors need
+type wifiFrame struct {
+	Time    time.Time
+	Subtype int
+
+	// Addresses 1 to 3 of the management header
+	Receiver    string
+	Transmitter string
+	BSSID       string
+
+	// Frame body after the management header
+	Body []byte
+
+	// Reported by radiotap; zero when the capture carried none
+	Signal    int
+	Frequency int
+}
+
+// decodeWiFiFrame decodes a captured frame of the given link type. Only
+// management frames are returned.
+func decodeWiFiFrame(linkType uint32, data []byte, at time.Time) (wifiFrame, bool) {
+	frame := wifiFrame{Time: at}
+
+	switch linkType {
+	case linkTypeIEEE80211Radiotap:
+		var ok bool
+		if data, ok = frame.parseRadiotap(data); !ok {
+			return wifiFrame{}, false
+		}
+	case linkTypeIEEE80211:
+	default:
+		return wifiFrame{}, false
+	}
+
+	// Frame control: protocol version and type in the low bits, subtype above
+	if len(data) < 24 || data[0]&0x0f != 0 {
+		return wifiFrame{}, false
+	}
+	frame.Subtype = int(data[0] >> 4)
+	headerLen := 24
+	if data[1]&0x80 != 0 {
+		// The order bit on a management frame adds an HT control field
+		headerLen += 4
+	}
+	if len(data) < headerLen {
+		return wifiFrame{}, false
+	}
+
+	frame.Receiver = wifiAddress(data[4:10])
+	frame.Transmitter = wifiAddress(data[10:16])
+	frame.BSSID = wifiAddress(data[16:22])
+	frame.Body = data[headerLen:]
+	return frame, true
+}
+
+// parseRadiotap reads the channel and signal from a radiotap header and returns
+// the 802.11 frame behind it, without its checksum
+func (f *wifiFrame) parseRadiotap(data []byte) ([]byte, bool) {
+	if len(data) < 8 || data[0] != 0 {
+		return nil, false
+	}
+	length := int(binary.LittleEndian.Uint16(data[2:4]))
+	if length < 8 || length > len(data) {
+		return nil, false
+	}
+	header := data[:length]
+	present := binary.LittleEndian.Uint32(header[4:8])
+
+	// Further present words follow while bit 31 is set; their fields come later
+	offset := 8
+	for word := present; word&(1<<31) != 0; {
+		if offset+4 > length {
+			return nil, false
+		}
+		word = binary.LittleEndian.Uint32(header[offset : offset+4])
+		offset += 4
+	}
+
+	var flags byte
+	for field, layout := range radiotapFieldLayout {
+		if present&(1<<field) == 0 {
+			continue
+		}
+		offset = (offset + layout.align - 1) &^ (layout.align - 1)
+		if offset+layout.size > length {
+			break
+		}
+		value := header[offset : offset+layout.size]
+		switch field {
+		case radiotapFlags:
+			flags = value[0]
+		case radiotapChannel:
+			f.Frequency = int(binary.LittleEndian.Uint16(value[0:2]))
+		case radiotapAntSignal:
+			f.Signal = int(int8(value[0]))
+		}
+		offset += layout.size
+	}
+
+	frame := data[length:]
+	if flags&radiotapFlagFCS != 0 {
+		if len(frame) < 4 {
+			return nil, false
+		}
+		frame = frame[:len(frame)-4]
+	}
+	return frame, true
+}
+
+// wifiAddress formats a hardware address the way scan results report it
+func wifiAddress(b []byte) string {
+	return strings.ToUpper(net.HardwareAddr(b).String())
+}
+
+// reasonCode returns the reason code of a deauthentication or disassociation frame
+func (f wifiFrame) reasonCode() uint16 {
+	if len(f.Body) < 2 {
+		return 0
+	}
+	return binary.LittleEndian.Uint16(f.Body[0:2])
+}
+
+// elements decodes the information elements of a beacon, probe request or probe
+// response, and the privacy bit of the latter two
+func (f wifiFrame) elements() (wifiElements, bool) {
+	switch f.Subtype {
+	case wifiSubtypeProbeRequest:
+		return parseInformationElements(f.Body), true
+	case wifiSubtypeBeacon, wifiSubtypeProbeResponse:
+		// Timestamp, beacon interval and capability precede the elements
+		if len(f.Body) < 12 {
+			return wifiElements{}, false
+		}
+		elements := parseInformationElements(f.Body[12:])
+		elements.Security.known = true
+		elements.Security.privacy = binary.LittleEndian.Uint16(f.Body[10:12])&0x0010 != 0
+		return elements, true
+	}
+	return wifiElements{}, false
+}
@@ -0,0 +1,39 @@
+package scanners
+
+// WiFi bands reported on WiFiDevice.Band
+const (
+	wifiBand24GHz = "2.4 GHz"
+	wifiBand5GHz  = "5 GHz"
+	wifiBand6GHz  = "6 GHz"
+)
+
+// wifiBandForFrequency names the band a center frequency in MHz belongs to
+func wifiBandForFrequency(freq int) string {
+	switch {
+	case freq >= 2400 && freq < 2500:
+		return wifiBand24GHz
+	case freq >= 5150 && freq < 5925:
+		return wifiBand5GHz
+	case freq >= 5925 && freq <= 7125:
+		return wifiBand6GHz
+	}
+	return ""
+}
+
+// wifiChannelForFrequency returns the channel number of a center frequency in
+// MHz, or 0 when it is outside the 2.4, 5 and 6 GHz bands
+func wifiChannelForFrequency(freq int) int {
+	switch {
+	case freq == 2484:
+		return 14
+	case freq >= 2412 && freq <= 2472:
+		return (freq - 2407) / 5
+	case freq >= 5150 && freq < 5925:
+		return (freq - 5000) / 5
+	case freq == 5935:
+		return 2
+	case freq >= 5955 && freq <= 7115:
+		return (freq - 5950) / 5
+	}
+	return 0
+}
@@ -0,0 +1,159 @@
+package scanners
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// 802.11 information element IDs and vendor-specific types
+const (
+	ieSSID           = 0
+	ieDSParameterSet = 3
+	ieRSN            = 48
+	ieVendorSpecific = 221
+)
+
+// Vendor OUI prefixes that carry the WPA1 and WPS elements
+var (
+	ouiMicrosoftWPA = []byte{0x00, 0x50, 0xf2, 0x01}
+	ouiMicrosoftWPS = []byte{0x00, 0x50, 0xf2, 0x04}
+	ouiIEEE80211    = []byte{0x00, 0x0f, 0xac}
+)
+
+// cipherSuiteNames maps cipher suite selectors, shared by WPA1 and RSN, to names
+var cipherSuiteNames = map[byte]string{
+	1:  "WEP-40",
+	2:  "TKIP",
+	4:  "CCMP",
+	5:  "WEP-104",
+	6:  "BIP-CMAC-128",
+	8:  "GCMP",
+	9:  "GCMP-256",
+	10: "CCMP-256",
+}
+
+// akmSuiteNames maps authentication and key management selectors to names
+var akmSuiteNames = map[byte]string{
+	1:  "802.1X",
+	2:  "PSK",
+	3:  "FT-802.1X",
+	4:  "FT-PSK",
+	5:  "802.1X-SHA256",
+	6:  "PSK-SHA256",
+	8:  "SAE",
+	9:  "FT-SAE",
+	11: "802.1X-SUITE-B",
+	12: "802.1X-SUITE-B-192",
+	18: "OWE",
+	24: "SAE-EXT-KEY",
+	25: "FT-SAE-EXT-KEY",
+}
+
+// RSN capability bits for management frame protection
+const (
+	rsnCapMFPRequired = 0x0040
+	rsnCapMFPCapable  = 0x0080
+)
+
+// wifiElements is what the decoder understood from a run of information elements
+type wifiElements struct {
+	SSID     string
+	HasSSID  bool
+	Channel  int
+	Security wifiSecurity
+}
+
+// parseInformationElements decodes the elements of a beacon or probe response body
+func parseInformationElements(data []byte) wifiElements {
+	var elements wifiElements
+
+	for len(data) >= 2 {
+		id, length := data[0], int(data[1])
+		if 2+length > len(data) {
+			break
+		}
+		body := data[2 : 2+length]
+		data = data[2+length:]
+
+		switch id {
+		case ieSSID:
+			elements.HasSSID = true
+			elements.SSID = string(bytes.TrimRight(body, "\x00"))
+		case ieDSParameterSet:
+			if length == 1 {
+				elements.Channel = int(body[0])
+			}
+		case ieRSN:
+			suites, capabilities, ok := parseSuites(body, ouiIEEE80211)
+			if ok {
+				elements.Security.rsn = suites
+				switch {
+				case capabilities&rsnCapMFPRequired != 0:
+					elements.Security.pmf = "required"
+				case capabilities&rsnCapMFPCapable != 0:
+					elements.Security.pmf = "capable"
+				}
+			}
+		case ieVendorSpecific:
+			switch {
+			case bytes.HasPrefix(body, ouiMicrosoftWPA):
+				if suites, _, ok := parseSuites(body[4:], ouiMicrosoftWPA[:3]); ok {
+					elements.Security.wpa = suites
+				}
+			case bytes.HasPrefix(body, ouiMicrosoftWPS):
+				elements.Security.wps = parseWPSAttributes(body[4:])
+			}
+		}
+	}
+
+	return elements
+}
+
+// parseSuites decodes the version, group cipher, pairwise ciphers, AKM suites and
+// optional capabilities shared by the RSN and WPA1 element layouts. Selectors with
+// a foreign OUI are skipped.
+func parseSuites(body []byte, oui []byte) (*wifiSuites, uint16, bool) {
+	if len(body) < 2 || binary.LittleEndian.Uint16(body[0:2]) != 1 {
+		return nil, 0, false
+	}
+	body = body[2:]
+	suites := &wifiSuites{}
+
+	selector := func(b []byte, names map[byte]string) string {
+		if !bytes.Equal(b[:3], oui) {
+			return ""
+		}
+		return names[b[3]]
+	}
+
+	// Every later field is optional; missing ones take their defaults
+	if len(body) >= 4 {
+		suites.group = selector(body[:4], cipherSuiteNames)
+		body = body[4:]
+	}
+	for _, list := range []struct {
+		names map[byte]string
+		out   *[]string
+	}{
+		{cipherSuiteNames, &suites.pairwise},
+		{akmSuiteNames, &suites.akm},
+	} {
+		if len(body) < 2 {
+			break
+		}
+		count := int(binary.LittleEndian.Uint16(body[0:2]))
+		body = body[2:]
+		for i := 0; i < count && len(body) >= 4; i++ {
+			if name := selector(body[:4], list.names); name != "" {
+				*list.out = append(*list.out, name)
+			}
+			body = body[4:]
+		}
+	}
+
+	var capabilities uint16
+	if len(body) >= 2 {
+		capabilities = binary.LittleEndian.Uint16(body[0:2])
+	}
+	return suites, capabilities, true
+}
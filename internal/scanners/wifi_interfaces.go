@@ -0,0 +1,364 @@
+package scanners
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Interface types reported by `iw dev`
+const (
+	wifiTypeManaged = "managed"
+	wifiTypeMonitor = "monitor"
+)
+
+// wifiInterface is a wireless interface as `iw dev` reports it
+type wifiInterface struct {
+	Name string
+	Phy  string // radio the interface belongs to, e.g. "phy0"
+	Type string
+
+	// Frequency the radio is tuned to in MHz; zero when not reported
+	Frequency int
+}
+
+// parseIwDev parses the interfaces listed by `iw dev`, grouped under their phy
+func parseIwDev(output string) []wifiInterface {
+	var interfaces []wifiInterface
+	var phy string
+	var current *wifiInterface
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if index, ok := strings.CutPrefix(line, "phy#"); ok {
+			phy = "phy" + index
+			current = nil
+			continue
+		}
+		if name, ok := strings.CutPrefix(line, "Interface "); ok {
+			interfaces = append(interfaces, wifiInterface{Name: strings.TrimSpace(name), Phy: phy})
+			current = &interfaces[len(interfaces)-1]
+			continue
+		}
+		// P2P devices are listed as "Unnamed/non-netdev interface" and cannot be used
+		if strings.HasPrefix(line, "Unnamed/") || current == nil {
+			current = nil
+			continue
+		}
+
+		if value, ok := strings.CutPrefix(line, "type "); ok {
+			current.Type = strings.TrimSpace(value)
+		} else if value, ok := strings.CutPrefix(line, "channel "); ok {
+			// channel 6 (2437 MHz), width: 20 MHz, center1: 2437 MHz
+			if _, freq, ok := strings.Cut(value, "("); ok {
+				freq, _, _ = strings.Cut(freq, " MHz")
+				current.Frequency, _ = strconv.Atoi(strings.TrimSpace(freq))
+			}
+		}
+	}
+	return interfaces
+}
+
+// wifiInterfaces enumerates the wireless interfaces and the radios they belong to
+func (ws *WiFiScanner) wifiInterfaces(ctx context.Context) ([]wifiInterface, error) {
+	if !isCommandAvailable(ws.runner, "iw") {
+		return nil, fmt.Errorf("iw not available")
+	}
+	output, err := ws.runner.Output(ctx, "iw", "dev")
+	if err != nil {
+		return nil, err
+	}
+	return parseIwDev(string(output)), nil
+}
+
+// parseIwPhy returns the radios listed by `iw phy`, including those without interfaces
+func parseIwPhy(output string) []string {
+	var phys []string
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		if name, ok := strings.CutPrefix(scanner.Text(), "Wiphy "); ok {
+			phys = append(phys, strings.TrimSpace(name))
+		}
+	}
+	return phys
+}
+
+// resolvePhy returns the radio named by a phy name or by one of its interfaces
+func resolvePhy(name string, interfaces []wifiInterface) string {
+	for _, iface := range interfaces {
+		if iface.Phy == name || iface.Name == name {
+			return iface.Phy
+		}
+	}
+	return ""
+}
+
+// resolveRadio resolves a phy name or interface name to its radio. Radios
+// without any interface are missing from `iw dev`, so they are looked up in
+// `iw phy`.
+func (ws *WiFiScanner) resolveRadio(ctx context.Context, name string, interfaces []wifiInterface) (string, error) {
+	if phy := resolvePhy(name, interfaces); phy != "" {
+		return phy, nil
+	}
+	output, err := ws.runner.Output(ctx, "iw", "phy")
+	if err != nil {
+		return "", fmt.Errorf("failed to list wireless radios: %v", err)
+	}
+	for _, phy := range parseIwPhy(string(output)) {
+		if phy == name {
+			return phy, nil
+		}
+	}
+	return "", nil
+}
+
+// monitorPhy returns the radio used for frame capture, or "" when there is none
+func (ws *WiFiScanner) monitorPhy(interfaces []wifiInterface) string {
+	if ws.config.WiFiMonitorPhy != "" {
+		return resolvePhy(ws.config.WiFiMonitorPhy, interfaces)
+	}
+	if ws.config.WiFiMonitorInterface != "" {
+		return resolvePhy(ws.config.WiFiMonitorInterface, interfaces)
+	}
+	return ""
+}
+
+// scanInterfaces picks the interfaces to scan on: the configured ones, or every
+// managed interface on a radio other than the monitor radio, since scanning
+// retunes the radio and would take it off the channels being captured
+func (ws *WiFiScanner) scanInterfaces(interfaces []wifiInterface) []string {
+	if len(ws.config.WiFiScanInterfaces) > 0 {
+		return ws.config.WiFiScanInterfaces
+	}
+
+	monitorPhy := ws.monitorPhy(interfaces)
+	var names []string
+	for _, iface := range interfaces {
+		if iface.Type != wifiTypeManaged || (monitorPhy != "" && iface.Phy == monitorPhy) {
+			continue
+		}
+		names = append(names, iface.Name)
+	}
+	return names
+}
+
+// setupMonitorInterface returns the interface to capture frames from. With a
+// monitor radio configured, a monitor interface is created on it unless one by
+// that name already exists; existing interfaces are never reconfigured, and
+// only interfaces created here are deleted again by Close.
+func (ws *WiFiScanner) setupMonitorInterface(ctx context.Context) (string, error) {
+	name := ws.config.WiFiMonitorInterface
+	if ws.config.WiFiMonitorPhy == "" {
+		return name, nil
+	}
+
+	interfaces, err := ws.wifiInterfaces(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to list wireless interfaces: %v", err)
+	}
+	phy, err := ws.resolveRadio(ctx, ws.config.WiFiMonitorPhy, interfaces)
+	if err != nil {
+		return "", err
+	}
+	if phy == "" {
+		return "", fmt.Errorf("no wireless radio %s", ws.config.WiFiMonitorPhy)
+	}
+	if name == "" {
+		name = "mon" + strings.TrimPrefix(phy, "phy")
+	}
+
+	for _, iface := range interfaces {
+		if iface.Name != name {
+			continue
+		}
+		if iface.Type != wifiTypeMonitor {
+			return "", fmt.Errorf("%s is a %s interface, not changing it to monitor mode", name, iface.Type)
+		}
+		if iface.Phy != phy {
+			return "", fmt.Errorf("%s is a monitor interface on %s, not %s", name, iface.Phy, phy)
+		}
+		return name, nil
+	}
+
+	if _, err := ws.runner.Output(ctx, "iw", "phy", phy, "interface", "add", name, "type", "monitor"); err != nil {
+		return "", fmt.Errorf("failed to create monitor interface %s on %s: %v", name, phy, err)
+	}
+	if _, err := ws.runner.Output(ctx, "ip", "link", "set", name, "up"); err != nil {
+		ws.deleteMonitorInterface(ctx, name)
+		return "", fmt.Errorf("failed to bring up monitor interface %s: %v", name, err)
+	}
+
+	ws.mu.Lock()
+	ws.createdMonitor = name
+	ws.mu.Unlock()
+	return name, nil
+}
+
+// deleteMonitorInterface removes a monitor interface created by setupMonitorInterface
+func (ws *WiFiScanner) deleteMonitorInterface(ctx context.Context, name string) error {
+	if _, err := ws.runner.Output(ctx, "iw", "dev", name, "del"); err != nil {
+		return fmt.Errorf("failed to delete monitor interface %s: %v", name, err)
+	}
+	return nil
+}
+
+// Close stops the monitor-mode capture and channel hopping, and deletes the
+// monitor interface if the scanner created it
+func (ws *WiFiScanner) Close() error {
+	ws.mu.Lock()
+	ws.closed = true
+	if ws.stopCapture != nil {
+		ws.stopCapture()
+		ws.stopCapture = nil
+	}
+	created := ws.createdMonitor
+	ws.createdMonitor = ""
+	ws.mu.Unlock()
+
+	if created == "" {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return ws.deleteMonitorInterface(ctx, created)
+}
+
+// defaultHopDwell is used when no positive dwell time is configured
+const defaultHopDwell = 500 * time.Millisecond
+
+// hopBands maps the band names accepted in configuration to WiFiDevice bands
+var hopBands = map[string]string{
+	"2.4": wifiBand24GHz,
+	"5":   wifiBand5GHz,
+	"6":   wifiBand6GHz,
+}
+
+// hopFrequencies returns the schedule for the monitor interface: every enabled
+// frequency of its radio in the configured bands, limited to the configured
+// channels when there are any, in ascending order
+func (ws *WiFiScanner) hopFrequencies(ctx context.Context, iface string) ([]int, error) {
+	bands := make(map[string]bool)
+	for _, name := range ws.config.WiFiHopBands {
+		key := strings.TrimSpace(strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), "ghz"))
+		band, ok := hopBands[key]
+		if !ok {
+			return nil, fmt.Errorf("unknown band %q", name)
+		}
+		bands[band] = true
+	}
+
+	interfaces, err := ws.wifiInterfaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+	phy := resolvePhy(iface, interfaces)
+	if phy == "" {
+		return nil, fmt.Errorf("%s is not a wireless interface", iface)
+	}
+	output, err := ws.runner.Output(ctx, "iw", "phy", phy, "info")
+	if err != nil {
+		return nil, err
+	}
+
+	var frequencies []int
+	for _, freq := range parseIwPhyFrequencies(string(output)) {
+		if !bands[wifiBandForFrequency(freq)] {
+			continue
+		}
+		if len(ws.config.WiFiHopChannels) > 0 && !containsInt(ws.config.WiFiHopChannels, wifiChannelForFrequency(freq)) {
+			continue
+		}
+		frequencies = append(frequencies, freq)
+	}
+	if len(frequencies) == 0 {
+		return nil, fmt.Errorf("%s supports no enabled channels in bands %s", phy, strings.Join(ws.config.WiFiHopBands, ", "))
+	}
+	sort.Ints(frequencies)
+	return frequencies, nil
+}
+
+// parseIwPhyFrequencies returns the enabled frequencies listed by `iw phy <phy> info`
+func parseIwPhyFrequencies(output string) []int {
+	var frequencies []int
+	seen := make(map[int]bool)
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		// * 2412 MHz [1] (20.0 dBm), or "* 5180.0 MHz [36] (disabled)" in newer iw
+		line, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "* ")
+		if !ok || strings.Contains(line, "(disabled)") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 3 || fields[1] != "MHz" || !strings.HasPrefix(fields[2], "[") {
+			continue
+		}
+		freq, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil || seen[int(freq)] {
+			continue
+		}
+		seen[int(freq)] = true
+		frequencies = append(frequencies, int(freq))
+	}
+	return frequencies
+}
+
+// hopChannels tunes the monitor interface through the hopping schedule until
+// ctx is done. Frequencies the radio refuses are dropped from the schedule, and
+// the problems are kept for Scan.
+func (ws *WiFiScanner) hopChannels(ctx context.Context, iface string) {
+	if len(ws.config.WiFiHopBands) == 0 {
+		return
+	}
+	frequencies, err := ws.hopFrequencies(ctx, iface)
+	if err != nil {
+		ws.hopError("channel hopping on %s: %v", iface, err)
+		return
+	}
+
+	dwell := ws.config.WiFiHopDwell
+	if dwell <= 0 {
+		dwell = defaultHopDwell
+	}
+
+	for i := 0; len(frequencies) > 0; {
+		if ctx.Err() != nil {
+			return
+		}
+		i %= len(frequencies)
+		freq := frequencies[i]
+		if _, err := ws.runner.Output(ctx, "iw", "dev", iface, "set", "freq", strconv.Itoa(freq)); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			ws.hopError("channel hopping on %s: skipping %d MHz: %v", iface, freq, err)
+			frequencies = append(frequencies[:i], frequencies[i+1:]...)
+			continue
+		}
+		if len(frequencies) == 1 {
+			// A single channel needs no hopping once tuned
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(dwell):
+		}
+		i++
+	}
+	ws.hopError("channel hopping on %s: no usable channels left", iface)
+}
+
+// hopError keeps a channel hopping problem for the next scan
+func (ws *WiFiScanner) hopError(format string, args ...interface{}) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	ws.hopErrs = append(ws.hopErrs, fmt.Sprintf(format, args...))
+}
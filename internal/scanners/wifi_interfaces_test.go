@@ -0,0 +1,35 @@
+package scanners
+
+import (
+	"context"
+	"testing"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+)
+
+func TestResolveRadio(t *testing.T) {
+	// phy1 has no interfaces, so only `iw phy` lists it
+	interfaces := []wifiInterface{{Name: "wlan0", Phy: "phy0", Type: "managed"}}
+	iwPhy := "Wiphy phy0\n\tmax # scan SSIDs: 4\nWiphy phy1\n\tmax # scan SSIDs: 20\n"
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"wlan0", "phy0"},
+		{"phy0", "phy0"},
+		{"phy1", "phy1"},
+		{"wlan9", ""},
+	}
+	for _, tt := range tests {
+		runner := &sequenceRunner{outputs: map[string][]string{"iw phy": {iwPhy}}}
+		ws, err := NewWiFiScanner(models.DefaultConfig(), runner)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := ws.resolveRadio(context.Background(), tt.name, interfaces)
+		if err != nil || got != tt.want {
+			t.Errorf("resolveRadio(%q) = %q, %v, want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
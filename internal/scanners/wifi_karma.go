@@ -0,0 +1,213 @@
+package scanners
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+)
+
+// karmaTracker watches probe requests, probe responses and beacons for access
+// points that answer probes for networks they do not run, as KARMA and MANA do.
+// Frame times come from the capture, like the deauth tracker's.
+type karmaTracker struct {
+	window    time.Duration
+	threshold int
+
+	// SSID -> client -> last directed probe request
+	probes map[string]map[string]time.Time
+	// BSSID -> SSID -> last beacon; hidden networks beacon an empty SSID
+	beacons map[string]map[string]time.Time
+	// SSID -> last beacon from any access point
+	advertised map[string]time.Time
+	// BSSID -> SSID -> last probe response
+	answers map[string]map[string]time.Time
+
+	alerted    map[string]time.Time
+	lastExpire time.Time
+}
+
+// newKarmaTracker creates a tracker for the configured window and SSID threshold
+func newKarmaTracker(window time.Duration, threshold int) *karmaTracker {
+	return &karmaTracker{
+		window:     window,
+		threshold:  threshold,
+		probes:     make(map[string]map[string]time.Time),
+		beacons:    make(map[string]map[string]time.Time),
+		advertised: make(map[string]time.Time),
+		answers:    make(map[string]map[string]time.Time),
+		alerted:    make(map[string]time.Time),
+	}
+}
+
+// record notes one management frame and returns a KARMA_ATTACK when a probe
+// response shows its access point answering for networks it does not run
+func (t *karmaTracker) record(frame wifiFrame) []models.Attack {
+	elements, ok := frame.elements()
+	if !ok {
+		return nil
+	}
+	if frame.Time.Sub(t.lastExpire) > t.window {
+		t.expire(frame.Time)
+	}
+
+	switch frame.Subtype {
+	case wifiSubtypeProbeRequest:
+		// Wildcard probes ask every network to answer and say nothing about the client
+		if elements.SSID != "" {
+			touch(t.probes, elements.SSID, frame.Transmitter, frame.Time)
+		}
+	case wifiSubtypeBeacon:
+		touch(t.beacons, frame.BSSID, elements.SSID, frame.Time)
+		if elements.SSID != "" {
+			t.advertised[elements.SSID] = frame.Time
+		}
+	case wifiSubtypeProbeResponse:
+		if elements.SSID != "" {
+			touch(t.answers, frame.BSSID, elements.SSID, frame.Time)
+			return t.check(frame.BSSID, elements.SSID, frame.Time)
+		}
+	}
+	return nil
+}
+
+// check applies the KARMA rules to an access point that just answered a probe for ssid
+func (t *karmaTracker) check(bssid, ssid string, now time.Time) []models.Attack {
+	answered := t.recent(t.answers[bssid], now)
+	if len(answered) >= t.threshold {
+		if !t.alert(bssid, now) {
+			return nil
+		}
+		return []models.Attack{t.attack(bssid, models.SeverityHigh,
+			fmt.Sprintf("answers probes for %d different SSIDs in %v: %s", len(answered), t.window, quoteSSIDs(answered)),
+			answered, now)}
+	}
+
+	// Only SSIDs that clients around the sensor actually asked for are of interest
+	if len(t.recent(t.probes[ssid], now)) == 0 {
+		return nil
+	}
+	beaconed := t.recent(t.beacons[bssid], now)
+	if containsString(beaconed, ssid) {
+		return nil
+	}
+
+	var named []string
+	for _, advertised := range beaconed {
+		if advertised != "" {
+			named = append(named, advertised)
+		}
+	}
+	switch {
+	case len(named) > 0:
+		// Running one network and answering for another is never legitimate
+		if !t.alert(bssid+"|"+ssid, now) {
+			return nil
+		}
+		return []models.Attack{t.attack(bssid, models.SeverityHigh,
+			fmt.Sprintf("answers probes for '%s' while advertising %s", ssid, quoteSSIDs(named)),
+			[]string{ssid}, now)}
+	case len(beaconed) == 0 && now.Sub(t.advertised[ssid]) > t.window:
+		// Silent access points answering for a network nobody beacons; hidden
+		// networks beacon an empty SSID and are left alone
+		if !t.alert(bssid+"|"+ssid, now) {
+			return nil
+		}
+		return []models.Attack{t.attack(bssid, models.SeverityMedium,
+			fmt.Sprintf("answers probes for '%s', which no access point advertises, without sending beacons", ssid),
+			[]string{ssid}, now)}
+	}
+	return nil
+}
+
+// alert reports whether key may be alerted now, at most once per window
+func (t *karmaTracker) alert(key string, now time.Time) bool {
+	if at, ok := t.alerted[key]; ok && now.Sub(at) < t.window {
+		return false
+	}
+	t.alerted[key] = now
+	return true
+}
+
+// attack describes an access point caught answering for other networks, with the
+// clients that probed for them
+func (t *karmaTracker) attack(bssid string, severity models.Severity, detail string, ssids []string, now time.Time) models.Attack {
+	clients := make(map[string]bool)
+	for _, ssid := range ssids {
+		for client := range t.probes[ssid] {
+			clients[client] = true
+		}
+	}
+	var clientList []string
+	for client := range clients {
+		clientList = append(clientList, client)
+	}
+	sort.Strings(clientList)
+
+	description := fmt.Sprintf("KARMA/MANA rogue access point %s %s", bssid, detail)
+	if len(clientList) > 0 {
+		description += fmt.Sprintf(" (probed by %s)", strings.Join(clientList, ", "))
+	}
+	return models.Attack{
+		Type:        "KARMA_ATTACK",
+		Severity:    severity,
+		Description: description,
+		Target:      bssid,
+		Timestamp:   now,
+	}
+}
+
+// recent returns the keys seen within the window, sorted
+func (t *karmaTracker) recent(seen map[string]time.Time, now time.Time) []string {
+	var keys []string
+	for key, at := range seen {
+		if now.Sub(at) <= t.window {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// expire drops everything last seen more than a window ago
+func (t *karmaTracker) expire(now time.Time) {
+	t.lastExpire = now
+	for _, nested := range []map[string]map[string]time.Time{t.probes, t.beacons, t.answers} {
+		for outer, inner := range nested {
+			for key, at := range inner {
+				if now.Sub(at) > t.window {
+					delete(inner, key)
+				}
+			}
+			if len(inner) == 0 {
+				delete(nested, outer)
+			}
+		}
+	}
+	for _, flat := range []map[string]time.Time{t.advertised, t.alerted} {
+		for key, at := range flat {
+			if now.Sub(at) > t.window {
+				delete(flat, key)
+			}
+		}
+	}
+}
+
+// touch records when inner key was last seen under outer key
+func touch(seen map[string]map[string]time.Time, outer, inner string, at time.Time) {
+	if seen[outer] == nil {
+		seen[outer] = make(map[string]time.Time)
+	}
+	seen[outer][inner] = at
+}
+
+// quoteSSIDs lists SSIDs for descriptions
+func quoteSSIDs(ssids []string) string {
+	quoted := make([]string, len(ssids))
+	for i, ssid := range ssids {
+		quoted[i] = "'" + ssid + "'"
+	}
+	return strings.Join(quoted, ", ")
+}
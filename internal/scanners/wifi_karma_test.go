@@ -0,0 +1,93 @@
+package scanners
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+)
+
+// karma-probes.pcap holds radiotap frames on 2412 MHz, one second apart:
+//
+//   - Beacons from 00:11:22:33:44:55 (CorpNet), the hidden network
+//     00:AA:BB:CC:DD:01 and 02:DE:AD:00:00:02 (Printer-Setup)
+//   - A wildcard probe and a probe for CorpNet from 66:77:88:99:AA:01, and probes
+//     for HiddenLab and GuestNet from 66:77:88:99:AA:02
+//   - Probe responses from CorpNet and the hidden network for their own networks
+//   - 02:DE:AD:00:00:02 answering for CorpNet, and the silent 02:DE:AD:00:00:03
+//     answering for GuestNet
+//   - The silent DE:AD:BE:EF:00:01 answering for HomeWiFi, CafeNet, Airport_Free
+//     and Hotel-Guest, which nobody around probed for
+const karmaCapture = "testdata/capture/karma-probes.pcap"
+
+// replayKarma feeds frames to a new tracker and returns the alerts raised
+func replayKarma(frames []wifiFrame, threshold int) []models.Attack {
+	tracker := newKarmaTracker(5*time.Minute, threshold)
+	var attacks []models.Attack
+	for _, frame := range frames {
+		attacks = append(attacks, tracker.record(frame)...)
+	}
+	return attacks
+}
+
+func TestKarmaFromPcap(t *testing.T) {
+	attacks := replayKarma(readFixture(t, karmaCapture), 3)
+	if len(attacks) != 3 {
+		t.Fatalf("got %d alerts, want 3: %+v", len(attacks), attacks)
+	}
+
+	tests := []struct {
+		target   string
+		severity models.Severity
+		detail   string
+	}{
+		{"02:DE:AD:00:00:02", models.SeverityHigh, "answers probes for 'CorpNet' while advertising 'Printer-Setup' (probed by 66:77:88:99:AA:01)"},
+		{"02:DE:AD:00:00:03", models.SeverityMedium, "answers probes for 'GuestNet', which no access point advertises, without sending beacons (probed by 66:77:88:99:AA:02)"},
+		// Reported at the third SSID and not again for the fourth within the window
+		{"DE:AD:BE:EF:00:01", models.SeverityHigh, "answers probes for 3 different SSIDs in 5m0s: 'Airport_Free', 'CafeNet', 'HomeWiFi'"},
+	}
+	for i, tt := range tests {
+		attack := attacks[i]
+		if attack.Type != "KARMA_ATTACK" || attack.Target != tt.target || attack.Severity != tt.severity ||
+			!strings.HasSuffix(attack.Description, tt.detail) {
+			t.Errorf("alert %d = %+v, want %s at %s: %s", i, attack, tt.target, tt.severity, tt.detail)
+		}
+	}
+	if want := time.Unix(1700000007, 0); !attacks[2].Timestamp.Equal(want) {
+		t.Errorf("threshold alert at %v, want %v", attacks[2].Timestamp, want)
+	}
+}
+
+func TestKarmaIgnoresOwnNetworks(t *testing.T) {
+	// The real access point and the hidden network only answer for their own
+	// networks, and the MANA access point stays below a higher threshold
+	attacks := replayKarma(readFixture(t, karmaCapture), 5)
+	if len(attacks) != 2 || attacks[0].Target != "02:DE:AD:00:00:02" || attacks[1].Target != "02:DE:AD:00:00:03" {
+		t.Errorf("got %+v, want alerts for 02:DE:AD:00:00:02 and 02:DE:AD:00:00:03 only", attacks)
+	}
+}
+
+func TestWiFiScanReportsKarmaFromCaptureFile(t *testing.T) {
+	config := models.DefaultConfig()
+	config.WiFiCaptureFile = karmaCapture
+	ws, err := NewWiFiScanner(config, &sequenceRunner{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, attacks, err := ws.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	var karma []string
+	for _, attack := range attacks {
+		if attack.Type == "KARMA_ATTACK" {
+			karma = append(karma, attack.Target)
+		}
+	}
+	if strings.Join(karma, ",") != "02:DE:AD:00:00:02,02:DE:AD:00:00:03,DE:AD:BE:EF:00:01" {
+		t.Errorf("got KARMA alerts for %q", karma)
+	}
+}
@@ -0,0 +1,74 @@
+//go:build linux
+
+package scanners
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Hardware types of monitor-mode interfaces, from /sys/class/net/<if>/type
+const (
+	arphrdIEEE80211         = 801
+	arphrdIEEE80211Radiotap = 803
+)
+
+// listenMonitor reads 802.11 frames from a monitor-mode interface until ctx is
+// done, passing management frames to handle. It needs CAP_NET_RAW.
+func listenMonitor(ctx context.Context, iface string, handle func(wifiFrame)) error {
+	ifi, err := net.InterfaceByName(iface)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile("/sys/class/net/" + iface + "/type")
+	if err != nil {
+		return err
+	}
+	var linkType uint32
+	switch strings.TrimSpace(string(data)) {
+	case fmt.Sprint(arphrdIEEE80211Radiotap):
+		linkType = linkTypeIEEE80211Radiotap
+	case fmt.Sprint(arphrdIEEE80211):
+		linkType = linkTypeIEEE80211
+	default:
+		return fmt.Errorf("%s is not in monitor mode", iface)
+	}
+
+	fd, err := syscall.Socket(syscall.AF_PACKET, syscall.SOCK_RAW, int(htons(syscall.ETH_P_ALL)))
+	if err != nil {
+		return err
+	}
+	defer syscall.Close(fd)
+
+	if err := syscall.Bind(fd, &syscall.SockaddrLinklayer{Protocol: htons(syscall.ETH_P_ALL), Ifindex: ifi.Index}); err != nil {
+		return err
+	}
+	timeout := syscall.NsecToTimeval(arpReadTimeout.Nanoseconds())
+	if err := syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &timeout); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 65536)
+	for ctx.Err() == nil {
+		n, _, err := syscall.Recvfrom(fd, buf, 0)
+		if err != nil {
+			if err == syscall.EAGAIN || err == syscall.EINTR {
+				continue
+			}
+			return err
+		}
+
+		// Frames are handed on, so they must not share the read buffer
+		if frame, ok := decodeWiFiFrame(linkType, append([]byte(nil), buf[:n]...), time.Now()); ok {
+			handle(frame)
+		}
+	}
+
+	return ctx.Err()
+}
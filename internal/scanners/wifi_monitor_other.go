@@ -0,0 +1,10 @@
+//go:build !linux
+
+package scanners
+
+import "context"
+
+// listenMonitor is only implemented on Linux
+func listenMonitor(ctx context.Context, iface string, handle func(wifiFrame)) error {
+	return errMonitorCaptureUnsupported
+}
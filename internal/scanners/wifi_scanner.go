@@ -2,81 +2,512 @@ package scanners
 
 import (
 	"bufio"
+	"context"
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"os/exec"
+	"math"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/boboTheFoff/shheissee-go/internal/models"
 )
 
+// Limits on networks learned from captured frames, so a beacon flood with random
+// BSSIDs cannot grow the table or the scan results without bound
+const (
+	wifiMaxObserved  = 1024
+	wifiMaxFrameOnly = 256
+)
+
 // WiFiScanner handles WiFi network scanning and attack detection
-type WiFiScanner struct{}
+type WiFiScanner struct {
+	config *models.AttackDetectorConfig
+	runner CommandRunner
+
+	// Management frames from a monitor-mode interface or a capture file
+	mu             sync.Mutex
+	capturing      bool
+	captureErr     error
+	hopErrs        []string
+	captureRead    bool
+	stopCapture    context.CancelFunc
+	monitorIface   string
+	createdMonitor string
+	closed         bool
+	deauth         *deauthTracker
+	karma          *karmaTracker
+	pending        []models.Attack
+	observed       map[string]models.WiFiDevice
+	droppedBSSIDs  int
+}
+
+// NewWiFiScanner creates a new WiFi scanner. With a frame source configured it
+// fails unless the deauthentication and KARMA thresholds are positive.
+func NewWiFiScanner(config *models.AttackDetectorConfig, runner CommandRunner) (*WiFiScanner, error) {
+	ws := &WiFiScanner{
+		config: config,
+		runner: runner,
+		deauth: newDeauthTracker(config.DeauthWindow, config.DeauthThreshold),
+		karma:  newKarmaTracker(config.KarmaWindow, config.KarmaSSIDThreshold),
+
+		observed: make(map[string]models.WiFiDevice),
+	}
+
+	if ws.hasFrameSource() {
+		if config.DeauthThreshold <= 0 {
+			return nil, fmt.Errorf("deauth_threshold must be positive, got %d", config.DeauthThreshold)
+		}
+		if config.KarmaSSIDThreshold <= 0 {
+			return nil, fmt.Errorf("karma_ssid_threshold must be positive, got %d", config.KarmaSSIDThreshold)
+		}
+	}
+	return ws, nil
+}
+
+// Name returns the registry name of the WiFi scanner
+func (ws *WiFiScanner) Name() string {
+	return "wifi"
+}
+
+// Available reports whether a WiFi scanning tool is installed or a frame source
+// is configured
+func (ws *WiFiScanner) Available() bool {
+	return isCommandAvailable(ws.runner, "iw") || isCommandAvailable(ws.runner, "nmcli") ||
+		isCommandAvailable(ws.runner, "iwlist") || ws.hasFrameSource()
+}
+
+// Scan discovers nearby WiFi networks and analyzes them and the captured
+// management frames for attacks. Capture errors and networks left out at the
+// captured network limits are returned along with the results.
+func (ws *WiFiScanner) Scan(ctx context.Context) ([]interface{}, []models.Attack, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	// A sensor with only a monitor interface or capture file has nothing to scan with
+	devices, err := ws.ScanWiFiNetworks(ctx)
+	if err != nil && !ws.hasFrameSource() {
+		return nil, nil, err
+	}
+
+	// Frames are processed first so the networks they describe join the analysis
+	frameAttacks, captureErr := ws.DetectDeauthenticationAttacks(ctx)
+	devices, observedErr := ws.withObservedNetworks(devices)
+
+	attacks := append(ws.DetectWiFiAttacks(ctx, devices), frameAttacks...)
+	var errs []string
+	if captureErr != nil {
+		errs = append(errs, fmt.Sprintf("WiFi frame capture: %v", captureErr))
+	}
+	if observedErr != nil {
+		errs = append(errs, observedErr.Error())
+	}
+	if len(errs) > 0 {
+		return toInterfaces(devices), attacks, errors.New(strings.Join(errs, "; "))
+	}
+	return toInterfaces(devices), attacks, nil
+}
 
-// NewWiFiScanner creates a new WiFi scanner
-func NewWiFiScanner() *WiFiScanner {
-	return &WiFiScanner{}
+// ScanWiFiNetworks discovers nearby WiFi access points and devices, preferring
+// iw, then NetworkManager, then the deprecated wireless extensions
+func (ws *WiFiScanner) ScanWiFiNetworks(ctx context.Context) ([]models.WiFiDevice, error) {
+	var errs []string
+	for _, scanMethod := range []func(context.Context) ([]models.WiFiDevice, error){
+		ws.scanWithIw,
+		ws.scanWithNmcli,
+		ws.scanWithIwlist,
+	} {
+		devices, err := scanMethod(ctx)
+		if err == nil {
+			return devices, nil
+		}
+		errs = append(errs, err.Error())
+	}
+	return nil, fmt.Errorf("no WiFi scanning method available: %s", strings.Join(errs, "; "))
 }
 
-// ScanWiFiNetworks discovers nearby WiFi access points and devices
-func (ws *WiFiScanner) ScanWiFiNetworks() ([]models.WiFiDevice, error) {
-	devices, err := ws.scanWithIwlist()
+// scanWithIw uses iw to scan on each scan interface
+func (ws *WiFiScanner) scanWithIw(ctx context.Context) ([]models.WiFiDevice, error) {
+	interfaces, err := ws.wifiInterfaces(ctx)
 	if err != nil {
-		devices, err = ws.scanWithNmcli()
+		return nil, err
+	}
+	names := ws.scanInterfaces(interfaces)
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no wireless interfaces to scan on")
+	}
+
+	return scanEachInterface(names, func(iface string) ([]models.WiFiDevice, error) {
+		output, err := ws.runner.Output(ctx, "iw", "dev", iface, "scan")
 		if err != nil {
-			return nil, fmt.Errorf("no WiFi scanning method available: %v", err)
+			return nil, fmt.Errorf("iw scan on %s failed: %v", iface, err)
 		}
+		return ws.parseIwScanOutput(string(output), time.Now()), nil
+	})
+}
+
+// scanEachInterface runs scan on every interface and merges the results. Access
+// points heard by several radios are reported once, with the strongest signal.
+// It fails only when no interface could be scanned.
+func scanEachInterface(interfaces []string, scan func(iface string) ([]models.WiFiDevice, error)) ([]models.WiFiDevice, error) {
+	var devices []models.WiFiDevice
+	seen := make(map[string]int)
+	var lastErr error
+	scanned := 0
+	for _, iface := range interfaces {
+		found, err := scan(iface)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		scanned++
+		for _, device := range found {
+			if i, ok := seen[device.Address]; ok {
+				if device.Signal > devices[i].Signal {
+					devices[i] = device
+				}
+				continue
+			}
+			seen[device.Address] = len(devices)
+			devices = append(devices, device)
+		}
+	}
+	if scanned == 0 {
+		return nil, lastErr
 	}
+
 	return devices, nil
 }
 
-// scanWithIwlist uses iwlist to scan for WiFi networks
-func (ws *WiFiScanner) scanWithIwlist() ([]models.WiFiDevice, error) {
-	if !isCommandAvailable("iwlist") {
-		return nil, fmt.Errorf("iwlist not available")
+// nmcliFields are the fields requested from nmcli. BANDWIDTH needs NetworkManager
+// 1.46 or later, so older versions are asked again without it.
+var nmcliFields = []string{"SSID", "BSSID", "FREQ", "CHAN", "BANDWIDTH", "SIGNAL", "SECURITY", "WPA-FLAGS", "RSN-FLAGS"}
+
+// scanWithNmcli uses nmcli in terse mode as alternative, on the configured scan
+// interfaces or on every interface NetworkManager manages
+func (ws *WiFiScanner) scanWithNmcli(ctx context.Context) ([]models.WiFiDevice, error) {
+	if !isCommandAvailable(ws.runner, "nmcli") {
+		return nil, fmt.Errorf("nmcli not available")
 	}
+	if len(ws.config.WiFiScanInterfaces) == 0 {
+		return ws.nmcliList(ctx)
+	}
+	return scanEachInterface(ws.config.WiFiScanInterfaces, func(iface string) ([]models.WiFiDevice, error) {
+		return ws.nmcliList(ctx, "ifname", iface)
+	})
+}
 
-	cmd := exec.Command("iwlist", "scan")
-	output, err := cmd.CombinedOutput()
+// nmcliList lists WiFi networks with nmcli, passing extra to `device wifi list`
+func (ws *WiFiScanner) nmcliList(ctx context.Context, extra ...string) ([]models.WiFiDevice, error) {
+	list := func(fields []string) ([]byte, error) {
+		args := append([]string{"-t", "-f", strings.Join(fields, ","), "device", "wifi", "list"}, extra...)
+		return ws.runner.Output(ctx, "nmcli", args...)
+	}
+
+	fields := nmcliFields
+	output, err := list(fields)
 	if err != nil {
-		return nil, err
+		fields = nil
+		for _, field := range nmcliFields {
+			if field != "BANDWIDTH" {
+				fields = append(fields, field)
+			}
+		}
+		output, err = list(fields)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	return ws.parseIwlistOutput(string(output)), nil
+	return ws.parseNmcliOutput(string(output), fields, time.Now()), nil
 }
 
-// scanWithNmcli uses nmcli as alternative
-func (ws *WiFiScanner) scanWithNmcli() ([]models.WiFiDevice, error) {
-	if !isCommandAvailable("nmcli") {
-		return nil, fmt.Errorf("nmcli not available")
+// scanWithIwlist uses iwlist to scan for WiFi networks, on the configured scan
+// interfaces or on every interface that supports scanning
+func (ws *WiFiScanner) scanWithIwlist(ctx context.Context) ([]models.WiFiDevice, error) {
+	if !isCommandAvailable(ws.runner, "iwlist") {
+		return nil, fmt.Errorf("iwlist not available")
 	}
 
-	cmd := exec.Command("nmcli", "device", "wifi", "list")
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return nil, err
+	if len(ws.config.WiFiScanInterfaces) == 0 {
+		output, err := ws.runner.Output(ctx, "iwlist", "scan")
+		if err != nil {
+			return nil, err
+		}
+		return ws.parseIwlistOutput(string(output), time.Now()), nil
+	}
+
+	return scanEachInterface(ws.config.WiFiScanInterfaces, func(iface string) ([]models.WiFiDevice, error) {
+		output, err := ws.runner.Output(ctx, "iwlist", iface, "scan")
+		if err != nil {
+			return nil, fmt.Errorf("iwlist scan on %s failed: %v", iface, err)
+		}
+		return ws.parseIwlistOutput(string(output), time.Now()), nil
+	})
+}
+
+// parseIwScanOutput parses `iw dev <if> scan` output, including its RSN, WPA
+// and WPS element blocks. Last-seen ages are taken relative to now.
+func (ws *WiFiScanner) parseIwScanOutput(output string, now time.Time) []models.WiFiDevice {
+	var devices []models.WiFiDevice
+	var current *models.WiFiDevice
+	var security wifiSecurity
+	var block string
+
+	finish := func() {
+		if current != nil {
+			security.apply(current)
+			fillWiFiChannel(current)
+			devices = append(devices, *current)
+		}
 	}
 
-	return ws.parseNmcliOutput(string(output)), nil
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		raw := scanner.Text()
+
+		// "BSS 00:11:22:33:44:55(on wlan0) -- associated" starts a new access point
+		if bss, ok := strings.CutPrefix(raw, "BSS "); ok {
+			finish()
+			if end := strings.IndexAny(bss, "( "); end >= 0 {
+				bss = bss[:end]
+			}
+			current = &models.WiFiDevice{Address: strings.ToUpper(bss), LastSeen: now}
+			security = wifiSecurity{known: true}
+			block = ""
+			continue
+		}
+		if current == nil {
+			continue
+		}
+
+		// Element blocks name themselves and carry their first field on the same line
+		line := strings.TrimSpace(raw)
+		if !strings.HasPrefix(line, "*") {
+			name, rest, _ := strings.Cut(line, ":")
+			switch name {
+			case "RSN", "WPA":
+				block = name
+				line = strings.TrimSpace(rest)
+			case "WPS":
+				block = name
+				security.wps = &models.WPSInfo{}
+				line = strings.TrimSpace(rest)
+			default:
+				block = ""
+			}
+		}
+
+		if field, ok := strings.CutPrefix(line, "* "); ok && block == "WPS" {
+			parseIwWPSField(security.wps, field)
+			continue
+		}
+		if field, ok := strings.CutPrefix(line, "* "); ok && (block == "RSN" || block == "WPA") {
+			suites := security.suites(block == "RSN")
+			key, value, _ := strings.Cut(field, ":")
+			switch strings.TrimSpace(key) {
+			case "Group cipher":
+				suites.group = normalizeCipher(value)
+			case "Pairwise ciphers":
+				for _, cipher := range strings.Fields(value) {
+					suites.pairwise = appendUnique(suites.pairwise, normalizeCipher(cipher))
+				}
+			case "Authentication suites":
+				suites.akm = appendUnique(suites.akm, normalizeAKMs(value)...)
+			case "Capabilities":
+				if strings.Contains(value, "MFP-required") {
+					security.pmf = "required"
+				} else if strings.Contains(value, "MFP-capable") {
+					security.pmf = "capable"
+				}
+			}
+			continue
+		}
+
+		key, value, _ := strings.Cut(line, ":")
+		value = strings.TrimSpace(value)
+		switch strings.ToLower(key) {
+		case "ssid":
+			current.SSID = unescapeSSID(value)
+		case "freq":
+			if freq, err := strconv.ParseFloat(value, 64); err == nil {
+				current.Frequency = int(math.Round(freq))
+			}
+		case "signal":
+			if fields := strings.Fields(value); len(fields) > 0 {
+				if signal, err := strconv.ParseFloat(fields[0], 64); err == nil {
+					current.Signal = int(math.Round(signal))
+				}
+			}
+		case "last seen":
+			if fields := strings.Fields(value); len(fields) > 0 {
+				if age, err := strconv.Atoi(fields[0]); err == nil {
+					current.LastSeen = now.Add(-time.Duration(age) * time.Millisecond)
+				}
+			}
+		case "ds parameter set":
+			current.Channel = strings.TrimPrefix(value, "channel ")
+		case "* primary channel":
+			if current.Channel == "" {
+				current.Channel = value
+			}
+		case "* secondary channel offset":
+			if (value == "above" || value == "below") && current.Width < 40 {
+				current.Width = 40
+			}
+		case "* channel width":
+			if width := parseIwChannelWidth(value); width > current.Width {
+				current.Width = width
+			}
+		case "capability":
+			security.privacy = strings.Contains(value, "Privacy")
+		}
+	}
+
+	finish()
+
+	return devices
+}
+
+// parseIwChannelWidth reads the width of a VHT, HE or EHT operation element, as in
+// "1 (80 MHz)" or "160 MHz". The VHT "0 (20 or 40 MHz)" defers to the HT element.
+func parseIwChannelWidth(value string) int {
+	if start := strings.Index(value, "("); start >= 0 {
+		value = strings.TrimSuffix(value[start+1:], ")")
+	}
+	if strings.Contains(value, " or ") {
+		return 0
+	}
+	if strings.HasPrefix(value, "80+80") {
+		return 160
+	}
+	if fields := strings.Fields(value); len(fields) > 0 {
+		if width, err := strconv.Atoi(fields[0]); err == nil {
+			return width
+		}
+	}
+	return 0
 }
 
-// parseIwlistOutput parses iwlist scan output
-func (ws *WiFiScanner) parseIwlistOutput(output string) []models.WiFiDevice {
+// parseNmcliOutput parses `nmcli -t` wifi list output with the given columns
+func (ws *WiFiScanner) parseNmcliOutput(output string, columns []string, now time.Time) []models.WiFiDevice {
+	var devices []models.WiFiDevice
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		values := splitNmcliTerse(line)
+		if len(values) != len(columns) {
+			continue
+		}
+		field := make(map[string]string, len(columns))
+		for i, column := range columns {
+			field[column] = values[i]
+		}
+
+		device := models.WiFiDevice{
+			Address:  strings.ToUpper(field["BSSID"]),
+			Channel:  field["CHAN"],
+			LastSeen: now,
+			Status:   "Unknown",
+		}
+		if ssid := field["SSID"]; ssid != "--" {
+			device.SSID = ssid
+		}
+		if fields := strings.Fields(field["FREQ"]); len(fields) > 0 {
+			device.Frequency, _ = strconv.Atoi(fields[0])
+		}
+		if fields := strings.Fields(field["BANDWIDTH"]); len(fields) > 0 {
+			device.Width, _ = strconv.Atoi(fields[0])
+		}
+		if quality, err := strconv.Atoi(field["SIGNAL"]); err == nil {
+			device.Signal = qualityToDBm(quality)
+		}
+		security := parseNmcliSecurity(field["SECURITY"], field["WPA-FLAGS"], field["RSN-FLAGS"])
+		security.apply(&device)
+		fillWiFiChannel(&device)
+		devices = append(devices, device)
+	}
+
+	return devices
+}
+
+// splitNmcliTerse splits a line of nmcli terse output, where colons and
+// backslashes inside values are escaped with a backslash
+func splitNmcliTerse(line string) []string {
+	var fields []string
+	var field strings.Builder
+
+	escaped := false
+	for _, r := range line {
+		switch {
+		case escaped:
+			field.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == ':':
+			fields = append(fields, field.String())
+			field.Reset()
+		default:
+			field.WriteRune(r)
+		}
+	}
+
+	return append(fields, field.String())
+}
+
+// qualityToDBm reverses NetworkManager's mapping of -100..-40 dBm onto 0..100%
+func qualityToDBm(quality int) int {
+	if quality < 0 {
+		quality = 0
+	} else if quality > 100 {
+		quality = 100
+	}
+	return -40 - (100-quality)*60/100
+}
+
+// parseIwlistOutput parses iwlist scan output, including the WPA and RSN
+// information elements it decodes and the raw ones it prints in hex
+func (ws *WiFiScanner) parseIwlistOutput(output string, now time.Time) []models.WiFiDevice {
 	var devices []models.WiFiDevice
 	var currentDevice models.WiFiDevice
+	var security wifiSecurity
+	var suites *wifiSuites
+
+	finish := func() {
+		if currentDevice.Address != "" {
+			security.apply(&currentDevice)
+			fillWiFiChannel(&currentDevice)
+			devices = append(devices, currentDevice)
+		}
+	}
+
+	frequencyRegex := regexp.MustCompile(`Frequency:([\d.]+) GHz(?: \(Channel (\d+)\))?`)
+	signalRegex := regexp.MustCompile(`Signal level=(-?\d+)(/100| dBm)`)
+	lastBeaconRegex := regexp.MustCompile(`Last beacon:(\d+)ms ago`)
 
 	scanner := bufio.NewScanner(strings.NewReader(output))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
 
 		// New cell/cell starts
 		if strings.HasPrefix(line, "Cell ") || strings.Contains(line, "Address:") {
-			if currentDevice.Address != "" {
-				devices = append(devices, currentDevice)
-			}
-			currentDevice = models.WiFiDevice{}
+			finish()
+			currentDevice = models.WiFiDevice{LastSeen: now}
+			security = wifiSecurity{}
+			suites = nil
 		}
 
 		// Extract BSSID/MAC address
@@ -88,20 +519,29 @@ func (ws *WiFiScanner) parseIwlistOutput(output string) []models.WiFiDevice {
 		}
 
 		// Extract SSID
-		if strings.HasPrefix(line, "ESSID:") {
-			ssid := strings.Trim(line, "ESSID:\"")
+		if ssid, ok := strings.CutPrefix(line, "ESSID:"); ok {
+			ssid = strings.TrimPrefix(ssid, "\"")
 			ssid = strings.TrimSuffix(ssid, "\"")
-			currentDevice.SSID = ssid
+			currentDevice.SSID = unescapeSSID(ssid)
 		}
 
-		// Extract signal level
-		if strings.Contains(line, "Signal level=") {
-			signalRegex := regexp.MustCompile(`Signal level=(-\d+) dBm`)
-			matches := signalRegex.FindStringSubmatch(line)
-			if len(matches) > 1 {
-				if signal, err := strconv.Atoi(matches[1]); err == nil {
-					currentDevice.Signal = fmt.Sprintf("%d dBm", signal)
+		// Extract frequency, which carries the channel on most drivers
+		if matches := frequencyRegex.FindStringSubmatch(line); matches != nil {
+			if ghz, err := strconv.ParseFloat(matches[1], 64); err == nil {
+				currentDevice.Frequency = int(math.Round(ghz * 1000))
+			}
+			if matches[2] != "" {
+				currentDevice.Channel = matches[2]
+			}
+		}
+
+		// Extract signal level, in dBm or as a quality percentage
+		if matches := signalRegex.FindStringSubmatch(line); matches != nil {
+			if signal, err := strconv.Atoi(matches[1]); err == nil {
+				if matches[2] == "/100" {
+					signal = qualityToDBm(signal)
 				}
+				currentDevice.Signal = signal
 			}
 		}
 
@@ -112,73 +552,100 @@ func (ws *WiFiScanner) parseIwlistOutput(output string) []models.WiFiDevice {
 				currentDevice.Channel = strings.TrimSpace(parts[1])
 			}
 		}
+
+		// Extract the age of the last beacon
+		if matches := lastBeaconRegex.FindStringSubmatch(line); matches != nil {
+			if age, err := strconv.Atoi(matches[1]); err == nil {
+				currentDevice.LastSeen = now.Add(-time.Duration(age) * time.Millisecond)
+			}
+		}
+
+		// Privacy bit: on without a WPA or RSN element means WEP
+		if value, ok := strings.CutPrefix(line, "Encryption key:"); ok {
+			security.known = true
+			security.privacy = value == "on"
+		}
+
+		// Information elements and the cipher lines that follow them
+		if ie, ok := strings.CutPrefix(line, "IE: "); ok {
+			suites = nil
+			switch {
+			case strings.HasPrefix(ie, "WPA Version"):
+				suites = security.suites(false)
+			case strings.Contains(ie, "802.11i/WPA2"):
+				suites = security.suites(true)
+			case strings.HasPrefix(ie, "Unknown: "):
+				if raw, err := hex.DecodeString(strings.TrimPrefix(ie, "Unknown: ")); err == nil {
+					security.merge(parseInformationElements(raw).Security)
+				}
+			}
+			continue
+		}
+		if suites != nil {
+			key, value, _ := strings.Cut(line, ":")
+			key = strings.TrimSpace(key)
+			switch {
+			case key == "Group Cipher":
+				suites.group = normalizeCipher(value)
+			case strings.HasPrefix(key, "Pairwise Ciphers"):
+				for _, cipher := range strings.Fields(value) {
+					suites.pairwise = appendUnique(suites.pairwise, normalizeCipher(cipher))
+				}
+			case strings.HasPrefix(key, "Authentication Suites"):
+				suites.akm = appendUnique(suites.akm, normalizeAKMs(value)...)
+			default:
+				suites = nil
+			}
+		}
 	}
 
 	// Add the last device
-	if currentDevice.Address != "" {
-		devices = append(devices, currentDevice)
-	}
+	finish()
 
 	return devices
 }
 
-// parseNmcliOutput parses nmcli wifi list output
-func (ws *WiFiScanner) parseNmcliOutput(output string) []models.WiFiDevice {
-	var devices []models.WiFiDevice
-
-	scanner := bufio.NewScanner(strings.NewReader(output))
-	// Skip header line
-	if scanner.Scan() {
-		// Header line
+// fillWiFiChannel derives the band, and the channel when the source did not
+// report it, from the frequency
+func fillWiFiChannel(device *models.WiFiDevice) {
+	if device.Frequency == 0 {
+		return
 	}
-
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" {
-			continue
+	device.Band = wifiBandForFrequency(device.Frequency)
+	if device.Channel == "" {
+		if channel := wifiChannelForFrequency(device.Frequency); channel != 0 {
+			device.Channel = strconv.Itoa(channel)
 		}
+	}
+}
+
+// unescapeSSID decodes the \xNN escapes iw and iwlist print for bytes that are
+// not printable, including backslashes and edge spaces
+func unescapeSSID(ssid string) string {
+	if !strings.Contains(ssid, `\x`) {
+		return ssid
+	}
 
-		// Parse nmcli format: "* SSID BSSID MODE CHAN RATE SIGNAL BARS SECURITY"
-		// This is approximate parsing - real nmcli output may vary
-		parts := strings.Fields(line)
-		if len(parts) >= 6 {
-			device := models.WiFiDevice{
-				SSID:   parts[1],
-				Address: parts[2],
-				Channel: parts[4],
-				Signal: fmt.Sprintf("%s dBm", parts[6]), // Adjust based on actual output
-				Status: "Unknown",
+	var out []byte
+	for i := 0; i < len(ssid); i++ {
+		if ssid[i] == '\\' && i+4 <= len(ssid) && ssid[i+1] == 'x' {
+			if b, err := hex.DecodeString(ssid[i+2 : i+4]); err == nil {
+				out = append(out, b[0])
+				i += 3
+				continue
 			}
-			devices = append(devices, device)
 		}
+		out = append(out, ssid[i])
 	}
-
-	return devices
+	return string(out)
 }
 
 // DetectWiFiAttacks analyzes WiFi networks for attack patterns
-func (ws *WiFiScanner) DetectWiFiAttacks(devices []models.WiFiDevice) []models.Attack {
+func (ws *WiFiScanner) DetectWiFiAttacks(ctx context.Context, devices []models.WiFiDevice) []models.Attack {
 	var attacks []models.Attack
 
-	// Evil Twin Detection - Duplicate SSIDs
-	ssidCounts := make(map[string][]string)
-	for _, device := range devices {
-		if device.SSID != "" && device.SSID != "Hidden" {
-			ssidCounts[device.SSID] = append(ssidCounts[device.SSID], device.Address)
-		}
-	}
-
-	for ssid, addresses := range ssidCounts {
-		if len(addresses) > 1 {
-			attacks = append(attacks, models.Attack{
-				Type:        "EVIL_TWIN",
-				Severity:    models.SeverityHigh,
-				Description: fmt.Sprintf("Potential evil twin attack: SSID '%s' appears %d times", ssid, len(addresses)),
-				Target:      ssid,
-				Timestamp:   time.Now(),
-			})
-		}
-	}
+	// Evil Twin Detection - access points that deviate from the authorized baseline
+	attacks = append(attacks, DetectEvilTwins(devices, ws.config.AuthorizedNetworks)...)
 
 	// Rogue Access Point Detection
 	roguePatterns := []string{"free", "public", "hack", "test", "evil", "wifi", "guest", "default"}
@@ -198,128 +665,274 @@ func (ws *WiFiScanner) DetectWiFiAttacks(devices []models.WiFiDevice) []models.A
 		}
 	}
 
-	// Open Network Detection
-	for _, device := range devices {
-		// In iwlist, check for "Encryption key:off"
-		// For simplicity, we'll note suspicious SSIDs that might indicate open networks
-		if device.SSID != "" && strings.Contains(strings.ToLower(device.SSID), "open") {
-			attacks = append(attacks, models.Attack{
-				Type:        "OPEN_NETWORK",
-				Severity:    models.SeverityMedium,
-				Description: fmt.Sprintf("Open WiFi network detected: %s", device.SSID),
-				Target:      device.SSID,
-				Timestamp:   time.Now(),
-			})
-		}
-	}
+	// Open networks and weak encryption, from the security each access point advertises
+	attacks = append(attacks, DetectWeakSecurity(devices)...)
 
-	// Weak Encryption Detection
-	for _, device := range devices {
-		// Look for indicators of WEP encryption in SSID or capabilities
-		if strings.Contains(strings.ToLower(device.SSID), "wep") ||
-		   (device.Address != "" && isWEPMAC(device.Address)) {
-			attacks = append(attacks, models.Attack{
-				Type:        "WEAK_ENCRYPTION",
-				Severity:    models.SeverityHigh,
-				Description: fmt.Sprintf("Weak encryption (WEP) detected on network: %s", device.SSID),
-				Target:      device.SSID,
-				Timestamp:   time.Now(),
-			})
+	// WPS state advertised by each network
+	attacks = append(attacks, DetectWPSVulnerabilities(devices)...)
+
+	return attacks
+}
+
+// hasFrameSource reports whether a monitor-mode interface, monitor radio or
+// capture file is configured
+func (ws *WiFiScanner) hasFrameSource() bool {
+	return ws.hasMonitor() || ws.config.WiFiCaptureFile != ""
+}
+
+// hasMonitor reports whether frames are captured live
+func (ws *WiFiScanner) hasMonitor() bool {
+	return ws.config.WiFiMonitorInterface != "" || ws.config.WiFiMonitorPhy != ""
+}
+
+// frameAttacks starts the monitor-mode capture on first use, reads the capture
+// file once, and returns the attacks found in frames since the last call
+func (ws *WiFiScanner) frameAttacks(ctx context.Context) ([]models.Attack, error) {
+	var errs []string
+
+	if path := ws.config.WiFiCaptureFile; path != "" {
+		ws.mu.Lock()
+		read := ws.captureRead
+		ws.captureRead = true
+		ws.mu.Unlock()
+		if !read {
+			if err := readCaptureFile(path, ws.handleFrame); err != nil {
+				errs = append(errs, err.Error())
+			}
 		}
 	}
 
-	// WPS Vulnerability Detection
-	if ws.checkWPSVulnerabilities(devices) {
-		attacks = append(attacks, models.Attack{
-			Type:        "WPS_VULNERABILITY",
-			Severity:    models.SeverityMedium,
-			Description: "WPS-enabled networks detected - vulnerable to pixie dust and brute force attacks",
-			Target:      "wifi_network",
-			Timestamp:   time.Now(),
-		})
+	if err := ws.startMonitorCapture(ctx); err != nil {
+		errs = append(errs, err.Error())
 	}
 
-	return attacks
+	ws.mu.Lock()
+	attacks := ws.pending
+	ws.pending = nil
+	errs = append(errs, ws.hopErrs...)
+	ws.hopErrs = nil
+	ws.mu.Unlock()
+
+	if len(errs) > 0 {
+		return attacks, errors.New(strings.Join(errs, "; "))
+	}
+	return attacks, nil
 }
 
-// checkWPSVulnerabilities checks for WPS-enabled networks
-func (ws *WiFiScanner) checkWPSVulnerabilities(devices []models.WiFiDevice) bool {
-	if !isCommandAvailable("wash") { // Requires reaver tools
-		return false
+// startMonitorCapture sets up the monitor interface and runs the capture and
+// channel hopping in the background once, until Close. If a previous capture
+// died, its error is returned and the next call starts a new one.
+func (ws *WiFiScanner) startMonitorCapture(ctx context.Context) error {
+	if !ws.hasMonitor() {
+		return nil
+	}
+
+	ws.mu.Lock()
+	if err := ws.captureErr; err != nil {
+		ws.captureErr = nil
+		ws.mu.Unlock()
+		return err
 	}
+	if ws.capturing || ws.closed {
+		ws.mu.Unlock()
+		return nil
+	}
+	ws.capturing = true
+	ws.mu.Unlock()
 
-	cmd := exec.Command("wash", "-i", "wlan0", "-s") // This would require wireless interface
-	output, err := cmd.CombinedOutput()
+	iface, err := ws.setupMonitorInterface(ctx)
 	if err != nil {
-		return false
+		ws.mu.Lock()
+		ws.capturing = false
+		ws.mu.Unlock()
+		return err
 	}
 
-	// Check for WPS in output
-	if strings.Contains(string(output), "WPS") || strings.Contains(string(output), "Version") {
-		return true
+	captureCtx, cancel := context.WithCancel(context.Background())
+	ws.mu.Lock()
+	if ws.closed {
+		// Closed while the interface was being set up
+		ws.mu.Unlock()
+		cancel()
+		return ws.Close()
 	}
+	ws.stopCapture = cancel
+	ws.monitorIface = iface
+	ws.mu.Unlock()
 
-	return false
+	go ws.hopChannels(captureCtx, iface)
+	go func() {
+		err := listenMonitor(captureCtx, iface, ws.handleFrame)
+		stopped := captureCtx.Err() != nil
+		cancel()
+
+		ws.mu.Lock()
+		ws.capturing = false
+		if !stopped {
+			ws.captureErr = fmt.Errorf("monitor capture on %s stopped: %v", iface, err)
+		}
+		ws.mu.Unlock()
+	}()
+	return nil
 }
 
-// DetectDeauthenticationAttacks detects WiFi deauth attacks using airodump-ng
-func (ws *WiFiScanner) DetectDeauthenticationAttacks() []models.Attack {
-	var attacks []models.Attack
+// handleFrame feeds one captured management frame to the frame detectors
+func (ws *WiFiScanner) handleFrame(frame wifiFrame) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
 
-	if !isCommandAvailable("airodump-ng") {
-		return attacks
+	ws.pending = append(ws.pending, ws.deauth.record(frame)...)
+	ws.pending = append(ws.pending, ws.karma.record(frame)...)
+	ws.observe(frame)
+}
+
+// observe keeps the network described by a beacon or probe response. Callers hold mu.
+func (ws *WiFiScanner) observe(frame wifiFrame) {
+	if frame.Subtype != wifiSubtypeBeacon && frame.Subtype != wifiSubtypeProbeResponse {
+		return
+	}
+	elements, ok := frame.elements()
+	if !ok {
+		return
 	}
 
-	// Run airodump-ng for a short period to collect data
-	cmd := exec.Command("timeout", "10", "airodump-ng", "--output-format", "csv", "-w", "/tmp/wifi_scan")
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return attacks
+	device := models.WiFiDevice{
+		Address:   frame.BSSID,
+		SSID:      elements.SSID,
+		Signal:    frame.Signal,
+		Frequency: frame.Frequency,
+		LastSeen:  frame.Time,
+	}
+	if elements.Channel != 0 {
+		device.Channel = strconv.Itoa(elements.Channel)
+	}
+	elements.Security.apply(&device)
+	fillWiFiChannel(&device)
+
+	// Hidden networks name themselves only in probe responses, and beacons leave
+	// out the WPS config methods that probe responses carry
+	previous, ok := ws.observed[frame.BSSID]
+	if !ok && len(ws.observed) >= wifiMaxObserved {
+		ws.droppedBSSIDs++
+		return
+	}
+	if ok {
+		if device.SSID == "" {
+			device.SSID = previous.SSID
+		}
+		if device.WPS != nil && previous.WPS != nil && len(device.WPS.ConfigMethods) == 0 {
+			device.WPS.ConfigMethods = previous.WPS.ConfigMethods
+			if device.WPS.DeviceName == "" {
+				device.WPS.DeviceName = previous.WPS.DeviceName
+			}
+		}
+	}
+	ws.observed[frame.BSSID] = device
+}
+
+// withObservedNetworks adds the networks seen in captured frames to the scan
+// results, and fills in WPS details the scan could not see. Networks silent for
+// longer than the WiFi missing grace, measured from the newest frame, are dropped.
+// Only the most recently heard networks missing from the scan are added; how
+// many were left out is returned as an error with the results.
+func (ws *WiFiScanner) withObservedNetworks(devices []models.WiFiDevice) ([]models.WiFiDevice, error) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	var newest time.Time
+	for _, device := range ws.observed {
+		if device.LastSeen.After(newest) {
+			newest = device.LastSeen
+		}
 	}
 
-	// Parse output for deauthentication patterns
-	outputStr := string(output)
-	deauthCount := strings.Count(outputStr, "DEAUTH")
+	addresses := make([]string, 0, len(ws.observed))
+	for address, device := range ws.observed {
+		if newest.Sub(device.LastSeen) > ws.config.WiFiMissingGrace {
+			delete(ws.observed, address)
+			continue
+		}
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
 
-	if deauthCount > 5 { // Threshold for attack detection
-		attacks = append(attacks, models.Attack{
-			Type:        "WIFI_DEAUTH_ATTACK",
-			Severity:    models.SeverityHigh,
-			Description: fmt.Sprintf("Deauthentication attack detected: %d deauth packets observed", deauthCount),
-			Target:      "wifi_network",
-			Timestamp:   time.Now(),
+	index := make(map[string]int, len(devices))
+	for i, device := range devices {
+		index[strings.ToUpper(device.Address)] = i
+	}
+	var frameOnly []models.WiFiDevice
+	for _, address := range addresses {
+		observed := ws.observed[address]
+		i, ok := index[address]
+		if !ok {
+			frameOnly = append(frameOnly, observed)
+			continue
+		}
+		if wps := devices[i].WPS; observed.WPS != nil && (wps == nil || len(wps.ConfigMethods) == 0) {
+			devices[i].WPS = observed.WPS
+		}
+	}
+
+	var errs []string
+	if len(frameOnly) > wifiMaxFrameOnly {
+		sort.SliceStable(frameOnly, func(i, j int) bool {
+			return frameOnly[i].LastSeen.After(frameOnly[j].LastSeen)
 		})
+		errs = append(errs, fmt.Sprintf("%d networks seen only in captured frames left out beyond the %d most recent",
+			len(frameOnly)-wifiMaxFrameOnly, wifiMaxFrameOnly))
+		frameOnly = frameOnly[:wifiMaxFrameOnly]
+		sort.Slice(frameOnly, func(i, j int) bool { return frameOnly[i].Address < frameOnly[j].Address })
 	}
+	devices = append(devices, frameOnly...)
 
-	// Clean up temp file
-	exec.Command("rm", "-f", "/tmp/wifi_scan-01.csv", "/tmp/wifi_scan-01.cap").Run()
+	if ws.droppedBSSIDs > 0 {
+		errs = append(errs, fmt.Sprintf("%d frames from new BSSIDs dropped beyond %d captured networks (possible beacon flood)",
+			ws.droppedBSSIDs, wifiMaxObserved))
+		ws.droppedBSSIDs = 0
+	}
+	if len(errs) > 0 {
+		return devices, errors.New(strings.Join(errs, "; "))
+	}
+	return devices, nil
+}
 
-	return attacks
+// DetectDeauthenticationAttacks reports deauthentication and disassociation
+// floods and KARMA access points seen in the captured frames since the last call,
+// together with any error reading the frame sources
+func (ws *WiFiScanner) DetectDeauthenticationAttacks(ctx context.Context) ([]models.Attack, error) {
+	if !ws.hasFrameSource() {
+		return nil, nil
+	}
+	return ws.frameAttacks(ctx)
 }
 
-// CheckWiFiInterfaceStatus checks the status of wireless interfaces
-func (ws *WiFiScanner) CheckWiFiInterfaceStatus() []models.Attack {
+// CheckWiFiInterfaceStatus reports wireless interfaces in monitor mode other
+// than the one the scanner captures from
+func (ws *WiFiScanner) CheckWiFiInterfaceStatus(ctx context.Context) []models.Attack {
 	var attacks []models.Attack
 
-	if !isCommandAvailable("iwconfig") {
-		return attacks
-	}
-
-	cmd := exec.Command("iwconfig")
-	output, err := cmd.CombinedOutput()
+	interfaces, err := ws.wifiInterfaces(ctx)
 	if err != nil {
 		return attacks
 	}
 
-	outputStr := string(output)
+	ws.mu.Lock()
+	own := ws.monitorIface
+	ws.mu.Unlock()
 
-	// Check for monitoring mode interfaces (indicating active monitoring)
-	if strings.Contains(outputStr, "Mode:Monitor") {
+	for _, iface := range interfaces {
+		if iface.Type != wifiTypeMonitor || iface.Name == own || iface.Name == ws.config.WiFiMonitorInterface {
+			continue
+		}
+		description := fmt.Sprintf("WiFi interface %s on %s is in monitor mode", iface.Name, iface.Phy)
+		if iface.Frequency != 0 {
+			description += fmt.Sprintf(" (%d MHz)", iface.Frequency)
+		}
 		attacks = append(attacks, models.Attack{
 			Type:        "WIFI_MONITORING",
 			Severity:    models.SeverityLow,
-			Description: "WiFi monitoring active - checking for attacks",
-			Target:      "wifi",
+			Description: description,
+			Target:      iface.Name,
 			Timestamp:   time.Now(),
 		})
 	}
@@ -327,18 +940,8 @@ func (ws *WiFiScanner) CheckWiFiInterfaceStatus() []models.Attack {
 	return attacks
 }
 
-// Helper functions
-
-// isWEPMAC makes a rough guess about WEP encryption based on MAC characteristics
-// This is not reliable but provides basic detection
-func isWEPMAC(mac string) bool {
-	// Very basic heuristic - not accurate but provides some detection
-	// In a real implementation, you would need to examine the actual capabilities
-	return false // Placeholder - would need actual capability parsing
-}
-
 // MonitorWiFiAttacks continuously monitors for WiFi attacks
-func (ws *WiFiScanner) MonitorWiFiAttacks() (<-chan models.Attack, error) {
+func (ws *WiFiScanner) MonitorWiFiAttacks(ctx context.Context) (<-chan models.Attack, error) {
 	attackCh := make(chan models.Attack, 100)
 
 	go func() {
@@ -346,22 +949,32 @@ func (ws *WiFiScanner) MonitorWiFiAttacks() (<-chan models.Attack, error) {
 
 		for {
 			// Check for deauth attacks
-			if attacks := ws.DetectDeauthenticationAttacks(); len(attacks) > 0 {
+			if attacks, _ := ws.DetectDeauthenticationAttacks(ctx); len(attacks) > 0 {
 				for _, attack := range attacks {
 					attackCh <- attack
 				}
 			}
 
 			// Check interface status
-			if attacks := ws.CheckWiFiInterfaceStatus(); len(attacks) > 0 {
+			if attacks := ws.CheckWiFiInterfaceStatus(ctx); len(attacks) > 0 {
 				for _, attack := range attacks {
 					attackCh <- attack
 				}
 			}
 
-			time.Sleep(30 * time.Second)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(30 * time.Second):
+			}
 		}
 	}()
 
 	return attackCh, nil
 }
+
+// LoadKnownWiFiDevices loads the BSSIDs of known WiFi access points from file.
+// The file uses the same format as the known network devices file.
+func LoadKnownWiFiDevices(filename string) ([]string, error) {
+	return LoadKnownDevices(filename)
+}
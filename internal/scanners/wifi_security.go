@@ -0,0 +1,339 @@
+package scanners
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+)
+
+// Security modes summarized onto WiFiDevice.Security
+const (
+	wifiSecurityOpen = "open"
+	wifiSecurityOWE  = "OWE"
+	wifiSecurityWEP  = "WEP"
+	wifiSecurityWPA  = "WPA"
+	wifiSecurityWPA2 = "WPA2"
+	wifiSecurityWPA3 = "WPA3"
+)
+
+// wpa3AKMs are the key management suites that make an RSN network WPA3
+var wpa3AKMs = map[string]bool{
+	"SAE":                true,
+	"FT-SAE":             true,
+	"SAE-EXT-KEY":        true,
+	"FT-SAE-EXT-KEY":     true,
+	"802.1X-SUITE-B":     true,
+	"802.1X-SUITE-B-192": true,
+}
+
+// wifiSuites are the ciphers and key management suites of one WPA1 or RSN element
+type wifiSuites struct {
+	group    string
+	pairwise []string
+	akm      []string
+}
+
+// wifiSecurity collects the protection an access point advertises before it is
+// summarized onto a WiFiDevice
+type wifiSecurity struct {
+	known   bool
+	privacy bool
+	wpa     *wifiSuites
+	rsn     *wifiSuites
+	pmf     string
+	wps     *models.WPSInfo
+}
+
+// merge adds what another source reported about the same access point
+func (s *wifiSecurity) merge(other wifiSecurity) {
+	s.known = s.known || other.known
+	s.privacy = s.privacy || other.privacy
+	if other.wps != nil {
+		s.wps = other.wps
+	}
+	if other.wpa != nil {
+		s.wpa = other.wpa
+	}
+	if other.rsn != nil {
+		s.rsn = other.rsn
+	}
+	if other.pmf != "" {
+		s.pmf = other.pmf
+	}
+}
+
+// suites returns the WPA1 or RSN suites, creating them on first use
+func (s *wifiSecurity) suites(rsn bool) *wifiSuites {
+	target := &s.wpa
+	if rsn {
+		target = &s.rsn
+	}
+	if *target == nil {
+		*target = &wifiSuites{}
+	}
+	return *target
+}
+
+// mode summarizes the protection as open, OWE, WEP or the WPA generations offered,
+// such as "WPA2/WPA3" for a transition-mode network
+func (s *wifiSecurity) mode() string {
+	if s.wpa == nil && s.rsn == nil {
+		if s.privacy {
+			return wifiSecurityWEP
+		}
+		return wifiSecurityOpen
+	}
+
+	var generations []string
+	if s.wpa != nil {
+		generations = append(generations, wifiSecurityWPA)
+	}
+	if s.rsn != nil {
+		var wpa2, wpa3, owe bool
+		for _, akm := range s.rsn.akm {
+			switch {
+			case wpa3AKMs[akm]:
+				wpa3 = true
+			case akm == "OWE":
+				owe = true
+			default:
+				wpa2 = true
+			}
+		}
+		if owe && !wpa2 && !wpa3 && s.wpa == nil {
+			return wifiSecurityOWE
+		}
+		if wpa2 || !wpa3 {
+			generations = append(generations, wifiSecurityWPA2)
+		}
+		if wpa3 {
+			generations = append(generations, wifiSecurityWPA3)
+		}
+	}
+	return strings.Join(generations, "/")
+}
+
+// apply summarizes the collected security info onto a device. Devices whose
+// source reported nothing about security are left unknown.
+func (s *wifiSecurity) apply(device *models.WiFiDevice) {
+	if !s.known && s.wpa == nil && s.rsn == nil {
+		return
+	}
+
+	device.Security = s.mode()
+	device.PMF = s.pmf
+	device.WPS = s.wps
+	device.GroupCipher = ""
+	device.Ciphers = nil
+	device.AKM = nil
+	for _, suites := range []*wifiSuites{s.rsn, s.wpa} {
+		if suites == nil {
+			continue
+		}
+		if device.GroupCipher == "" {
+			device.GroupCipher = suites.group
+		}
+		device.Ciphers = appendUnique(device.Ciphers, suites.pairwise...)
+		device.AKM = appendUnique(device.AKM, suites.akm...)
+	}
+}
+
+// appendUnique appends the items not already in list
+func appendUnique(list []string, items ...string) []string {
+	for _, item := range items {
+		if item != "" && !containsString(list, item) {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// normalizeCipher maps the cipher names printed by iwlist and iw onto the decoder's names
+func normalizeCipher(name string) string {
+	switch upper := strings.ToUpper(strings.TrimSpace(name)); upper {
+	case "", "NONE", "USE GROUP CIPHER SUITE":
+		return ""
+	case "GCMP-128":
+		return "GCMP"
+	case "AES-128-CMAC":
+		return "BIP-CMAC-128"
+	default:
+		for _, known := range cipherSuiteNames {
+			if upper == known {
+				return upper
+			}
+		}
+		return ""
+	}
+}
+
+// normalizeAKMs maps a list of key management suites printed by iwlist or iw onto
+// the decoder's names. iw separates suites with spaces but also uses spaces inside
+// "IEEE 802.1X", so that prefix is folded first.
+func normalizeAKMs(list string) []string {
+	list = strings.ReplaceAll(list, "IEEE 802.1X", "802.1X")
+
+	var akms []string
+	for _, field := range strings.Fields(list) {
+		name := strings.ToUpper(field)
+		name = strings.ReplaceAll(name, "FT/", "FT-")
+		name = strings.ReplaceAll(name, "/SHA-256", "-SHA256")
+		name = strings.ReplaceAll(name, "/SUITE-B", "-SUITE-B")
+		if name == "NONE" || strings.HasPrefix(name, "UNKNOWN") || strings.HasPrefix(name, "(") {
+			continue
+		}
+		akms = append(akms, name)
+	}
+	return akms
+}
+
+// nmcliFlagNames maps the WPA-FLAGS and RSN-FLAGS words of nmcli onto the decoder's names
+var nmcliFlagNames = map[string]string{
+	"pair_wep40":      "WEP-40",
+	"pair_wep104":     "WEP-104",
+	"pair_tkip":       "TKIP",
+	"pair_ccmp":       "CCMP",
+	"psk":             "PSK",
+	"802.1X":          "802.1X",
+	"sae":             "SAE",
+	"owe":             "OWE",
+	"owe_tm":          "OWE",
+	"eap_suite_b_192": "802.1X-SUITE-B-192",
+}
+
+// parseNmcliSecurity reads the SECURITY, WPA-FLAGS and RSN-FLAGS fields of nmcli
+func parseNmcliSecurity(security, wpaFlags, rsnFlags string) wifiSecurity {
+	s := wifiSecurity{known: true}
+	security = strings.TrimSpace(security)
+	s.privacy = security != "" && security != "--"
+
+	for _, flags := range []struct {
+		value string
+		rsn   bool
+	}{
+		{wpaFlags, false},
+		{rsnFlags, true},
+	} {
+		value := strings.TrimSpace(flags.value)
+		if value == "" || value == "--" || value == "(none)" {
+			continue
+		}
+		suites := s.suites(flags.rsn)
+		for _, flag := range strings.Fields(value) {
+			if group, ok := strings.CutPrefix(flag, "group_"); ok {
+				suites.group = nmcliFlagNames["pair_"+group]
+				continue
+			}
+			name, ok := nmcliFlagNames[flag]
+			if !ok {
+				continue
+			}
+			if strings.HasPrefix(flag, "pair_") {
+				suites.pairwise = appendUnique(suites.pairwise, name)
+			} else {
+				suites.akm = appendUnique(suites.akm, name)
+			}
+		}
+	}
+
+	return s
+}
+
+// wifiSecurityRule flags an access point whose advertised protection is weak
+type wifiSecurityRule struct {
+	attackType string
+	severity   models.Severity
+	reason     string
+	match      func(device models.WiFiDevice) bool
+}
+
+// wifiSecurityRules are evaluated against the parsed security of every access point.
+// Devices whose security could not be determined never match.
+var wifiSecurityRules = []wifiSecurityRule{
+	{"OPEN_NETWORK", models.SeverityMedium, "no encryption", func(d models.WiFiDevice) bool {
+		return d.Security == wifiSecurityOpen
+	}},
+	{"WEAK_ENCRYPTION", models.SeverityHigh, "WEP encryption", func(d models.WiFiDevice) bool {
+		return d.Security == wifiSecurityWEP
+	}},
+	{"WEAK_ENCRYPTION", models.SeverityMedium, "WPA1 accepted", func(d models.WiFiDevice) bool {
+		return containsString(strings.Split(d.Security, "/"), wifiSecurityWPA)
+	}},
+	{"WEAK_ENCRYPTION", models.SeverityMedium, "TKIP cipher accepted", func(d models.WiFiDevice) bool {
+		return containsString(d.Ciphers, "TKIP")
+	}},
+	{"WEAK_ENCRYPTION", models.SeverityHigh, "WEP cipher accepted", func(d models.WiFiDevice) bool {
+		return containsString(d.Ciphers, "WEP-40") || containsString(d.Ciphers, "WEP-104")
+	}},
+}
+
+// DetectWeakSecurity raises one attack per rule type and access point, listing every
+// matching reason at the highest matching severity
+func DetectWeakSecurity(devices []models.WiFiDevice) []models.Attack {
+	var attacks []models.Attack
+
+	for _, device := range devices {
+		if device.Security == "" {
+			continue
+		}
+
+		var types []string
+		reasons := make(map[string][]string)
+		severities := make(map[string]models.Severity)
+		for _, rule := range wifiSecurityRules {
+			if !rule.match(device) {
+				continue
+			}
+			if _, ok := reasons[rule.attackType]; !ok {
+				types = append(types, rule.attackType)
+				severities[rule.attackType] = rule.severity
+			}
+			reasons[rule.attackType] = append(reasons[rule.attackType], rule.reason)
+			if severityRank(rule.severity) > severityRank(severities[rule.attackType]) {
+				severities[rule.attackType] = rule.severity
+			}
+		}
+
+		sort.Strings(types)
+		for _, attackType := range types {
+			description := fmt.Sprintf("Weak WiFi security on %s (%s): %s [%s]",
+				ssidOrHidden(device.SSID), device.Address, strings.Join(reasons[attackType], ", "), device.Security)
+			if attackType == "OPEN_NETWORK" {
+				description = fmt.Sprintf("Open WiFi network detected: %s (%s)", ssidOrHidden(device.SSID), device.Address)
+			}
+			attacks = append(attacks, models.Attack{
+				Type:        attackType,
+				Severity:    severities[attackType],
+				Description: description,
+				Target:      device.SSID,
+				Timestamp:   time.Now(),
+			})
+		}
+	}
+
+	return attacks
+}
+
+// severityRank orders severities for picking the highest
+func severityRank(severity models.Severity) int {
+	switch severity {
+	case models.SeverityHigh:
+		return 3
+	case models.SeverityMedium:
+		return 2
+	case models.SeverityLow:
+		return 1
+	}
+	return 0
+}
+
+// ssidOrHidden names a network in descriptions
+func ssidOrHidden(ssid string) string {
+	if ssid == "" {
+		return "<hidden>"
+	}
+	return "'" + ssid + "'"
+}
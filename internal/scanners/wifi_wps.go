@@ -0,0 +1,255 @@
+package scanners
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+)
+
+// WPS attribute types read from the WPS information element
+const (
+	wpsAttrConfigMethods   = 0x1008
+	wpsAttrDeviceName      = 0x1011
+	wpsAttrState           = 0x1044
+	wpsAttrVendorExtension = 0x1049
+	wpsAttrVersion         = 0x104a
+	wpsAttrSelectedMethods = 0x1053
+	wpsAttrAPSetupLocked   = 0x1057
+
+	// wpsSubelemVersion2 is the Version2 subelement of the Wi-Fi Alliance extension
+	wpsSubelemVersion2 = 0x00
+)
+
+// WPS setup states reported on WPSInfo.State
+const (
+	wpsStateUnconfigured = "unconfigured"
+	wpsStateConfigured   = "configured"
+)
+
+// wfaVendorID marks the Wi-Fi Alliance vendor extension that carries Version2
+var wfaVendorID = []byte{0x00, 0x37, 0x2a}
+
+// wpsConfigMethodNames maps config method bits to names, composite bits first so
+// that physical and virtual variants win over the plain method
+var wpsConfigMethodNames = []struct {
+	bits uint16
+	name string
+}{
+	{0x4008, "Physical Display"},
+	{0x2008, "Virtual Display"},
+	{0x0480, "Physical PushButton"},
+	{0x0280, "Virtual PushButton"},
+	{0x0001, "USBA"},
+	{0x0002, "Ethernet"},
+	{0x0004, "Label"},
+	{0x0008, "Display"},
+	{0x0010, "External NFC"},
+	{0x0020, "Integrated NFC"},
+	{0x0040, "NFC Interface"},
+	{0x0080, "PushButton"},
+	{0x0100, "Keypad"},
+}
+
+// iwWPSMethodNames maps the config method names iw prints onto the decoder's names
+var iwWPSMethodNames = map[string]string{
+	"USB":       "USBA",
+	"Ext. NFC":  "External NFC",
+	"Int. NFC":  "Integrated NFC",
+	"NFC Intf.": "NFC Interface",
+	"PBC":       "PushButton",
+}
+
+// wpsPINMethods are the config methods that let an enrollee join with a PIN
+var wpsPINMethods = []string{"Label", "Display", "Keypad", "Virtual Display", "Physical Display"}
+
+// parseWPSAttributes decodes the attributes of a WPS information element body
+func parseWPSAttributes(data []byte) *models.WPSInfo {
+	wps := &models.WPSInfo{}
+	var methods, selectedMethods uint16
+
+	for len(data) >= 4 {
+		attr, length := binary.BigEndian.Uint16(data[0:2]), int(binary.BigEndian.Uint16(data[2:4]))
+		if 4+length > len(data) {
+			break
+		}
+		value := data[4 : 4+length]
+		data = data[4+length:]
+
+		switch {
+		case attr == wpsAttrVersion && length == 1:
+			if wps.Version == "" {
+				wps.Version = wpsVersion(value[0])
+			}
+		case attr == wpsAttrState && length == 1:
+			wps.State = wpsState(int(value[0]))
+		case attr == wpsAttrAPSetupLocked && length == 1:
+			wps.Locked = value[0] != 0
+		case attr == wpsAttrConfigMethods && length == 2:
+			methods = binary.BigEndian.Uint16(value)
+		case attr == wpsAttrSelectedMethods && length == 2:
+			selectedMethods = binary.BigEndian.Uint16(value)
+		case attr == wpsAttrDeviceName:
+			wps.DeviceName = printable(string(value))
+		case attr == wpsAttrVendorExtension && length > 3 && string(value[:3]) == string(wfaVendorID):
+			// Version2 supersedes the version 1.0 every WPS 2.0 device also sends
+			for sub := value[3:]; len(sub) >= 2 && 2+int(sub[1]) <= len(sub); sub = sub[2+int(sub[1]):] {
+				if sub[0] == wpsSubelemVersion2 && sub[1] == 1 {
+					wps.Version = wpsVersion(sub[2])
+				}
+			}
+		}
+	}
+
+	// Beacons only carry the methods of an active registrar
+	if methods == 0 {
+		methods = selectedMethods
+	}
+	wps.ConfigMethods = wpsConfigMethods(methods)
+	return wps
+}
+
+// wpsVersion formats a version byte, major version in the high nibble
+func wpsVersion(b byte) string {
+	return fmt.Sprintf("%d.%d", b>>4, b&0x0f)
+}
+
+// wpsState names the Wi-Fi Protected Setup State attribute
+func wpsState(state int) string {
+	switch state {
+	case 1:
+		return wpsStateUnconfigured
+	case 2:
+		return wpsStateConfigured
+	}
+	return ""
+}
+
+// wpsConfigMethods names the bits of a config methods attribute. The physical
+// and virtual variants share the plain method's bit, so both can be named, and
+// the plain method is left out when either is.
+func wpsConfigMethods(methods uint16) []string {
+	var names []string
+	var named uint16
+	for _, method := range wpsConfigMethodNames {
+		if methods&method.bits != method.bits || named&method.bits == method.bits {
+			continue
+		}
+		names = append(names, method.name)
+		named |= method.bits
+	}
+	return names
+}
+
+// parseIwWPSField reads one "* Name: value" line of an iw WPS block
+func parseIwWPSField(wps *models.WPSInfo, field string) {
+	key, value, _ := strings.Cut(field, ":")
+	value = strings.TrimSpace(value)
+
+	switch strings.TrimSpace(key) {
+	case "Version":
+		if wps.Version == "" {
+			wps.Version = value
+		}
+	case "Version2":
+		wps.Version = value
+	case "Wi-Fi Protected Setup State":
+		if fields := strings.Fields(value); len(fields) > 0 {
+			if state, err := strconv.Atoi(fields[0]); err == nil {
+				wps.State = wpsState(state)
+			}
+		}
+	case "AP setup locked":
+		locked, err := strconv.ParseUint(strings.TrimPrefix(value, "0x"), 16, 8)
+		wps.Locked = err == nil && locked != 0
+	case "Device name":
+		wps.DeviceName = value
+	case "Config methods":
+		wps.ConfigMethods = nil
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			if mapped, ok := iwWPSMethodNames[name]; ok {
+				name = mapped
+			}
+			if name != "" {
+				wps.ConfigMethods = append(wps.ConfigMethods, name)
+			}
+		}
+	}
+}
+
+// DetectWPSVulnerabilities reports every network that advertises WPS without
+// locking it. Unconfigured access points and PIN methods on version 1.x, which has
+// no brute-force lockout, are high severity; other PIN methods, or methods the
+// network did not advertise, are medium; push-button only is low.
+func DetectWPSVulnerabilities(devices []models.WiFiDevice) []models.Attack {
+	var attacks []models.Attack
+
+	sorted := append([]models.WiFiDevice(nil), devices...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Address < sorted[j].Address })
+
+	for _, device := range sorted {
+		wps := device.WPS
+		if wps == nil || wps.Locked {
+			continue
+		}
+
+		var pin bool
+		for _, method := range wps.ConfigMethods {
+			pin = pin || containsString(wpsPINMethods, method)
+		}
+		legacy := strings.HasPrefix(wps.Version, "1.")
+
+		severity := models.SeverityLow
+		var risks []string
+		switch {
+		case wps.State == wpsStateUnconfigured:
+			severity = models.SeverityHigh
+			risks = append(risks, "takeover of the unconfigured access point")
+		case pin && legacy:
+			severity = models.SeverityHigh
+		case pin || len(wps.ConfigMethods) == 0:
+			severity = models.SeverityMedium
+		}
+		if pin || len(wps.ConfigMethods) == 0 {
+			risks = append(risks, "PIN brute force and pixie dust")
+		} else {
+			risks = append(risks, "push-button enrollment")
+		}
+
+		details := []string{"version " + valueOrUnknown(wps.Version)}
+		if wps.State != "" {
+			details = append(details, wps.State)
+		}
+		details = append(details, "not locked")
+		if len(wps.ConfigMethods) > 0 {
+			details = append(details, "methods "+strings.Join(wps.ConfigMethods, ", "))
+		}
+		if wps.DeviceName != "" {
+			details = append(details, "device "+wps.DeviceName)
+		}
+
+		attacks = append(attacks, models.Attack{
+			Type:     "WPS_VULNERABILITY",
+			Severity: severity,
+			Description: fmt.Sprintf("WPS enabled on %s (%s): %s - vulnerable to %s",
+				ssidOrHidden(device.SSID), device.Address, strings.Join(details, ", "), strings.Join(risks, "; ")),
+			Target:    device.Address,
+			Timestamp: time.Now(),
+		})
+	}
+
+	return attacks
+}
+
+// valueOrUnknown names a missing value in descriptions
+func valueOrUnknown(value string) string {
+	if value == "" {
+		return "unknown"
+	}
+	return value
+}
@@ -0,0 +1,227 @@
+package scanners
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+)
+
+// wpsCapture holds radiotap beacons and a probe response on 2437 MHz:
+//
+//   - 02:00:00:00:0A:01 LegacyCam: WPS 1.0 beacon with display, push-button and
+//     keypad as the selected registrar's methods
+//   - 02:00:00:00:0A:02 OfficeAP: WPS 2.0 beacon with an authorized MACs
+//     subelement ahead of Version2, then a probe response with both push-button
+//     variants and a device name
+//   - 02:00:00:00:0A:03 LockedAP: AP setup locked
+//   - 02:00:00:00:0A:04 SetupMe: unconfigured WPS 2.0 with a physical display
+const wpsCapture = "testdata/capture/wps-beacons.pcap"
+
+func TestWPSConfigMethods(t *testing.T) {
+	tests := []struct {
+		methods uint16
+		want    []string
+	}{
+		{0x0188, []string{"Display", "PushButton", "Keypad"}},
+		{0x0680, []string{"Physical PushButton", "Virtual PushButton"}},
+		{0x0280, []string{"Virtual PushButton"}},
+		{0x600c, []string{"Physical Display", "Virtual Display", "Label"}},
+		{0x0002, []string{"Ethernet"}},
+		{0, nil},
+	}
+	for _, tt := range tests {
+		if got := wpsConfigMethods(tt.methods); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("wpsConfigMethods(%#04x) = %q, want %q", tt.methods, got, tt.want)
+		}
+	}
+}
+
+func TestParseWPSAttributes(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want models.WPSInfo
+	}{
+		{
+			"version 1.0 with config methods",
+			"104a000110" + "104400010" + "2" + "10080002" + "0188" + "1011000443616d31",
+			models.WPSInfo{Version: "1.0", State: "configured", ConfigMethods: []string{"Display", "PushButton", "Keypad"}, DeviceName: "Cam1"},
+		},
+		{
+			// The Wi-Fi Alliance extension overrides the version whichever comes first
+			"Version2 ahead of the version",
+			"1049000600372a" + "000120" + "104a000110" + "104400010" + "1",
+			models.WPSInfo{Version: "2.0", State: "unconfigured"},
+		},
+		{
+			"locked, with selected methods only",
+			"104a000110" + "105700010" + "1" + "10530002" + "4008",
+			models.WPSInfo{Version: "1.0", Locked: true, ConfigMethods: []string{"Physical Display"}},
+		},
+		{
+			// Other vendors' extensions and truncated attributes are ignored
+			"foreign extension and truncation",
+			"1049000500" + "0c4300" + "20" + "104a000110" + "1008000501",
+			models.WPSInfo{Version: "1.0"},
+		},
+	}
+	for _, tt := range tests {
+		data, err := hex.DecodeString(tt.data)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := parseWPSAttributes(data); !reflect.DeepEqual(*got, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, *got, tt.want)
+		}
+	}
+}
+
+func TestParseIwWPSFields(t *testing.T) {
+	ws, err := NewWiFiScanner(models.DefaultConfig(), &sequenceRunner{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	devices := ws.parseIwScanOutput(readWiFiFixture(t, "iw-scan-wps.txt"), time.Now())
+	if len(devices) != 6 {
+		t.Fatalf("got %d devices, want 6", len(devices))
+	}
+
+	want := []models.WPSInfo{
+		{Version: "1.0", State: "configured", ConfigMethods: []string{"Label", "Display", "Keypad"}, DeviceName: "RT-AC66U"},
+		{Version: "2.0", State: "configured", ConfigMethods: []string{"PushButton"}},
+		{Version: "2.0", State: "configured", Locked: true, ConfigMethods: []string{"Display", "Keypad"}},
+		{Version: "1.0", State: "unconfigured", ConfigMethods: []string{"PushButton"}},
+		{Version: "2.0", State: "configured", ConfigMethods: []string{"Label", "External NFC", "Keypad"}},
+		{Version: "1.0", State: "configured"},
+	}
+	for i, device := range devices {
+		if device.WPS == nil || !reflect.DeepEqual(*device.WPS, want[i]) {
+			t.Errorf("%s: got WPS %+v, want %+v", device.SSID, device.WPS, want[i])
+		}
+	}
+	// The RSN block before WPS is still read
+	if devices[1].Security != "WPA2/WPA3" || devices[1].PMF != "capable" {
+		t.Errorf("OfficeAP security = %s, PMF %s", devices[1].Security, devices[1].PMF)
+	}
+}
+
+// wpsAlerts returns the severity of each WPS_VULNERABILITY by target
+func wpsAlerts(attacks []models.Attack) map[string]models.Severity {
+	alerts := make(map[string]models.Severity)
+	for _, attack := range attacks {
+		if attack.Type == "WPS_VULNERABILITY" {
+			alerts[attack.Target] = attack.Severity
+		}
+	}
+	return alerts
+}
+
+func TestDetectWPSVulnerabilitiesFromIw(t *testing.T) {
+	ws, err := NewWiFiScanner(models.DefaultConfig(), &sequenceRunner{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	attacks := DetectWPSVulnerabilities(ws.parseIwScanOutput(readWiFiFixture(t, "iw-scan-wps.txt"), time.Now()))
+
+	want := map[string]models.Severity{
+		"AA:BB:CC:DD:EE:21": models.SeverityHigh,   // PIN methods on WPS 1.0
+		"AA:BB:CC:DD:EE:22": models.SeverityLow,    // push-button only
+		"AA:BB:CC:DD:EE:24": models.SeverityHigh,   // unconfigured
+		"AA:BB:CC:DD:EE:25": models.SeverityMedium, // PIN methods on WPS 2.0
+		"AA:BB:CC:DD:EE:26": models.SeverityMedium, // no methods advertised
+	}
+	if got := wpsAlerts(attacks); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// Alerts are ordered by BSSID and describe the network
+	if len(attacks) == 0 || !strings.HasPrefix(attacks[0].Description,
+		"WPS enabled on 'HomeRouter' (AA:BB:CC:DD:EE:21): version 1.0, configured, not locked, methods Label, Display, Keypad, device RT-AC66U") {
+		t.Errorf("unexpected first alert: %+v", attacks)
+	}
+}
+
+func TestDetectWPSVulnerabilitiesFromPcap(t *testing.T) {
+	config := models.DefaultConfig()
+	config.WiFiCaptureFile = wpsCapture
+	ws, err := NewWiFiScanner(config, &sequenceRunner{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, attacks, err := ws.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+
+	wps := make(map[string]models.WPSInfo)
+	for _, result := range results {
+		device := result.(models.WiFiDevice)
+		if device.WPS != nil {
+			wps[device.SSID] = *device.WPS
+		}
+	}
+	wantWPS := map[string]models.WPSInfo{
+		"LegacyCam": {Version: "1.0", State: "configured", ConfigMethods: []string{"Display", "PushButton", "Keypad"}},
+		"OfficeAP":  {Version: "2.0", State: "configured", ConfigMethods: []string{"Physical PushButton", "Virtual PushButton"}, DeviceName: "Office AP"},
+		"LockedAP":  {Version: "1.0", State: "configured", Locked: true, ConfigMethods: []string{"Display", "Keypad"}},
+		"SetupMe":   {Version: "2.0", State: "unconfigured", ConfigMethods: []string{"Physical Display"}},
+	}
+	if !reflect.DeepEqual(wps, wantWPS) {
+		t.Errorf("got WPS %+v, want %+v", wps, wantWPS)
+	}
+
+	want := map[string]models.Severity{
+		"02:00:00:00:0A:01": models.SeverityHigh,
+		"02:00:00:00:0A:02": models.SeverityLow,
+		"02:00:00:00:0A:04": models.SeverityHigh,
+	}
+	if got := wpsAlerts(attacks); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestObservedNetworkLimits(t *testing.T) {
+	config := models.DefaultConfig()
+	config.WiFiCaptureFile = wpsCapture
+	ws, err := NewWiFiScanner(config, &sequenceRunner{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A beacon flood with random BSSIDs, one new network every millisecond
+	start := time.Unix(1700000000, 0)
+	body := append(make([]byte, 12), 0, 4, 'f', 'a', 'k', 'e')
+	for i := 0; i < wifiMaxObserved+100; i++ {
+		ws.handleFrame(wifiFrame{
+			Subtype: wifiSubtypeBeacon,
+			BSSID:   fmt.Sprintf("02:00:00:00:%02X:%02X", i>>8, i&0xff),
+			Body:    body,
+			Time:    start.Add(time.Duration(i) * time.Millisecond),
+		})
+	}
+	if len(ws.observed) != wifiMaxObserved {
+		t.Errorf("observed %d networks, want %d", len(ws.observed), wifiMaxObserved)
+	}
+
+	// One network is also in the scan results and only has its WPS details filled in
+	scanned := []models.WiFiDevice{{Address: "02:00:00:00:00:00", SSID: "scanned"}}
+	devices, err := ws.withObservedNetworks(scanned)
+	if len(devices) != 1+wifiMaxFrameOnly {
+		t.Errorf("got %d devices, want %d", len(devices), 1+wifiMaxFrameOnly)
+	}
+	if err == nil || !strings.Contains(err.Error(), "767 networks seen only in captured frames") ||
+		!strings.Contains(err.Error(), "100 frames from new BSSIDs dropped") {
+		t.Errorf("limits not reported: %v", err)
+	}
+	// The most recently heard networks are the ones kept
+	if last := devices[len(devices)-1]; last.Address != "02:00:00:00:03:FF" {
+		t.Errorf("last frame-only network is %s, want 02:00:00:00:03:FF", last.Address)
+	}
+}
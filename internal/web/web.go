@@ -112,7 +112,7 @@ func (ws *WebServer) handleAPIAttacks(w http.ResponseWriter, r *http.Request) {
 
 	// This would output JSON in a complete implementation
 	fmt.Fprintf(w, `{"attacks": [], "count": 0}`)
-	recentAttacks = recentAttacks // use the variable
+	_ = recentAttacks // use the variable
 }
 
 // handleAPIStatus provides system status JSON